@@ -0,0 +1,5 @@
+// Package compress controls the compression of files: NTFS compression of
+// files and directory trees, and the compression backed by the Windows
+// Overlay Filter used by compact.exe. Report measures the space saved by
+// either in a tree.
+package compress
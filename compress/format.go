@@ -0,0 +1,49 @@
+package compress
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Format is an NTFS compression format, as used by FSCTL_GET_COMPRESSION
+// and FSCTL_SET_COMPRESSION.
+type Format uint16
+
+const (
+	FormatNone    Format = 0 // COMPRESSION_FORMAT_NONE
+	FormatDefault Format = 1 // COMPRESSION_FORMAT_DEFAULT
+	FormatLZNT1   Format = 2 // COMPRESSION_FORMAT_LZNT1
+)
+
+func (f Format) String() string {
+	switch f {
+	case FormatNone:
+		return "none"
+	case FormatDefault:
+		return "default"
+	case FormatLZNT1:
+		return "LZNT1"
+	default:
+		return "unknown format"
+	}
+}
+
+// DefaultSkipExtensions are the extensions of the files holding already
+// compressed data, which are not worth compressing again.
+var DefaultSkipExtensions = []string{
+	".7z", ".aac", ".apk", ".avi", ".br", ".bz2", ".cab", ".docx", ".flac",
+	".gif", ".gz", ".heic", ".jar", ".jpeg", ".jpg", ".lz", ".lz4", ".lzma",
+	".m4a", ".m4v", ".mkv", ".mov", ".mp3", ".mp4", ".msi", ".nupkg", ".ogg",
+	".png", ".pptx", ".rar", ".tgz", ".webm", ".webp", ".whl", ".wim", ".xlsx",
+	".xz", ".zip", ".zst",
+}
+
+// skipExtension reports whether the extension of name is one of exts,
+// compared case-insensitively.
+func skipExtension(exts []string, name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	return ext != "" && slices.ContainsFunc(exts, func(e string) bool {
+		return strings.EqualFold(e, ext)
+	})
+}
@@ -0,0 +1,242 @@
+// Package lznt1 implements the LZNT1 compression format used by NTFS
+// compression and RtlCompressBuffer, as described in [MS-XCA]. It works on
+// any platform, so that compressed data read from raw volumes can be
+// decoded without Windows.
+//
+// [MS-XCA]: https://learn.microsoft.com/en-us/openspecs/windows_protocols/ms-xca/94164d22-2928-4417-876e-d193766c4db6
+package lznt1
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// ChunkSize is the size of the chunks of uncompressed data, which are
+// compressed independently.
+const ChunkSize = 4096
+
+// ErrCorrupt is returned when decompressing data which is not valid LZNT1.
+var ErrCorrupt = errors.New("lznt1: corrupt data")
+
+const (
+	// chunkCompressed flags the headers of compressed chunks.
+	chunkCompressed = 0x8000
+	// chunkSignature is set in the headers of every chunk.
+	chunkSignature = 0x3000
+	chunkSizeMask  = 0x0FFF
+
+	minMatch = 3
+)
+
+// Decompress appends the decompression of src to dst and returns the
+// extended buffer. The data ends at the end of src or at the first empty
+// chunk header, so that a compression unit read from a volume, whose
+// unused tail is zero, can be passed whole. Chunks followed by others are
+// padded with zeros to ChunkSize, as NTFS does.
+func Decompress(dst, src []byte) ([]byte, error) {
+	for len(src) >= 2 {
+		header := binary.LittleEndian.Uint16(src)
+		if header == 0 {
+			break
+		}
+		size := int(header&chunkSizeMask) + 1
+		if 2+size > len(src) {
+			return dst, ErrCorrupt
+		}
+		data := src[2 : 2+size]
+		src = src[2+size:]
+
+		start := len(dst)
+		if header&chunkCompressed == 0 {
+			dst = append(dst, data...)
+		} else {
+			var err error
+			if dst, err = decompressChunk(dst, data); err != nil {
+				return dst, err
+			}
+		}
+		if len(dst)-start > ChunkSize {
+			return dst, ErrCorrupt
+		}
+
+		if len(src) >= 2 && binary.LittleEndian.Uint16(src) != 0 {
+			dst = append(dst, make([]byte, ChunkSize-(len(dst)-start))...)
+		}
+	}
+
+	return dst, nil
+}
+
+// decompressChunk appends the decompression of the data of a compressed
+// chunk to dst.
+func decompressChunk(dst, data []byte) ([]byte, error) {
+	start := len(dst)
+	for len(data) > 0 {
+		flags := data[0]
+		data = data[1:]
+		for bit := 0; bit < 8 && len(data) > 0; bit++ {
+			if flags&(1<<bit) == 0 {
+				dst = append(dst, data[0])
+				data = data[1:]
+				continue
+			}
+
+			if len(data) < 2 {
+				return dst, ErrCorrupt
+			}
+			token := binary.LittleEndian.Uint16(data)
+			data = data[2:]
+
+			pos := len(dst) - start
+			offsetBits := offsetBits(pos)
+			offset := int(token>>(16-offsetBits)) + 1
+			length := int(token&(0xFFFF>>offsetBits)) + minMatch
+			if offset > pos || pos+length > ChunkSize {
+				return dst, ErrCorrupt
+			}
+
+			// The source may overlap the bytes being written.
+			from := len(dst) - offset
+			for i := range length {
+				dst = append(dst, dst[from+i])
+			}
+		}
+	}
+
+	return dst, nil
+}
+
+// offsetBits returns the number of bits of the offsets of the back
+// references found at pos in a chunk, the remaining bits of the 16-bit
+// tokens holding their lengths.
+func offsetBits(pos int) int {
+	n := 4
+	for i := pos - 1; i >= 0x10; i >>= 1 {
+		n++
+	}
+
+	return n
+}
+
+// Compress appends the compression of src to dst and returns the extended
+// buffer. Chunks which do not compress are stored as they are. The result
+// is not terminated by an empty chunk header, which callers storing it in
+// a larger buffer get by zeroing the rest of the buffer.
+func Compress(dst, src []byte) []byte {
+	var table matchTable
+	for len(src) > 0 {
+		chunk := src[:min(len(src), ChunkSize)]
+		src = src[len(chunk):]
+
+		start := len(dst)
+		dst = append(dst, 0, 0)
+		dst = table.compressChunk(dst, chunk)
+		size := len(dst) - start - 2
+		if size < len(chunk) {
+			binary.LittleEndian.PutUint16(dst[start:], chunkCompressed|chunkSignature|uint16(size-1))
+			continue
+		}
+
+		dst = append(dst[:start], 0, 0)
+		binary.LittleEndian.PutUint16(dst[start:], chunkSignature|uint16(len(chunk)-1))
+		dst = append(dst, chunk...)
+	}
+
+	return dst
+}
+
+const (
+	hashBits = 12
+	// maxChain bounds the candidates tried for each match.
+	maxChain = 64
+)
+
+// matchTable finds back references with hash chains of the positions of
+// the 3-byte prefixes of a chunk.
+type matchTable struct {
+	head [1 << hashBits]int16
+	prev [ChunkSize]int16
+}
+
+func hash3(b []byte) int {
+	return int((uint32(b[0])<<16|uint32(b[1])<<8|uint32(b[2]))*2654435761) >> (32 - hashBits)
+}
+
+// insert records that the prefix of chunk at pos starts at pos.
+func (t *matchTable) insert(chunk []byte, pos int) {
+	if pos+minMatch > len(chunk) {
+		return
+	}
+	h := hash3(chunk[pos:])
+	t.prev[pos] = t.head[h]
+	t.head[h] = int16(pos)
+}
+
+// compressChunk appends the data of the compressed form of chunk to dst.
+// The data may end up larger than chunk, in which case the caller stores
+// it as is.
+func (t *matchTable) compressChunk(dst, chunk []byte) []byte {
+	for i := range t.head {
+		t.head[i] = -1
+	}
+
+	flagsAt, bit := -1, 8
+	for pos := 0; pos < len(chunk); {
+		if bit == 8 {
+			flagsAt, bit = len(dst), 0
+			dst = append(dst, 0)
+		}
+
+		offset, length := t.longestMatch(chunk, pos)
+		if length < minMatch {
+			dst = append(dst, chunk[pos])
+			t.insert(chunk, pos)
+			pos++
+			bit++
+			continue
+		}
+
+		offsetBits := offsetBits(pos)
+		token := uint16(offset-1)<<(16-offsetBits) | uint16(length-minMatch)
+		dst = binary.LittleEndian.AppendUint16(dst, token)
+		dst[flagsAt] |= 1 << bit
+		for end := pos + length; pos < end; pos++ {
+			t.insert(chunk, pos)
+		}
+		bit++
+	}
+
+	return dst
+}
+
+// longestMatch returns the offset and length of the longest back reference
+// encodable at pos.
+func (t *matchTable) longestMatch(chunk []byte, pos int) (offset, length int) {
+	if pos == 0 || pos+minMatch > len(chunk) {
+		return 0, 0
+	}
+
+	offsetBits := offsetBits(pos)
+	maxLength := min(0xFFFF>>offsetBits+minMatch, len(chunk)-pos)
+	maxOffset := 1 << offsetBits
+
+	cand := int(t.head[hash3(chunk[pos:])])
+	for n := 0; cand >= 0 && n < maxChain; n++ {
+		if pos-cand > maxOffset {
+			break
+		}
+		l := 0
+		for l < maxLength && chunk[cand+l] == chunk[pos+l] {
+			l++
+		}
+		if l > length {
+			offset, length = pos-cand, l
+			if l == maxLength {
+				break
+			}
+		}
+		cand = int(t.prev[cand])
+	}
+
+	return offset, length
+}
@@ -0,0 +1,198 @@
+package lznt1
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"math/rand/v2"
+	"strings"
+	"testing"
+)
+
+func randomBytes(n int, seed uint64) []byte {
+	r := rand.New(rand.NewPCG(seed, seed))
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(r.Uint32())
+	}
+
+	return b
+}
+
+func TestRoundTrip(t *testing.T) {
+	text := []byte(strings.Repeat("The quick brown fox jumps over the lazy dog. ", 200))
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"one byte", []byte{'x'}},
+		{"short", []byte("abcabcabcabc")},
+		{"random", randomBytes(ChunkSize, 1)},
+		{"zeros", make([]byte, ChunkSize)},
+		{"repetitive", text[:ChunkSize]},
+		{"one chunk and a byte", text[:ChunkSize+1]},
+		{"multi chunk", text},
+		{"mixed chunks", bytes.Join([][]byte{
+			make([]byte, ChunkSize), randomBytes(ChunkSize, 2), text[:ChunkSize], randomBytes(100, 3),
+		}, nil)},
+		{"long matches", bytes.Repeat([]byte{1, 2, 3, 4, 5, 6, 7}, 3*ChunkSize)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Compress(nil, tt.data)
+			got, err := Decompress(nil, c)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tt.data) {
+				t.Fatalf("round trip of %d bytes returned %d different bytes", len(tt.data), len(got))
+			}
+
+			// The compressed data of a unit is followed by zeros.
+			got, err = Decompress(nil, append(c, make([]byte, 64)...))
+			if err != nil || !bytes.Equal(got, tt.data) {
+				t.Errorf("round trip with zero tail failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestCompressChunkKinds(t *testing.T) {
+	random := randomBytes(ChunkSize, 4)
+	c := Compress(nil, random)
+	if len(c) != 2+ChunkSize {
+		t.Fatalf("incompressible chunk compressed to %d bytes, want %d", len(c), 2+ChunkSize)
+	}
+	if h := binary.LittleEndian.Uint16(c); h != chunkSignature|(ChunkSize-1) {
+		t.Errorf("header of stored chunk = %#x", h)
+	}
+	if !bytes.Equal(c[2:], random) {
+		t.Error("stored chunk differs from its data")
+	}
+
+	c = Compress(nil, make([]byte, ChunkSize))
+	if h := binary.LittleEndian.Uint16(c); h&chunkCompressed == 0 || int(h&chunkSizeMask)+3 != len(c) {
+		t.Errorf("header of compressed chunk = %#x for %d bytes", h, len(c))
+	}
+	if len(c) > 64 {
+		t.Errorf("chunk of zeros compressed to %d bytes", len(c))
+	}
+}
+
+// TestDecompressXCA decodes the LZNT1 example of [MS-XCA].
+func TestDecompressXCA(t *testing.T) {
+	src, err := hex.DecodeString("38b08846232000204720410010a24701a045204400084501507900c045200524138805b4024a44ef0358028c091601484500be009e000401189000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "F# F# G A A G F# E D D E F# F# E E F# F# G A A G F# E D D E F# E D D E E F# D E F# G F# D E F# G F# E D E A F# F# G A A G F# E D D E F# E D D\x00"
+
+	got, err := Decompress(nil, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestDecompressPadding(t *testing.T) {
+	// Chunks followed by others hold ChunkSize bytes, zeros included.
+	src := append(Compress(nil, []byte("abcabcabc")), Compress(nil, []byte("xyz"))...)
+	got, err := Decompress([]byte("prefix"), src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := append([]byte("prefix"), []byte("abcabcabc")...)
+	want = append(want, make([]byte, ChunkSize-9)...)
+	want = append(want, "xyz"...)
+	if !bytes.Equal(got, want) {
+		t.Errorf("got %d bytes, want %d", len(got), len(want))
+	}
+}
+
+func TestDecompressCorrupt(t *testing.T) {
+	header := func(compressed bool, data ...byte) []byte {
+		h := chunkSignature | uint16(len(data)-1)
+		if compressed {
+			h |= chunkCompressed
+		}
+		return append(binary.LittleEndian.AppendUint16(nil, h), data...)
+	}
+
+	tests := []struct {
+		name string
+		src  []byte
+	}{
+		{"size beyond data", []byte{0x10, 0xB0, 1, 2, 3}},
+		{"truncated stored chunk", header(false, make([]byte, 16)...)[:10]},
+		{"reference before chunk", header(true, 0x01, 0x00, 0x00)},
+		{"truncated token", header(true, 0x02, 'a', 0x00)},
+		{"reference beyond chunk", header(true, 0x02, 'a', 0xFF, 0x0F)},
+		{"offset beyond data", header(true, 0x02, 'a', 0x00, 0x10)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Decompress(nil, tt.src); !errors.Is(err, ErrCorrupt) {
+				t.Errorf("err = %v, want %v", err, ErrCorrupt)
+			}
+		})
+	}
+}
+
+func TestDecompressTruncated(t *testing.T) {
+	text := []byte(strings.Repeat("truncated compressed data ", 400))
+	c := Compress(nil, text)
+
+	// Every prefix either decodes or fails, without panicking.
+	for n := range len(c) {
+		Decompress(nil, c[:n])
+	}
+	for i := range 200 {
+		Decompress(nil, randomBytes(1+i*37%1000, uint64(i)))
+	}
+}
+
+func TestOffsetBits(t *testing.T) {
+	tests := []struct{ pos, want int }{
+		{1, 4}, {16, 4}, {17, 5}, {32, 5}, {33, 6}, {64, 6}, {65, 7},
+		{128, 7}, {129, 8}, {256, 8}, {512, 9}, {1024, 10}, {2048, 11}, {2049, 12}, {4095, 12},
+	}
+
+	for _, tt := range tests {
+		if got := offsetBits(tt.pos); got != tt.want {
+			t.Errorf("offsetBits(%d) = %d, want %d", tt.pos, got, tt.want)
+		}
+	}
+}
+
+func FuzzDecompress(f *testing.F) {
+	f.Add(Compress(nil, []byte(strings.Repeat("fuzz ", 1000))))
+	f.Add(Compress(nil, randomBytes(100, 5)))
+	f.Add([]byte{0x02, 0xB0, 0x02, 'a', 0xFF, 0x0F})
+
+	f.Fuzz(func(t *testing.T, src []byte) {
+		Decompress(nil, src)
+	})
+}
+
+func FuzzRoundTrip(f *testing.F) {
+	f.Add([]byte("abcabcabcabcabc"))
+	f.Add(make([]byte, ChunkSize+1))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		got, err := Decompress(nil, Compress(nil, data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Fatal("round trip differs")
+		}
+	})
+}
@@ -0,0 +1,201 @@
+package compress
+
+import (
+	"encoding/binary"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/sys/windows"
+
+	"github.com/go-sw/ntfs/internal/fsctl"
+)
+
+// State returns the NTFS compression format of the file or directory at
+// path. The format of a directory is the one its new files get.
+func State(path string) (Format, error) {
+	h, err := fsctl.Open(path, windows.FILE_READ_ATTRIBUTES, windows.FILE_FLAG_OPEN_REPARSE_POINT)
+	if err != nil {
+		return 0, err
+	}
+	defer windows.CloseHandle(h)
+
+	f, err := StateHandle(h)
+	if err != nil {
+		return 0, &os.PathError{Op: "FSCTL_GET_COMPRESSION", Path: path, Err: err}
+	}
+
+	return f, nil
+}
+
+// StateHandle is like State for the file opened as h.
+func StateHandle(h windows.Handle) (Format, error) {
+	out := make([]byte, 2)
+	if _, err := fsctl.Call(h, windows.FSCTL_GET_COMPRESSION, nil, out); err != nil {
+		return 0, err
+	}
+
+	return Format(binary.LittleEndian.Uint16(out)), nil
+}
+
+// Compress compresses the file at path with LZNT1. Compressing a directory
+// only makes the files created in it compressed; CompressTree compresses
+// existing files as well.
+func Compress(path string) error {
+	return setPath(path, FormatDefault)
+}
+
+// Uncompress uncompresses the file at path, or makes the files created in
+// the directory at path uncompressed.
+func Uncompress(path string) error {
+	return setPath(path, FormatNone)
+}
+
+func setPath(path string, format Format) error {
+	h, err := fsctl.Open(path, windows.FILE_READ_DATA|windows.FILE_WRITE_DATA|
+		windows.FILE_READ_ATTRIBUTES|windows.FILE_WRITE_ATTRIBUTES, windows.FILE_FLAG_OPEN_REPARSE_POINT)
+	if err != nil {
+		return err
+	}
+	defer windows.CloseHandle(h)
+
+	if err := SetHandle(h, format); err != nil {
+		return &os.PathError{Op: "FSCTL_SET_COMPRESSION", Path: path, Err: err}
+	}
+
+	return nil
+}
+
+// SetHandle sets the NTFS compression format of the file opened as h,
+// which must have been opened with read and write access to its data and
+// attributes. The data of the file is compressed or uncompressed before
+// SetHandle returns.
+func SetHandle(h windows.Handle, format Format) error {
+	in := binary.LittleEndian.AppendUint16(nil, uint16(format))
+	_, err := fsctl.Call(h, windows.FSCTL_SET_COMPRESSION, in, nil)
+
+	return err
+}
+
+// TreeOptions control CompressTree and UncompressTree.
+type TreeOptions struct {
+	// SkipExtensions are the extensions of the files left alone by
+	// CompressTree, DefaultSkipExtensions if nil. Directories are always
+	// updated.
+	SkipExtensions []string
+	// Skip, if not nil, leaves alone the files and directories for which
+	// it returns true, along with the contents of the directories.
+	Skip func(path string, d fs.DirEntry) bool
+	// Progress, if not nil, is called for every file and directory
+	// updated, with the error met if any. If it returns an error,
+	// the walk stops and returns it, unless it is filepath.SkipAll.
+	// If Progress is nil, errors stop the walk.
+	Progress func(path string, err error) error
+}
+
+// TreeStats counts the files handled by CompressTree and UncompressTree.
+type TreeStats struct {
+	Updated int
+	Skipped int
+	Failed  int
+}
+
+// CompressTree compresses the directory root, all the files below it and
+// its subdirectories, so that the files created in them later are
+// compressed as well. Files already compressed, encrypted files, and
+// files with one of the skipped extensions are left alone. Reparse points
+// are not followed. A nil opts is the same as the zero TreeOptions.
+func CompressTree(root string, opts *TreeOptions) (TreeStats, error) {
+	return walkTree(root, opts, func(path string, d fs.DirEntry, skipExts []string) (bool, error) {
+		return update(path, d, FormatDefault, skipExts)
+	})
+}
+
+// UncompressTree uncompresses the directory root, all the files below it
+// and its subdirectories.
+func UncompressTree(root string, opts *TreeOptions) (TreeStats, error) {
+	return walkTree(root, opts, func(path string, d fs.DirEntry, skipExts []string) (bool, error) {
+		return update(path, d, FormatNone, skipExts)
+	})
+}
+
+// walkTree calls update for the files of the tree rooted at root selected
+// by opts, update reporting whether it changed the file.
+func walkTree(root string, opts *TreeOptions, update func(path string, d fs.DirEntry, skipExts []string) (bool, error)) (TreeStats, error) {
+	if opts == nil {
+		opts = new(TreeOptions)
+	}
+	skipExts := opts.SkipExtensions
+	if skipExts == nil {
+		skipExts = DefaultSkipExtensions
+	}
+
+	var stats TreeStats
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && opts.Skip != nil && opts.Skip(path, d) {
+			stats.Skipped++
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if err == nil {
+			var updated bool
+			if updated, err = update(path, d, skipExts); err == nil && !updated {
+				stats.Skipped++
+				return nil
+			}
+		}
+		if err != nil {
+			stats.Failed++
+		} else {
+			stats.Updated++
+		}
+
+		if opts.Progress == nil {
+			return err
+		}
+		return opts.Progress(path, err)
+	})
+	if errors.Is(err, filepath.SkipAll) {
+		err = nil
+	}
+
+	return stats, err
+}
+
+// update sets the compression format of the file at path, and reports
+// whether it had to be changed.
+func update(path string, d fs.DirEntry, format Format, skipExts []string) (bool, error) {
+	// Irregular files are reparse points, which are left alone.
+	if d.Type()&(fs.ModeSymlink|fs.ModeIrregular) != 0 {
+		return false, nil
+	}
+	if format != FormatNone && !d.IsDir() && skipExtension(skipExts, d.Name()) {
+		return false, nil
+	}
+
+	h, err := fsctl.Open(path, windows.FILE_READ_DATA|windows.FILE_WRITE_DATA|
+		windows.FILE_READ_ATTRIBUTES|windows.FILE_WRITE_ATTRIBUTES, 0)
+	if err != nil {
+		return false, err
+	}
+	defer windows.CloseHandle(h)
+
+	var fi windows.ByHandleFileInformation
+	if err := windows.GetFileInformationByHandle(h, &fi); err != nil {
+		return false, &os.PathError{Op: "getfileinformation", Path: path, Err: err}
+	}
+	compressed := fi.FileAttributes&windows.FILE_ATTRIBUTE_COMPRESSED != 0
+	if compressed == (format != FormatNone) || fi.FileAttributes&windows.FILE_ATTRIBUTE_ENCRYPTED != 0 {
+		return false, nil
+	}
+
+	if err := SetHandle(h, format); err != nil {
+		return false, &os.PathError{Op: "FSCTL_SET_COMPRESSION", Path: path, Err: err}
+	}
+
+	return true, nil
+}
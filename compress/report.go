@@ -0,0 +1,78 @@
+package compress
+
+// Method is the way the data of a file is stored, as reported by Report.
+type Method int
+
+const (
+	MethodNone Method = iota
+	MethodLZNT1
+	MethodXPRESS4K
+	MethodXPRESS8K
+	MethodXPRESS16K
+	MethodLZX
+	// MethodSparse is used for uncompressed sparse files, whose holes
+	// take no space.
+	MethodSparse
+)
+
+var methodNames = [...]string{
+	MethodNone:      "none",
+	MethodLZNT1:     "LZNT1",
+	MethodXPRESS4K:  "XPRESS4K",
+	MethodXPRESS8K:  "XPRESS8K",
+	MethodXPRESS16K: "XPRESS16K",
+	MethodLZX:       "LZX",
+	MethodSparse:    "sparse",
+}
+
+func (m Method) String() string {
+	if m < 0 || int(m) >= len(methodNames) {
+		return "unknown method"
+	}
+
+	return methodNames[m]
+}
+
+// Usage is the space used by a set of files.
+type Usage struct {
+	Files int
+	// Logical is the sum of the sizes of the files, and OnDisk the space
+	// allocated to them.
+	Logical int64
+	OnDisk  int64
+}
+
+// Saved returns the space saved by compression and holes, which is
+// negative when the allocation of the files exceeds their size.
+func (u Usage) Saved() int64 {
+	return u.Logical - u.OnDisk
+}
+
+// Ratio returns the ratio of the space allocated to the files to their
+// size, or 1 if they are empty.
+func (u Usage) Ratio() float64 {
+	if u.Logical == 0 {
+		return 1
+	}
+
+	return float64(u.OnDisk) / float64(u.Logical)
+}
+
+func (u *Usage) add(logical, onDisk int64) {
+	u.Files++
+	u.Logical += logical
+	u.OnDisk += onDisk
+}
+
+// TreeReport is the space used by the files of a tree, returned by Report.
+type TreeReport struct {
+	Root  string
+	Total Usage
+	// Methods groups the files by the way their data is stored.
+	Methods map[Method]Usage
+	// Directories holds the usage of the files of every directory and its
+	// subdirectories, by path relative to the root, the root being ".".
+	Directories map[string]Usage
+	// Unreadable counts the files which could not be queried.
+	Unreadable int
+}
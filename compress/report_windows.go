@@ -0,0 +1,152 @@
+package compress
+
+import (
+	"io/fs"
+	"path/filepath"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+
+	"github.com/go-sw/ntfs/internal/fsctl"
+)
+
+// fileStandardInfo is FILE_STANDARD_INFO.
+type fileStandardInfo struct {
+	AllocationSize int64
+	EndOfFile      int64
+	NumberOfLinks  uint32
+	DeletePending  bool
+	Directory      bool
+}
+
+// Report walks the tree rooted at root and returns the size of its files
+// compared to the space they use on disk, by compression method and by
+// directory. The space used by compressed and sparse files is their
+// compressed size, as reported by FileCompressionInfo, and that of other
+// files is their allocation size. Files with several hard links are only
+// counted once, and reparse points are not followed.
+func Report(root string) (*TreeReport, error) {
+	r := &TreeReport{
+		Root:        root,
+		Methods:     make(map[Method]Usage),
+		Directories: make(map[string]Usage),
+	}
+	type fileID struct {
+		volume    uint32
+		high, low uint32
+	}
+	seen := make(map[fileID]bool)
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			r.Unreadable++
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		method, fi, logical, onDisk, err := usage(path)
+		if err != nil {
+			r.Unreadable++
+			return nil
+		}
+		if fi.NumberOfLinks > 1 {
+			id := fileID{fi.VolumeSerialNumber, fi.FileIndexHigh, fi.FileIndexLow}
+			if seen[id] {
+				return nil
+			}
+			seen[id] = true
+		}
+
+		r.Total.add(logical, onDisk)
+		u := r.Methods[method]
+		u.add(logical, onDisk)
+		r.Methods[method] = u
+
+		rel, err := filepath.Rel(root, filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		for {
+			u := r.Directories[rel]
+			u.add(logical, onDisk)
+			r.Directories[rel] = u
+			if rel == "." {
+				break
+			}
+			rel = filepath.Dir(rel)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// methodOf returns the method of the files compressed by WOF with alg.
+func methodOf(alg Algorithm) Method {
+	switch alg {
+	case XPRESS4K:
+		return MethodXPRESS4K
+	case XPRESS8K:
+		return MethodXPRESS8K
+	case XPRESS16K:
+		return MethodXPRESS16K
+	case LZX:
+		return MethodLZX
+	default:
+		return MethodNone
+	}
+}
+
+// usage returns the storage method, size and space used of the file at
+// path.
+func usage(path string) (Method, *windows.ByHandleFileInformation, int64, int64, error) {
+	h, err := fsctl.Open(path, windows.FILE_READ_ATTRIBUTES, windows.FILE_FLAG_OPEN_REPARSE_POINT)
+	if err != nil {
+		return 0, nil, 0, 0, err
+	}
+	defer windows.CloseHandle(h)
+
+	var fi windows.ByHandleFileInformation
+	if err := windows.GetFileInformationByHandle(h, &fi); err != nil {
+		return 0, nil, 0, 0, err
+	}
+	var si fileStandardInfo
+	err = windows.GetFileInformationByHandleEx(h, windows.FileStandardInfo,
+		(*byte)(unsafe.Pointer(&si)), uint32(unsafe.Sizeof(si)))
+	if err != nil {
+		return 0, nil, 0, 0, err
+	}
+	ci, err := fsctl.CompressionInfo(h)
+	if err != nil {
+		return 0, nil, 0, 0, err
+	}
+
+	method := MethodNone
+	switch {
+	case fi.FileAttributes&windows.FILE_ATTRIBUTE_COMPRESSED != 0:
+		method = MethodLZNT1
+	case fi.FileAttributes&windows.FILE_ATTRIBUTE_REPARSE_POINT != 0:
+		if alg, ok, err := WOFStateHandle(h); err == nil && ok {
+			method = methodOf(alg)
+		}
+	}
+	if method == MethodNone && fi.FileAttributes&windows.FILE_ATTRIBUTE_SPARSE_FILE != 0 {
+		method = MethodSparse
+	}
+
+	onDisk := si.AllocationSize
+	if method != MethodNone {
+		onDisk = ci.CompressedFileSize
+	}
+
+	return method, &fi, si.EndOfFile, onDisk, nil
+}
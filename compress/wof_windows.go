@@ -0,0 +1,267 @@
+package compress
+
+import (
+	"encoding/binary"
+	"errors"
+	"io/fs"
+	"os"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/windows"
+
+	"github.com/go-sw/ntfs/internal/fsctl"
+)
+
+const (
+	fsctlSetExternalBacking    = 0x9030C // FSCTL_SET_EXTERNAL_BACKING
+	fsctlGetExternalBacking    = 0x90310 // FSCTL_GET_EXTERNAL_BACKING
+	fsctlDeleteExternalBacking = 0x90314 // FSCTL_DELETE_EXTERNAL_BACKING
+
+	wofCurrentVersion          = 1 // WOF_CURRENT_VERSION
+	wofProviderFile            = 2 // WOF_PROVIDER_FILE
+	fileProviderCurrentVersion = 1 // FILE_PROVIDER_CURRENT_VERSION
+)
+
+// Algorithm is a compression algorithm of the file provider of the
+// Windows Overlay Filter.
+type Algorithm uint32
+
+const (
+	XPRESS4K  Algorithm = 0 // FILE_PROVIDER_COMPRESSION_XPRESS4K
+	LZX       Algorithm = 1 // FILE_PROVIDER_COMPRESSION_LZX
+	XPRESS8K  Algorithm = 2 // FILE_PROVIDER_COMPRESSION_XPRESS8K
+	XPRESS16K Algorithm = 3 // FILE_PROVIDER_COMPRESSION_XPRESS16K
+)
+
+func (a Algorithm) String() string {
+	switch a {
+	case XPRESS4K:
+		return "XPRESS4K"
+	case LZX:
+		return "LZX"
+	case XPRESS8K:
+		return "XPRESS8K"
+	case XPRESS16K:
+		return "XPRESS16K"
+	default:
+		return "unknown algorithm"
+	}
+}
+
+// ErrNotBeneficial is returned by WOFCompress when compressing the file
+// would not save space, in which case it is left alone.
+var ErrNotBeneficial = errors.New("compress: compression not beneficial")
+
+// WOFCompress compresses the file at path with alg, like compact.exe /EXE.
+// The file reads as before, but writing to it decompresses it entirely, so
+// that only files which are seldom written are worth compressing, such as
+// executables. The compression happens before WOFCompress returns.
+func WOFCompress(path string, alg Algorithm) error {
+	return withFile(path, windows.GENERIC_READ|windows.GENERIC_WRITE, fsctlSetExternalBacking, func(h windows.Handle) error {
+		return WOFCompressHandle(h, alg)
+	})
+}
+
+// WOFCompressHandle is like WOFCompress for the file opened as h.
+func WOFCompressHandle(h windows.Handle, alg Algorithm) error {
+	// WOF_EXTERNAL_INFO followed by FILE_PROVIDER_EXTERNAL_INFO_V1.
+	in := make([]byte, 20)
+	binary.LittleEndian.PutUint32(in, wofCurrentVersion)
+	binary.LittleEndian.PutUint32(in[4:], wofProviderFile)
+	binary.LittleEndian.PutUint32(in[8:], fileProviderCurrentVersion)
+	binary.LittleEndian.PutUint32(in[12:], uint32(alg))
+
+	_, err := fsctl.Call(h, fsctlSetExternalBacking, in, nil)
+	if errors.Is(err, windows.ERROR_COMPRESSION_NOT_BENEFICIAL) {
+		return ErrNotBeneficial
+	}
+
+	return err
+}
+
+// WOFUncompress decompresses the file at path, compressed with
+// WOFCompress. Files which are not compressed are left alone.
+func WOFUncompress(path string) error {
+	return withFile(path, windows.GENERIC_READ|windows.GENERIC_WRITE, fsctlDeleteExternalBacking, WOFUncompressHandle)
+}
+
+// WOFUncompressHandle is like WOFUncompress for the file opened as h.
+func WOFUncompressHandle(h windows.Handle) error {
+	_, err := fsctl.Call(h, fsctlDeleteExternalBacking, nil, nil)
+	if errors.Is(err, windows.ERROR_OBJECT_NOT_EXTERNALLY_BACKED) {
+		return nil
+	}
+
+	return err
+}
+
+// WOFState returns the algorithm the file at path is compressed with, and
+// false if it is not compressed by the file provider of the Windows
+// Overlay Filter.
+func WOFState(path string) (Algorithm, bool, error) {
+	var (
+		alg Algorithm
+		ok  bool
+	)
+	err := withFile(path, windows.FILE_READ_ATTRIBUTES, fsctlGetExternalBacking, func(h windows.Handle) error {
+		var err error
+		alg, ok, err = WOFStateHandle(h)
+		return err
+	})
+
+	return alg, ok, err
+}
+
+// WOFStateHandle is like WOFState for the file opened as h.
+func WOFStateHandle(h windows.Handle) (Algorithm, bool, error) {
+	out := make([]byte, 64)
+	n, err := fsctl.Call(h, fsctlGetExternalBacking, nil, out)
+	if errors.Is(err, windows.ERROR_OBJECT_NOT_EXTERNALLY_BACKED) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	if n < 20 || binary.LittleEndian.Uint32(out[4:]) != wofProviderFile {
+		// Backed by another provider, such as a WIM file.
+		return 0, false, nil
+	}
+
+	return Algorithm(binary.LittleEndian.Uint32(out[12:])), true, nil
+}
+
+func withFile(path string, access uint32, code uint32, fn func(windows.Handle) error) error {
+	h, err := fsctl.Open(path, access, 0)
+	if err != nil {
+		return err
+	}
+	defer windows.CloseHandle(h)
+
+	if err := fn(h); err != nil {
+		op := "FSCTL_SET_EXTERNAL_BACKING"
+		switch code {
+		case fsctlGetExternalBacking:
+			op = "FSCTL_GET_EXTERNAL_BACKING"
+		case fsctlDeleteExternalBacking:
+			op = "FSCTL_DELETE_EXTERNAL_BACKING"
+		}
+		return &os.PathError{Op: op, Path: path, Err: err}
+	}
+
+	return nil
+}
+
+// DefaultWrittenWithin is the period within which files written are not
+// WOF-compressed unless set otherwise.
+const DefaultWrittenWithin = 7 * 24 * time.Hour
+
+// WOFTreeOptions control WOFCompressTree.
+type WOFTreeOptions struct {
+	TreeOptions
+	Algorithm Algorithm
+	// MinSize is the size of the smallest file compressed, 4KiB if zero,
+	// as smaller files are stored in a single cluster or in their MFT
+	// record.
+	MinSize int64
+	// WrittenWithin leaves alone the files written within this period,
+	// DefaultWrittenWithin if zero, as they are likely to be written again
+	// soon, which would decompress them. A negative period disables this
+	// check.
+	WrittenWithin time.Duration
+}
+
+// Reasons for which files are not worth WOF-compressing.
+var (
+	ErrRecentlyWritten = errors.New("compress: recently written")
+	ErrTooSmall        = errors.New("compress: too small")
+	ErrIncompatible    = errors.New("compress: compressed, encrypted, sparse or reparse point")
+)
+
+// WOFSuitable reports why the file described by fi, as returned by
+// os.Lstat, is not worth WOF-compressing, or nil if it is: files which are
+// too small or were written recently, and those which are already
+// compressed, encrypted, sparse, or reparse points, which WOF compression
+// does not support. A nil opts is the same as the zero WOFTreeOptions.
+func WOFSuitable(fi fs.FileInfo, opts *WOFTreeOptions) error {
+	if opts == nil {
+		opts = new(WOFTreeOptions)
+	}
+	minSize := opts.MinSize
+	if minSize <= 0 {
+		minSize = 4 << 10
+	}
+	within := opts.WrittenWithin
+	if within == 0 {
+		within = DefaultWrittenWithin
+	}
+
+	if !fi.Mode().IsRegular() {
+		return ErrIncompatible
+	}
+	if d, ok := fi.Sys().(*syscall.Win32FileAttributeData); ok {
+		const incompatible = windows.FILE_ATTRIBUTE_COMPRESSED | windows.FILE_ATTRIBUTE_ENCRYPTED |
+			windows.FILE_ATTRIBUTE_SPARSE_FILE | windows.FILE_ATTRIBUTE_REPARSE_POINT
+		if d.FileAttributes&incompatible != 0 {
+			return ErrIncompatible
+		}
+	}
+	if fi.Size() < minSize {
+		return ErrTooSmall
+	}
+	if within > 0 && time.Since(fi.ModTime()) < within {
+		return ErrRecentlyWritten
+	}
+
+	return nil
+}
+
+// WOFCompressTree compresses with opts.Algorithm the files of the tree
+// rooted at root which are worth it according to WOFSuitable and the
+// skipped extensions, replicating compact.exe /C /S /EXE. Directories are
+// left alone, as WOF compression does not apply to the files created in
+// them. A nil opts is the same as the zero WOFTreeOptions.
+func WOFCompressTree(root string, opts *WOFTreeOptions) (TreeStats, error) {
+	if opts == nil {
+		opts = new(WOFTreeOptions)
+	}
+
+	return walkTree(root, &opts.TreeOptions, func(path string, d fs.DirEntry, skipExts []string) (bool, error) {
+		if d.IsDir() || skipExtension(skipExts, d.Name()) {
+			return false, nil
+		}
+
+		fi, err := d.Info()
+		if err != nil {
+			return false, err
+		}
+		if WOFSuitable(fi, opts) != nil {
+			return false, nil
+		}
+
+		if _, ok, err := WOFState(path); err != nil || ok {
+			return false, err
+		}
+		err = WOFCompress(path, opts.Algorithm)
+		if errors.Is(err, ErrNotBeneficial) {
+			return false, nil
+		}
+
+		return err == nil, err
+	})
+}
+
+// WOFUncompressTree decompresses the files of the tree rooted at root
+// compressed with WOFCompress.
+func WOFUncompressTree(root string, opts *TreeOptions) (TreeStats, error) {
+	return walkTree(root, opts, func(path string, d fs.DirEntry, _ []string) (bool, error) {
+		if !d.Type().IsRegular() {
+			return false, nil
+		}
+		if _, ok, err := WOFState(path); err != nil || !ok {
+			return false, err
+		}
+
+		return true, WOFUncompress(path)
+	})
+}
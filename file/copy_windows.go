@@ -0,0 +1,139 @@
+package file
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/windows"
+
+	"github.com/go-sw/ntfs/compress"
+)
+
+// copyFileFailIfExists is COPY_FILE_FAIL_IF_EXISTS.
+const copyFileFailIfExists = 0x1
+
+// Compression selects the compression of the copies made by CopyWith.
+type Compression int
+
+const (
+	// CompressionKeep leaves the copy as CopyFileEx makes it, which keeps
+	// the NTFS compression of the source but not its WOF compression.
+	CompressionKeep Compression = iota
+	// CompressionNone uncompresses the copy, for copies to volumes where
+	// files are read and written often.
+	CompressionNone
+	// CompressionNTFS compresses the copy with NTFS compression.
+	CompressionNTFS
+	// CompressionWOF compresses the copy with the WOF algorithm of
+	// CopyOptions.Algorithm, like compact.exe /EXE. Copies which would not
+	// get smaller are left uncompressed.
+	CompressionWOF
+)
+
+// CopyOptions control a copy.
+type CopyOptions struct {
+	// FailIfExists fails the copy if the destination exists, rather than
+	// replacing it.
+	FailIfExists bool
+	// Compression is applied to the copy once CopyFileEx completes.
+	Compression Compression
+	// Algorithm is the algorithm used with CompressionWOF.
+	Algorithm compress.Algorithm
+	// Verify checks that the source did not change while it was copied,
+	// by holding a read lease on it and comparing its USN, change time and
+	// size before and after the copy, which gives copies of live files
+	// close to those of a snapshot.
+	Verify bool
+	// Retries is the number of times a verified copy is made again when
+	// the source changed. Once they are exhausted, the last copy is left
+	// in place and ErrSourceChanged is returned.
+	Retries int
+}
+
+// Copy copies the file src to dst with CopyFileEx, replacing dst if it
+// exists. The copy keeps the attributes, alternate data streams and
+// extended attributes of src.
+func Copy(src, dst string) error {
+	return CopyWith(src, dst, nil)
+}
+
+// CopyWith is like Copy, with the copy controlled by opts. A nil opts is
+// the same as the zero CopyOptions. If the compression of the copy cannot
+// be set, the copy is left in place and the error is returned.
+func CopyWith(src, dst string, opts *CopyOptions) error {
+	if opts == nil {
+		opts = new(CopyOptions)
+	}
+
+	from, err := windows.UTF16PtrFromString(src)
+	if err != nil {
+		return &os.PathError{Op: "CopyFileEx", Path: src, Err: err}
+	}
+	to, err := windows.UTF16PtrFromString(dst)
+	if err != nil {
+		return &os.PathError{Op: "CopyFileEx", Path: dst, Err: err}
+	}
+
+	var flags uint32
+	if opts.FailIfExists {
+		flags |= copyFileFailIfExists
+	}
+	if !opts.Verify {
+		if err := copyFile(src, dst, from, to, flags); err != nil {
+			return err
+		}
+		return setCompression(dst, opts)
+	}
+
+	for i := 0; ; i++ {
+		changed, err := copyVerified(src, dst, from, to, flags)
+		if err != nil {
+			return err
+		}
+		if !changed {
+			return setCompression(dst, opts)
+		}
+		if i == opts.Retries {
+			if err := setCompression(dst, opts); err != nil {
+				return err
+			}
+			return &os.LinkError{Op: "copy", Old: src, New: dst, Err: ErrSourceChanged}
+		}
+		// The copy being retried is replaced.
+		flags &^= copyFileFailIfExists
+	}
+}
+
+func copyFile(src, dst string, from, to *uint16, flags uint32) error {
+	if err := copyFileEx(from, to, 0, 0, nil, flags); err != nil {
+		return &os.LinkError{Op: "CopyFileEx", Old: src, New: dst, Err: err}
+	}
+
+	return nil
+}
+
+// setCompression applies the compression selected by opts to the copy at
+// path.
+func setCompression(path string, opts *CopyOptions) error {
+	switch opts.Compression {
+	case CompressionNone:
+		if err := compress.WOFUncompress(path); err != nil {
+			return err
+		}
+		return compress.Uncompress(path)
+	case CompressionNTFS:
+		return compress.Compress(path)
+	case CompressionWOF:
+		// WOF does not compress files which NTFS already compresses.
+		if err := compress.Uncompress(path); err != nil {
+			return err
+		}
+		err := compress.WOFCompress(path, opts.Algorithm)
+		if errors.Is(err, compress.ErrNotBeneficial) {
+			return nil
+		}
+		return err
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,4 @@
+// Package file copies files with CopyFileEx, controlling what the copies
+// keep of the NTFS features of their sources, and verifying that the
+// sources did not change while they were copied.
+package file
@@ -0,0 +1,5 @@
+package file
+
+//go:generate go run golang.org/x/sys/windows/mkwinsyscall -output zsyscall_windows.go syscall_windows.go
+
+//sys	copyFileEx(existing *uint16, new *uint16, progress uintptr, data uintptr, cancel *int32, flags uint32) (err error) = kernel32.CopyFileExW
@@ -0,0 +1,114 @@
+package file
+
+import (
+	"encoding/binary"
+	"errors"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+
+	"github.com/go-sw/ntfs/internal/fsctl"
+	"github.com/go-sw/ntfs/oplock"
+)
+
+// ErrSourceChanged is returned by CopyWith when verifying copies, if the
+// source kept changing while it was copied.
+var ErrSourceChanged = errors.New("file: source changed while copied")
+
+// fileBasicInfo is FILE_BASIC_INFO.
+type fileBasicInfo struct {
+	CreationTime   int64
+	LastAccessTime int64
+	LastWriteTime  int64
+	ChangeTime     int64
+	FileAttributes uint32
+	// FILE_BASIC_INFO ends on 4 bytes of padding, so that
+	// GetFileInformationByHandleEx gets its 40 bytes on 386 as well.
+	_ uint32
+}
+
+// sourceState is the state of a file compared before and after copying
+// it.
+type sourceState struct {
+	// usn is the USN of the last change of the file recorded in the
+	// change journal, or zero if the journal is inactive.
+	usn        int64
+	changeTime int64
+	size       int64
+}
+
+// stateOf returns the state of the file at path.
+func stateOf(path string) (sourceState, error) {
+	h, err := fsctl.Open(path, windows.FILE_READ_ATTRIBUTES, 0)
+	if err != nil {
+		return sourceState{}, err
+	}
+	defer windows.CloseHandle(h)
+
+	var s sourceState
+	var basic fileBasicInfo
+	if err := windows.GetFileInformationByHandleEx(h, windows.FileBasicInfo,
+		(*byte)(unsafe.Pointer(&basic)), uint32(unsafe.Sizeof(basic))); err != nil {
+		return sourceState{}, err
+	}
+	s.changeTime = basic.ChangeTime
+
+	var fi windows.ByHandleFileInformation
+	if err := windows.GetFileInformationByHandle(h, &fi); err != nil {
+		return sourceState{}, err
+	}
+	s.size = int64(fi.FileSizeHigh)<<32 | int64(fi.FileSizeLow)
+
+	// File systems without a change journal are left to the change time
+	// and size.
+	rec := make([]byte, 1024)
+	if n, err := fsctl.Call(h, windows.FSCTL_READ_FILE_USN_DATA, nil, rec); err == nil && n >= 48 {
+		// USN_RECORD_V3 has 128-bit file references.
+		off := 24
+		if binary.LittleEndian.Uint16(rec[4:]) == 3 {
+			off = 40
+		}
+		s.usn = int64(binary.LittleEndian.Uint64(rec[off:]))
+	}
+
+	return s, nil
+}
+
+// copyVerified copies src to dst while holding a read lease on src, and
+// reports whether src changed during the copy, as told by a break of the
+// lease or by its state.
+func copyVerified(src, dst string, from, to *uint16, flags uint32) (changed bool, err error) {
+	// The state alone is compared on file systems which do not grant
+	// leases, and while src is open for writing.
+	lease, err := oplock.Acquire(src, oplock.Read)
+	if err == nil {
+		defer lease.Close()
+	}
+
+	before, err := stateOf(src)
+	if err != nil {
+		return false, err
+	}
+
+	if err := copyFile(src, dst, from, to, flags); err != nil {
+		return false, err
+	}
+
+	after, err := stateOf(src)
+	if err != nil {
+		return false, err
+	}
+	if after != before {
+		return true, nil
+	}
+
+	if lease != nil {
+		select {
+		case <-lease.Breaks():
+			return true, nil
+		default:
+		}
+	}
+
+	return false, nil
+}
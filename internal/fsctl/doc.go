@@ -0,0 +1,3 @@
+// Package fsctl contains the DeviceIoControl plumbing shared by the
+// packages in this module.
+package fsctl
@@ -0,0 +1,63 @@
+package fsctl
+
+import (
+	"os"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// ShareAll grants every sharing mode, so that handles opened for
+// metadata access do not get in the way of other users of the file.
+const ShareAll = windows.FILE_SHARE_READ | windows.FILE_SHARE_WRITE | windows.FILE_SHARE_DELETE
+
+// Open opens path with the given access and additional flags for use
+// with DeviceIoControl. Backup semantics are always requested so that
+// directories can be opened as well.
+func Open(path string, access, flags uint32) (windows.Handle, error) {
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return windows.InvalidHandle, &os.PathError{Op: "open", Path: path, Err: err}
+	}
+
+	h, err := windows.CreateFile(p, access, ShareAll, nil, windows.OPEN_EXISTING,
+		windows.FILE_FLAG_BACKUP_SEMANTICS|flags, 0)
+	if err != nil {
+		return windows.InvalidHandle, &os.PathError{Op: "open", Path: path, Err: err}
+	}
+
+	return h, nil
+}
+
+// Call issues the control code on h with in and out as the input and
+// output buffers, and returns the number of bytes written to out.
+func Call(h windows.Handle, code uint32, in, out []byte) (int, error) {
+	var inPtr, outPtr *byte
+	if len(in) > 0 {
+		inPtr = &in[0]
+	}
+	if len(out) > 0 {
+		outPtr = &out[0]
+	}
+
+	var n uint32
+	err := windows.DeviceIoControl(h, code, inPtr, uint32(len(in)), outPtr, uint32(len(out)), &n, nil)
+
+	return int(n), err
+}
+
+// CallStruct issues the control code on h with fixed size input and
+// output structures. Either pointer may be nil.
+func CallStruct[I, O any](h windows.Handle, code uint32, in *I, out *O) error {
+	var inPtr, outPtr *byte
+	var inLen, outLen uint32
+	if in != nil {
+		inPtr, inLen = (*byte)(unsafe.Pointer(in)), uint32(unsafe.Sizeof(*in))
+	}
+	if out != nil {
+		outPtr, outLen = (*byte)(unsafe.Pointer(out)), uint32(unsafe.Sizeof(*out))
+	}
+
+	var n uint32
+	return windows.DeviceIoControl(h, code, inPtr, inLen, outPtr, outLen, &n, nil)
+}
@@ -0,0 +1,30 @@
+package fsctl
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// FileCompressionInfo is FILE_COMPRESSION_INFO.
+type FileCompressionInfo struct {
+	CompressedFileSize   int64
+	CompressionFormat    uint16
+	CompressionUnitShift uint8
+	ChunkShift           uint8
+	ClusterShift         uint8
+	Reserved             [3]uint8
+}
+
+// CompressionInfo returns the FILE_COMPRESSION_INFO of the file opened as
+// h, which holds the space used by compressed and sparse files.
+func CompressionInfo(h windows.Handle) (*FileCompressionInfo, error) {
+	var ci FileCompressionInfo
+	err := windows.GetFileInformationByHandleEx(h, windows.FileCompressionInfo,
+		(*byte)(unsafe.Pointer(&ci)), uint32(unsafe.Sizeof(ci)))
+	if err != nil {
+		return nil, err
+	}
+
+	return &ci, nil
+}
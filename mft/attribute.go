@@ -0,0 +1,197 @@
+package mft
+
+import (
+	"encoding/binary"
+	"fmt"
+	"unicode/utf16"
+)
+
+// AttributeType is the type code of an attribute.
+type AttributeType uint32
+
+const (
+	AttrStandardInformation AttributeType = 0x10
+	AttrAttributeList       AttributeType = 0x20
+	AttrFileName            AttributeType = 0x30
+	AttrObjectID            AttributeType = 0x40
+	AttrSecurityDescriptor  AttributeType = 0x50
+	AttrVolumeName          AttributeType = 0x60
+	AttrVolumeInformation   AttributeType = 0x70
+	AttrData                AttributeType = 0x80
+	AttrIndexRoot           AttributeType = 0x90
+	AttrIndexAllocation     AttributeType = 0xA0
+	AttrBitmap              AttributeType = 0xB0
+	AttrReparsePoint        AttributeType = 0xC0
+	AttrEAInformation       AttributeType = 0xD0
+	AttrEA                  AttributeType = 0xE0
+	AttrLoggedUtilityStream AttributeType = 0x100
+
+	attrEnd AttributeType = 0xFFFFFFFF
+)
+
+var attributeNames = map[AttributeType]string{
+	AttrStandardInformation: "$STANDARD_INFORMATION",
+	AttrAttributeList:       "$ATTRIBUTE_LIST",
+	AttrFileName:            "$FILE_NAME",
+	AttrObjectID:            "$OBJECT_ID",
+	AttrSecurityDescriptor:  "$SECURITY_DESCRIPTOR",
+	AttrVolumeName:          "$VOLUME_NAME",
+	AttrVolumeInformation:   "$VOLUME_INFORMATION",
+	AttrData:                "$DATA",
+	AttrIndexRoot:           "$INDEX_ROOT",
+	AttrIndexAllocation:     "$INDEX_ALLOCATION",
+	AttrBitmap:              "$BITMAP",
+	AttrReparsePoint:        "$REPARSE_POINT",
+	AttrEAInformation:       "$EA_INFORMATION",
+	AttrEA:                  "$EA",
+	AttrLoggedUtilityStream: "$LOGGED_UTILITY_STREAM",
+}
+
+func (t AttributeType) String() string {
+	if s, ok := attributeNames[t]; ok {
+		return s
+	}
+
+	return fmt.Sprintf("AttributeType(0x%X)", uint32(t))
+}
+
+// AttributeFlags are the flags of an attribute header.
+type AttributeFlags uint16
+
+const (
+	AttrCompressed AttributeFlags = 0x0001 // ATTRIBUTE_FLAG_COMPRESSION_MASK
+	AttrEncrypted  AttributeFlags = 0x4000 // ATTRIBUTE_FLAG_ENCRYPTED
+	AttrSparse     AttributeFlags = 0x8000 // ATTRIBUTE_FLAG_SPARSE
+)
+
+// Attribute is an attribute stored in a FILE record. Its value is either
+// resident, stored in the record itself, or non-resident, stored in
+// clusters described by mapping pairs.
+type Attribute struct {
+	Type     AttributeType
+	Name     string
+	Flags    AttributeFlags
+	Instance uint16
+	Resident bool
+
+	// Value is the value of a resident attribute.
+	Value []byte
+
+	// LowestVCN and HighestVCN are the range of virtual clusters
+	// described by a non-resident attribute, which may be split across
+	// several records.
+	LowestVCN  int64
+	HighestVCN int64
+	// CompressionUnit is the log2 of the number of clusters in a
+	// compression unit, or 0 if the attribute is not compressed.
+	CompressionUnit uint8
+	AllocatedSize   int64
+	DataSize        int64
+	InitializedSize int64
+	// CompressedSize is the number of bytes allocated to a compressed
+	// or sparse attribute.
+	CompressedSize int64
+
+	mappingPairs []byte
+}
+
+// Size returns the size of the value of the attribute.
+func (a *Attribute) Size() int64 {
+	if a.Resident {
+		return int64(len(a.Value))
+	}
+
+	return a.DataSize
+}
+
+// parseAttributes parses the attributes of a record, starting at off.
+func parseAttributes(buf []byte, off int) ([]Attribute, error) {
+	var attrs []Attribute
+	for {
+		if off+4 > len(buf) {
+			return nil, ErrCorrupt
+		}
+		t := AttributeType(binary.LittleEndian.Uint32(buf[off:]))
+		if t == attrEnd {
+			return attrs, nil
+		}
+		if off+16 > len(buf) {
+			return nil, ErrCorrupt
+		}
+
+		length := int(binary.LittleEndian.Uint32(buf[off+4:]))
+		if length < 16 || off+length > len(buf) {
+			return nil, ErrCorrupt
+		}
+
+		a, err := parseAttribute(buf[off : off+length])
+		if err != nil {
+			return nil, err
+		}
+		attrs = append(attrs, *a)
+		off += length
+	}
+}
+
+// parseAttribute parses the attribute record in buf.
+func parseAttribute(buf []byte) (*Attribute, error) {
+	a := &Attribute{
+		Type:     AttributeType(binary.LittleEndian.Uint32(buf[0:])),
+		Resident: buf[8] == 0,
+		Flags:    AttributeFlags(binary.LittleEndian.Uint16(buf[12:])),
+		Instance: binary.LittleEndian.Uint16(buf[14:]),
+	}
+
+	if n := int(buf[9]); n > 0 {
+		off := int(binary.LittleEndian.Uint16(buf[10:]))
+		if off+2*n > len(buf) {
+			return nil, ErrCorrupt
+		}
+		a.Name = decodeName(buf[off : off+2*n])
+	}
+
+	if a.Resident {
+		if len(buf) < 24 {
+			return nil, ErrCorrupt
+		}
+		size := int(binary.LittleEndian.Uint32(buf[16:]))
+		off := int(binary.LittleEndian.Uint16(buf[20:]))
+		if off+size > len(buf) {
+			return nil, ErrCorrupt
+		}
+		a.Value = buf[off : off+size]
+
+		return a, nil
+	}
+
+	if len(buf) < 64 {
+		return nil, ErrCorrupt
+	}
+	a.LowestVCN = int64(binary.LittleEndian.Uint64(buf[16:]))
+	a.HighestVCN = int64(binary.LittleEndian.Uint64(buf[24:]))
+	a.CompressionUnit = buf[34]
+	a.AllocatedSize = int64(binary.LittleEndian.Uint64(buf[40:]))
+	a.DataSize = int64(binary.LittleEndian.Uint64(buf[48:]))
+	a.InitializedSize = int64(binary.LittleEndian.Uint64(buf[56:]))
+	if a.Flags&(AttrCompressed|AttrSparse) != 0 && len(buf) >= 72 {
+		a.CompressedSize = int64(binary.LittleEndian.Uint64(buf[64:]))
+	}
+
+	off := int(binary.LittleEndian.Uint16(buf[32:]))
+	if off > len(buf) {
+		return nil, ErrCorrupt
+	}
+	a.mappingPairs = buf[off:]
+
+	return a, nil
+}
+
+// decodeName decodes a little-endian UTF-16 name.
+func decodeName(b []byte) string {
+	u := make([]uint16, len(b)/2)
+	for i := range u {
+		u[i] = binary.LittleEndian.Uint16(b[2*i:])
+	}
+
+	return string(utf16.Decode(u))
+}
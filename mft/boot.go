@@ -0,0 +1,92 @@
+package mft
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+var ErrNotNTFS = errors.New("mft: not an NTFS boot sector")
+
+// bootSectorSize is the size of the part of the NTFS boot sector read by
+// ParseBootSector.
+const bootSectorSize = 512
+
+// BootSector holds the geometry of an NTFS volume, as found in its boot
+// sector.
+type BootSector struct {
+	BytesPerSector    uint16
+	SectorsPerCluster uint32
+	TotalSectors      uint64
+	MftLCN            int64
+	MftMirrLCN        int64
+	// RecordSize is the size of a FILE record in bytes.
+	RecordSize uint32
+	// IndexBlockSize is the size of an index buffer in bytes.
+	IndexBlockSize uint32
+	SerialNumber   uint64
+}
+
+// BytesPerCluster returns the size of a cluster in bytes.
+func (b *BootSector) BytesPerCluster() int64 {
+	return int64(b.BytesPerSector) * int64(b.SectorsPerCluster)
+}
+
+// ParseBootSector parses the boot sector found at the start of an NTFS
+// volume. It returns ErrNotNTFS if buf does not hold one, or if its record
+// or index buffer size is not a power of two from 1KiB to 64KiB.
+func ParseBootSector(buf []byte) (*BootSector, error) {
+	if len(buf) < bootSectorSize || string(buf[3:11]) != "NTFS    " {
+		return nil, ErrNotNTFS
+	}
+
+	b := &BootSector{
+		BytesPerSector: binary.LittleEndian.Uint16(buf[0x0B:]),
+		TotalSectors:   binary.LittleEndian.Uint64(buf[0x28:]),
+		MftLCN:         int64(binary.LittleEndian.Uint64(buf[0x30:])),
+		MftMirrLCN:     int64(binary.LittleEndian.Uint64(buf[0x38:])),
+		SerialNumber:   binary.LittleEndian.Uint64(buf[0x48:]),
+	}
+
+	// Cluster sizes above 64KiB store the power of two as a negative
+	// number.
+	if spc := buf[0x0D]; spc <= 0x80 {
+		b.SectorsPerCluster = uint32(spc)
+	} else {
+		b.SectorsPerCluster = 1 << (256 - uint32(spc))
+	}
+	if b.BytesPerSector == 0 || b.BytesPerSector&(b.BytesPerSector-1) != 0 || b.SectorsPerCluster == 0 {
+		return nil, ErrNotNTFS
+	}
+
+	record := b.clustersToBytes(int8(buf[0x40]))
+	index := b.clustersToBytes(int8(buf[0x44]))
+	if !validBlockSize(record) || !validBlockSize(index) {
+		return nil, ErrNotNTFS
+	}
+	b.RecordSize, b.IndexBlockSize = uint32(record), uint32(index)
+
+	return b, nil
+}
+
+// Bounds of the sizes of FILE records and index buffers.
+const (
+	minBlockSize = 1 << 10
+	maxBlockSize = 64 << 10
+)
+
+// validBlockSize reports whether n is a plausible size for FILE records
+// and index buffers, which are powers of two from 1KiB to 64KiB.
+func validBlockSize(n int64) bool {
+	return n >= minBlockSize && n <= maxBlockSize && n&(n-1) == 0
+}
+
+// clustersToBytes decodes the size of records and index buffers, which
+// are given in clusters, or as the negated power of two of their size in
+// bytes when smaller than a cluster.
+func (b *BootSector) clustersToBytes(n int8) int64 {
+	if n < 0 {
+		return 1 << uint(-int(n))
+	}
+
+	return int64(n) * b.BytesPerCluster()
+}
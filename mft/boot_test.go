@@ -0,0 +1,89 @@
+package mft
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// bootSector returns an NTFS boot sector with 512-byte sectors, 8 sectors
+// per cluster, and the given record and index buffer sizes.
+func bootSector(record, index int8) []byte {
+	buf := make([]byte, bootSectorSize)
+	copy(buf[3:], "NTFS    ")
+	binary.LittleEndian.PutUint16(buf[0x0B:], 512)
+	buf[0x0D] = 8
+	binary.LittleEndian.PutUint64(buf[0x28:], 1<<20)
+	binary.LittleEndian.PutUint64(buf[0x30:], 0xC0000)
+	binary.LittleEndian.PutUint64(buf[0x38:], 2)
+	buf[0x40] = byte(record)
+	buf[0x44] = byte(index)
+	binary.LittleEndian.PutUint64(buf[0x48:], 0x1234)
+
+	return buf
+}
+
+func TestParseBootSector(t *testing.T) {
+	b, err := ParseBootSector(bootSector(-10, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := BootSector{
+		BytesPerSector:    512,
+		SectorsPerCluster: 8,
+		TotalSectors:      1 << 20,
+		MftLCN:            0xC0000,
+		MftMirrLCN:        2,
+		RecordSize:        1024,
+		IndexBlockSize:    4096,
+		SerialNumber:      0x1234,
+	}
+	if *b != want {
+		t.Errorf("boot sector = %+v, want %+v", *b, want)
+	}
+	if n := b.BytesPerCluster(); n != 4096 {
+		t.Errorf("BytesPerCluster = %d, want 4096", n)
+	}
+}
+
+func TestParseBootSectorLargeClusters(t *testing.T) {
+	buf := bootSector(-12, -16)
+	buf[0x0D] = 0xF4 // 2^12 sectors per cluster
+	b, err := ParseBootSector(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.SectorsPerCluster != 4096 || b.RecordSize != 4096 || b.IndexBlockSize != 64<<10 {
+		t.Errorf("boot sector = %+v", *b)
+	}
+}
+
+func TestParseBootSectorErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		buf  []byte
+	}{
+		{"short", bootSector(-10, 1)[:bootSectorSize-1]},
+		{"signature", func() []byte { b := bootSector(-10, 1); b[3] = 'X'; return b }()},
+		{"sector size", func() []byte { b := bootSector(-10, 1); b[0x0B] = 0x01; return b }()},
+		{"no sectors per cluster", func() []byte { b := bootSector(-10, 1); b[0x0D] = 0; return b }()},
+		{"small record", bootSector(-9, 1)},
+		{"huge record", bootSector(-31, 1)},
+		{"negated record shift", bootSector(-128, 1)},
+		{"record of many clusters", bootSector(32, 1)},
+		{"zero record", bootSector(0, 1)},
+		{"record not a power of two", bootSector(3, 1)},
+		{"small index buffer", bootSector(-10, -9)},
+		{"huge index buffer", bootSector(-10, -17)},
+		{"zero index buffer", bootSector(-10, 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseBootSector(tt.buf); !errors.Is(err, ErrNotNTFS) {
+				t.Errorf("err = %v, want %v", err, ErrNotNTFS)
+			}
+		})
+	}
+}
@@ -0,0 +1,314 @@
+package mft
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrAttributeType = errors.New("mft: unexpected attribute type")
+	ErrNonResident   = errors.New("mft: attribute value is not resident")
+	ErrResident      = errors.New("mft: attribute value is resident")
+)
+
+// FileNamespace is the namespace a $FILE_NAME belongs to.
+type FileNamespace uint8
+
+const (
+	NamespacePOSIX    FileNamespace = 0 // case-sensitive name
+	NamespaceWin32    FileNamespace = 1 // long name with a separate DOS name
+	NamespaceDOS      FileNamespace = 2 // 8.3 short name
+	NamespaceWin32DOS FileNamespace = 3 // long name which also is a valid 8.3 name
+)
+
+// StandardInformation is the value of a $STANDARD_INFORMATION attribute.
+type StandardInformation struct {
+	CreationTime     time.Time
+	ModificationTime time.Time
+	// ChangeTime is the time the FILE record last changed.
+	ChangeTime time.Time
+	AccessTime time.Time
+	// FileAttributes are the FILE_ATTRIBUTE_* flags of the file.
+	FileAttributes  uint32
+	MaximumVersions uint32
+	VersionNumber   uint32
+	ClassID         uint32
+
+	// The following fields are only present in NTFS 3.0 and later, and
+	// are zero otherwise.
+
+	OwnerID uint32
+	// SecurityID indexes the security descriptor of the file in $Secure.
+	SecurityID   uint32
+	QuotaCharged uint64
+	// Usn is the update sequence number of the last change journaled.
+	Usn uint64
+}
+
+// FileName is the value of a $FILE_NAME attribute. The timestamps and
+// sizes are only updated when the name is, and may be out of date.
+type FileName struct {
+	// Parent references the directory holding the name.
+	Parent           FileReference
+	CreationTime     time.Time
+	ModificationTime time.Time
+	ChangeTime       time.Time
+	AccessTime       time.Time
+	AllocatedSize    int64
+	Size             int64
+	FileAttributes   uint32
+	// ReparseTag is the reparse point tag of the file, which replaces the
+	// size of its extended attributes when set.
+	ReparseTag uint32
+	Namespace  FileNamespace
+	Name       string
+}
+
+// AttributeListEntry is an entry of an $ATTRIBUTE_LIST attribute, locating
+// an attribute of a file stored in one of its records.
+type AttributeListEntry struct {
+	Type      AttributeType
+	Name      string
+	LowestVCN int64
+	// Record references the record holding the attribute.
+	Record   FileReference
+	Instance uint16
+}
+
+// GUID is a GUID in its on-disk byte order.
+type GUID [16]byte
+
+// IsZero reports whether g is the null GUID.
+func (g GUID) IsZero() bool {
+	return g == GUID{}
+}
+
+func (g GUID) String() string {
+	return fmt.Sprintf("%08x-%04x-%04x-%x-%x",
+		binary.LittleEndian.Uint32(g[0:]), binary.LittleEndian.Uint16(g[4:]),
+		binary.LittleEndian.Uint16(g[6:]), g[8:10], g[10:])
+}
+
+// ObjectID is the value of an $OBJECT_ID attribute. Only the object ID
+// itself is mandatory; the birth IDs and domain ID are zero when absent.
+type ObjectID struct {
+	ObjectID      GUID
+	BirthVolumeID GUID
+	BirthObjectID GUID
+	DomainID      GUID
+}
+
+// Data describes the value of a $DATA attribute, that is a data stream of
+// the file. The unnamed stream has an empty name.
+type Data struct {
+	Name            string
+	Resident        bool
+	Size            int64
+	AllocatedSize   int64
+	InitializedSize int64
+	Compressed      bool
+	Encrypted       bool
+	Sparse          bool
+	// Value is the content of a resident stream.
+	Value []byte
+	// Runs are the data runs of a non-resident stream, or of the part of
+	// it described by this attribute.
+	Runs []DataRun
+}
+
+// StandardInformation decodes a $STANDARD_INFORMATION attribute.
+func (a *Attribute) StandardInformation() (*StandardInformation, error) {
+	v, err := a.residentValue(AttrStandardInformation, 48)
+	if err != nil {
+		return nil, err
+	}
+
+	si := &StandardInformation{
+		CreationTime:     filetime(v[0:]),
+		ModificationTime: filetime(v[8:]),
+		ChangeTime:       filetime(v[16:]),
+		AccessTime:       filetime(v[24:]),
+		FileAttributes:   binary.LittleEndian.Uint32(v[32:]),
+		MaximumVersions:  binary.LittleEndian.Uint32(v[36:]),
+		VersionNumber:    binary.LittleEndian.Uint32(v[40:]),
+		ClassID:          binary.LittleEndian.Uint32(v[44:]),
+	}
+	if len(v) >= 72 {
+		si.OwnerID = binary.LittleEndian.Uint32(v[48:])
+		si.SecurityID = binary.LittleEndian.Uint32(v[52:])
+		si.QuotaCharged = binary.LittleEndian.Uint64(v[56:])
+		si.Usn = binary.LittleEndian.Uint64(v[64:])
+	}
+
+	return si, nil
+}
+
+// FileName decodes a $FILE_NAME attribute.
+func (a *Attribute) FileName() (*FileName, error) {
+	v, err := a.residentValue(AttrFileName, 66)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeFileName(v)
+}
+
+// decodeFileName decodes the value of a $FILE_NAME attribute, as also
+// found in the keys of directory indexes.
+func decodeFileName(v []byte) (*FileName, error) {
+	n := int(v[64])
+	if 66+2*n > len(v) {
+		return nil, ErrCorrupt
+	}
+
+	return &FileName{
+		Parent:           FileReference(binary.LittleEndian.Uint64(v[0:])),
+		CreationTime:     filetime(v[8:]),
+		ModificationTime: filetime(v[16:]),
+		ChangeTime:       filetime(v[24:]),
+		AccessTime:       filetime(v[32:]),
+		AllocatedSize:    int64(binary.LittleEndian.Uint64(v[40:])),
+		Size:             int64(binary.LittleEndian.Uint64(v[48:])),
+		FileAttributes:   binary.LittleEndian.Uint32(v[56:]),
+		ReparseTag:       binary.LittleEndian.Uint32(v[60:]),
+		Namespace:        FileNamespace(v[65]),
+		Name:             decodeName(v[66 : 66+2*n]),
+	}, nil
+}
+
+// AttributeList decodes a resident $ATTRIBUTE_LIST attribute.
+func (a *Attribute) AttributeList() ([]AttributeListEntry, error) {
+	v, err := a.residentValue(AttrAttributeList, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return DecodeAttributeList(v)
+}
+
+// DecodeAttributeList decodes the value of an $ATTRIBUTE_LIST attribute,
+// which is needed for non-resident lists read separately.
+func DecodeAttributeList(v []byte) ([]AttributeListEntry, error) {
+	var entries []AttributeListEntry
+	for off := 0; off+26 <= len(v); {
+		length := int(binary.LittleEndian.Uint16(v[off+4:]))
+		if length < 26 || off+length > len(v) {
+			return nil, ErrCorrupt
+		}
+		e := v[off : off+length]
+
+		entry := AttributeListEntry{
+			Type:      AttributeType(binary.LittleEndian.Uint32(e[0:])),
+			LowestVCN: int64(binary.LittleEndian.Uint64(e[8:])),
+			Record:    FileReference(binary.LittleEndian.Uint64(e[16:])),
+			Instance:  binary.LittleEndian.Uint16(e[24:]),
+		}
+		if n := int(e[6]); n > 0 {
+			nameOff := int(e[7])
+			if nameOff+2*n > len(e) {
+				return nil, ErrCorrupt
+			}
+			entry.Name = decodeName(e[nameOff : nameOff+2*n])
+		}
+
+		entries = append(entries, entry)
+		off += length
+	}
+
+	return entries, nil
+}
+
+// ObjectID decodes an $OBJECT_ID attribute.
+func (a *Attribute) ObjectID() (*ObjectID, error) {
+	v, err := a.residentValue(AttrObjectID, 16)
+	if err != nil {
+		return nil, err
+	}
+
+	var oid ObjectID
+	copy(oid.ObjectID[:], v)
+	if len(v) >= 64 {
+		copy(oid.BirthVolumeID[:], v[16:])
+		copy(oid.BirthObjectID[:], v[32:])
+		copy(oid.DomainID[:], v[48:])
+	}
+
+	return &oid, nil
+}
+
+// Data decodes a $DATA attribute.
+func (a *Attribute) Data() (*Data, error) {
+	if a.Type != AttrData {
+		return nil, ErrAttributeType
+	}
+
+	d := &Data{
+		Name:       a.Name,
+		Resident:   a.Resident,
+		Compressed: a.Flags&AttrCompressed != 0,
+		Encrypted:  a.Flags&AttrEncrypted != 0,
+		Sparse:     a.Flags&AttrSparse != 0,
+	}
+	if a.Resident {
+		d.Size = int64(len(a.Value))
+		d.AllocatedSize = d.Size
+		d.InitializedSize = d.Size
+		d.Value = a.Value
+		return d, nil
+	}
+
+	d.Size = a.DataSize
+	d.AllocatedSize = a.AllocatedSize
+	d.InitializedSize = a.InitializedSize
+
+	runs, err := a.DataRuns()
+	if err != nil {
+		return nil, err
+	}
+	d.Runs = runs
+
+	return d, nil
+}
+
+// DataRuns decodes the mapping pairs of a non-resident attribute.
+func (a *Attribute) DataRuns() ([]DataRun, error) {
+	if a.Resident {
+		return nil, ErrResident
+	}
+
+	return DecodeDataRuns(a.mappingPairs, a.LowestVCN)
+}
+
+// residentValue returns the value of a resident attribute of type t,
+// checking that it is at least minLen bytes long.
+func (a *Attribute) residentValue(t AttributeType, minLen int) ([]byte, error) {
+	if a.Type != t {
+		return nil, ErrAttributeType
+	}
+	if !a.Resident {
+		return nil, ErrNonResident
+	}
+	if len(a.Value) < minLen {
+		return nil, ErrCorrupt
+	}
+
+	return a.Value, nil
+}
+
+// filetimeEpoch is the number of 100ns intervals between 1601-01-01 and
+// 1970-01-01.
+const filetimeEpoch = 116444736000000000
+
+// filetime decodes a little-endian FILETIME. Zero decodes to the zero
+// time.
+func filetime(b []byte) time.Time {
+	ft := int64(binary.LittleEndian.Uint64(b))
+	if ft == 0 {
+		return time.Time{}
+	}
+
+	ft -= filetimeEpoch
+	return time.Unix(ft/1e7, ft%1e7*100)
+}
@@ -0,0 +1,90 @@
+package mft
+
+import (
+	"errors"
+	"testing"
+)
+
+// attributeListEntry encodes an ATTRIBUTE_LIST_ENTRY.
+func attributeListEntry(e AttributeListEntry) []byte {
+	n := encodeName(e.Name)
+	length := (26 + len(n) + 7) &^ 7
+
+	b := make([]byte, length)
+	le.PutUint32(b[0:], uint32(e.Type))
+	le.PutUint16(b[4:], uint16(length))
+	b[6] = byte(len(n) / 2)
+	b[7] = 26
+	le.PutUint64(b[8:], uint64(e.LowestVCN))
+	le.PutUint64(b[16:], uint64(e.Record))
+	le.PutUint16(b[24:], e.Instance)
+	copy(b[26:], n)
+
+	return b
+}
+
+func TestDecodeAttributeList(t *testing.T) {
+	want := []AttributeListEntry{
+		{Type: AttrStandardInformation, Record: NewFileReference(9, 9)},
+		{Type: AttrData, Name: "$SDS", Record: NewFileReference(30, 2), Instance: 4},
+		{Type: AttrData, Name: "$SDS", LowestVCN: 0x40, Record: NewFileReference(31, 2), Instance: 1},
+	}
+	var v []byte
+	for _, e := range want {
+		v = append(v, attributeListEntry(e)...)
+	}
+
+	got, err := DecodeAttributeList(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("entry %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestDecodeAttributeListErrors(t *testing.T) {
+	entry := func() []byte {
+		return attributeListEntry(AttributeListEntry{Type: AttrData, Name: "$SDS", Record: 30})
+	}
+
+	tests := []struct {
+		name string
+		v    func() []byte
+	}{
+		{"short length", func() []byte {
+			b := entry()
+			le.PutUint16(b[4:], 16)
+			return b
+		}},
+		{"length beyond value", func() []byte {
+			b := entry()
+			le.PutUint16(b[4:], uint16(len(b)+8))
+			return b
+		}},
+		{"name beyond entry", func() []byte {
+			b := entry()
+			b[6] = 20
+			return b
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecodeAttributeList(tt.v()); !errors.Is(err, ErrCorrupt) {
+				t.Errorf("err = %v, want %v", err, ErrCorrupt)
+			}
+		})
+	}
+
+	// Trailing bytes too short for an entry are ignored.
+	got, err := DecodeAttributeList(append(entry(), 0, 0, 0))
+	if err != nil || len(got) != 1 {
+		t.Errorf("got %v, %v, want 1 entry", got, err)
+	}
+}
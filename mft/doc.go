@@ -0,0 +1,7 @@
+// Package mft reads the master file table of an NTFS volume and parses
+// its FILE records, giving a view of the on-disk metadata of every file
+// without going through the file system.
+//
+// The parsing code works on any io.ReaderAt exposing the raw volume, such
+// as a disk image. On Windows, Open reads a mounted volume directly.
+package mft
@@ -0,0 +1,292 @@
+package mft
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"time"
+)
+
+// rootRecord is the record number of the root directory.
+const rootRecord = 5
+
+// maxDepth bounds the depth of the paths built by Enumerate, to stop on
+// parent reference loops found in corrupt tables.
+const maxDepth = 1024
+
+// SkipAll can be returned by the function passed to Enumerate to stop the
+// enumeration without reporting an error.
+var SkipAll = errors.New("skip all entries")
+
+// Entry describes a file found while enumerating the MFT.
+type Entry struct {
+	Reference FileReference
+	Parent    FileReference
+	// Name is the long name of the file.
+	Name string
+	// Path is the path of the file relative to the root of the volume,
+	// using backslashes as separators. The root directory has an empty
+	// path.
+	Path string
+	// Orphan is set if a parent directory of the file could not be
+	// found, in which case Path starts at the topmost parent found.
+	Orphan    bool
+	Directory bool
+	// Size is the size of the unnamed data stream of the file.
+	Size int64
+	// Streams are the named data streams of the file, along with the
+	// content of those which are resident.
+	Streams []Stream
+	// FileAttributes are the FILE_ATTRIBUTE_* flags of the file.
+	FileAttributes uint32
+	// SecurityID identifies the security descriptor of the file, which
+	// can be read with Secure.Descriptor.
+	SecurityID       uint32
+	CreationTime     time.Time
+	ModificationTime time.Time
+	ChangeTime       time.Time
+	AccessTime       time.Time
+
+	// Deleted is set for the entries of deleted files reported when
+	// Options.Deleted is set. The record of a deleted file may be reused
+	// at any time, and its clusters may already belong to other files.
+	Deleted bool
+	// Runs are the data runs of the unnamed data stream of a deleted
+	// file, from which its content may be recovered.
+	Runs []DataRun
+	// Data is the content of the unnamed data stream of a deleted file
+	// if it is resident.
+	Data []byte
+}
+
+// Options control which records Table.EnumerateWith reports.
+type Options struct {
+	// Deleted reports the records whose in-use flag is clear but whose
+	// attributes still decode, as the entries of deleted files.
+	Deleted bool
+}
+
+// Enumerate calls fn for every file of the volume, after building the path
+// of every file from the parent references of the $FILE_NAME attributes
+// read in a single sequential pass over the table. Records which cannot be
+// parsed are skipped. If fn returns an error, Enumerate stops and returns
+// it, unless it is SkipAll.
+func (t *Table) Enumerate(fn func(Entry) error) error {
+	return t.EnumerateWith(nil, fn)
+}
+
+// EnumerateWith is like Enumerate, with the records reported controlled by
+// opts. A nil opts is the same as the zero Options.
+func (t *Table) EnumerateWith(opts *Options, fn func(Entry) error) error {
+	if opts == nil {
+		opts = new(Options)
+	}
+
+	entries, err := t.collect(opts)
+	if err != nil {
+		return err
+	}
+
+	byNumber := make(map[uint64]int, len(entries))
+	for i := range entries {
+		byNumber[entries[i].Reference.RecordNumber()] = i
+	}
+
+	paths := make(map[uint64]resolved, len(entries))
+	for i := range entries {
+		e := &entries[i]
+		r := resolvePath(entries, byNumber, paths, e.Reference.RecordNumber(), 0)
+		e.Path, e.Orphan = r.path, r.orphan
+
+		if err := fn(*e); err != nil {
+			if errors.Is(err, SkipAll) {
+				return nil
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// collect reads the entries of the files selected by opts from the table.
+func (t *Table) collect(opts *Options) ([]Entry, error) {
+	var (
+		entries []Entry
+		index   = make(map[uint64]int)
+		// extNames and extStreams hold names and streams found in
+		// extension records, whose base record may come later in the
+		// table.
+		extNames   = make(map[uint64]*FileName)
+		extStreams = make(map[uint64][]Stream)
+	)
+
+	for rec, err := range t.Records() {
+		if err != nil {
+			var recErr *RecordError
+			if errors.As(err, &recErr) {
+				continue
+			}
+			return nil, err
+		}
+		if !rec.InUse() {
+			if opts.Deleted && rec.IsBase() {
+				if e, ok := deletedEntry(rec); ok {
+					index[rec.Number] = len(entries)
+					entries = append(entries, e)
+				}
+			}
+			continue
+		}
+
+		if !rec.IsBase() {
+			base := rec.BaseRecord.RecordNumber()
+			if fn := longName(rec); fn != nil {
+				extNames[base] = fn
+			}
+			extStreams[base] = append(extStreams[base], rec.Streams()...)
+			continue
+		}
+
+		index[rec.Number] = len(entries)
+		entries = append(entries, newEntry(rec))
+	}
+
+	for number, fn := range extNames {
+		if i, ok := index[number]; ok && entries[i].Name == "" {
+			e := &entries[i]
+			e.Name, e.Parent = fn.Name, fn.Parent
+		}
+	}
+	for number, streams := range extStreams {
+		if i, ok := index[number]; ok {
+			entries[i].Streams = append(entries[i].Streams, streams...)
+		}
+	}
+
+	return entries, nil
+}
+
+// newEntry returns the entry describing the base record rec.
+func newEntry(rec *Record) Entry {
+	e := Entry{
+		Reference: rec.Reference(),
+		Directory: rec.IsDirectory(),
+	}
+
+	if a := rec.Attribute(AttrStandardInformation, ""); a != nil {
+		if si, err := a.StandardInformation(); err == nil {
+			e.FileAttributes = si.FileAttributes
+			e.SecurityID = si.SecurityID
+			e.CreationTime = si.CreationTime
+			e.ModificationTime = si.ModificationTime
+			e.ChangeTime = si.ChangeTime
+			e.AccessTime = si.AccessTime
+		}
+	}
+
+	if fn := longName(rec); fn != nil {
+		e.Name, e.Parent = fn.Name, fn.Parent
+		e.Size = fn.Size
+	}
+
+	if a := rec.Attribute(AttrData, ""); a != nil && a.LowestVCN == 0 {
+		e.Size = a.Size()
+	}
+	e.Streams = rec.Streams()
+
+	return e
+}
+
+// deletedEntry returns the entry describing the base record rec of a
+// deleted file, if enough of it is left to be worth reporting.
+func deletedEntry(rec *Record) (Entry, bool) {
+	e := newEntry(rec)
+	if e.Name == "" {
+		return Entry{}, false
+	}
+	e.Deleted = true
+
+	if a := rec.Attribute(AttrData, ""); a != nil && a.LowestVCN == 0 {
+		if a.Resident {
+			e.Data = bytes.Clone(a.Value)
+		} else if runs, err := a.DataRuns(); err == nil {
+			e.Runs = runs
+		}
+	}
+
+	return e, true
+}
+
+// longName returns the $FILE_NAME of rec in the Win32 or POSIX namespace,
+// falling back to the DOS name if it is the only one.
+func longName(rec *Record) *FileName {
+	var best *FileName
+	for _, a := range rec.AttributesOf(AttrFileName) {
+		fn, err := a.FileName()
+		if err != nil {
+			continue
+		}
+		if fn.Namespace != NamespaceDOS {
+			return fn
+		}
+		if best == nil {
+			best = fn
+		}
+	}
+
+	return best
+}
+
+type resolved struct {
+	path   string
+	orphan bool
+}
+
+// resolvePath returns the path of the record number, memoizing the paths
+// of directories in paths.
+func resolvePath(entries []Entry, byNumber map[uint64]int, paths map[uint64]resolved, number uint64, depth int) resolved {
+	if number == rootRecord {
+		return resolved{}
+	}
+	if r, ok := paths[number]; ok {
+		return r
+	}
+
+	i, ok := byNumber[number]
+	if !ok || depth > maxDepth {
+		return resolved{orphan: true}
+	}
+	e := &entries[i]
+
+	var r resolved
+	parent := e.Parent.RecordNumber()
+	if pi, ok := byNumber[parent]; !ok || parent == number ||
+		entries[pi].Reference.Sequence() != e.Parent.Sequence() {
+		// The parent was deleted or reused.
+		r = resolved{path: e.Name, orphan: true}
+	} else {
+		p := resolvePath(entries, byNumber, paths, parent, depth+1)
+		r = resolved{path: joinPath(p.path, e.Name), orphan: p.orphan}
+	}
+
+	if e.Directory {
+		paths[number] = r
+	}
+
+	return r
+}
+
+func joinPath(dir, name string) string {
+	if dir == "" {
+		return name
+	}
+
+	var b strings.Builder
+	b.Grow(len(dir) + 1 + len(name))
+	b.WriteString(dir)
+	b.WriteByte('\\')
+	b.WriteString(name)
+
+	return b.String()
+}
@@ -0,0 +1,29 @@
+package mft
+
+// Enumerate calls fn for every file of the NTFS volume containing vol, as
+// described by Table.Enumerate. Reading the MFT directly is much faster
+// than walking the directory tree, and is not hindered by directory ACLs
+// since the volume is opened with backup semantics; this requires
+// administrative rights.
+func Enumerate(vol string, fn func(Entry) error) error {
+	t, err := Open(vol)
+	if err != nil {
+		return err
+	}
+	defer t.Close()
+
+	return t.Enumerate(fn)
+}
+
+// EnumerateWith is like Enumerate, with the records reported controlled by
+// opts. Setting Options.Deleted reports deleted files which may still be
+// recovered.
+func EnumerateWith(vol string, opts *Options, fn func(Entry) error) error {
+	t, err := Open(vol)
+	if err != nil {
+		return err
+	}
+	defer t.Close()
+
+	return t.EnumerateWith(opts, fn)
+}
@@ -0,0 +1,172 @@
+package mft
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+
+	"github.com/go-sw/ntfs/volume"
+)
+
+// ExtentMismatchError reports the first difference found by
+// CompareExtents.
+type ExtentMismatchError struct {
+	// VCN is the first virtual cluster mapped differently.
+	VCN int64
+	// Parsed and Reported are the extents covering VCN in the parsed and
+	// the reported mapping, with a zero Length if there is none.
+	Parsed   volume.Extent
+	Reported volume.Extent
+}
+
+func (e *ExtentMismatchError) Error() string {
+	return fmt.Sprintf("mft: extents differ at VCN %d: parsed %+v, reported %+v", e.VCN, e.Parsed, e.Reported)
+}
+
+// Extents returns the data runs of a non-resident attribute as cluster
+// extents, with the unallocated tails of compression units marked as such.
+func (a *Attribute) Extents() ([]volume.Extent, error) {
+	runs, err := a.DataRuns()
+	if err != nil {
+		return nil, err
+	}
+
+	extents := runsToExtents(runs)
+	if a.CompressionUnit > 0 {
+		volume.MarkCompressionUnits(extents, 1<<a.CompressionUnit)
+	}
+
+	return extents, nil
+}
+
+// FileExtents returns the extents of the data stream with the given name
+// of the file described by the base record rec, gathering the parts of the
+// stream stored in extension records.
+func (t *Table) FileExtents(rec *Record, stream string) ([]volume.Extent, error) {
+	parts := dataParts(rec, stream)
+
+	if list := rec.Attribute(AttrAttributeList, ""); list != nil {
+		v, err := t.ReadAttribute(list)
+		if err != nil {
+			return nil, err
+		}
+		entries, err := DecodeAttributeList(v)
+		if err != nil {
+			return nil, err
+		}
+
+		seen := map[uint64]bool{rec.Number: true}
+		for _, e := range entries {
+			n := e.Record.RecordNumber()
+			if e.Type != AttrData || e.Name != stream || seen[n] {
+				continue
+			}
+			seen[n] = true
+
+			ext, err := t.RecordNumber(n)
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, dataParts(ext, stream)...)
+		}
+	}
+
+	if len(parts) == 0 {
+		return nil, nil
+	}
+	slices.SortFunc(parts, func(a, b *Attribute) int {
+		return cmp.Compare(a.LowestVCN, b.LowestVCN)
+	})
+
+	var extents []volume.Extent
+	for _, a := range parts {
+		if a.Resident {
+			return nil, nil
+		}
+		e, err := a.Extents()
+		if err != nil {
+			return nil, err
+		}
+		extents = append(extents, e...)
+	}
+
+	return extents, nil
+}
+
+// CompareExtents compares two mappings of the same stream, such as the
+// extents parsed from the MFT and those reported by the file system. Runs
+// split differently but mapping the same clusters are equal, and holes
+// compare equal whatever their kind. It returns nil if the mappings are
+// equal, or an *ExtentMismatchError.
+func CompareExtents(parsed, reported []volume.Extent) error {
+	a, b := mergeExtents(parsed), mergeExtents(reported)
+
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var ea, eb volume.Extent
+		if i < len(a) {
+			ea = a[i]
+		}
+		if i < len(b) {
+			eb = b[i]
+		}
+		if ea.VCN == eb.VCN && ea.LCN == eb.LCN && ea.Length == eb.Length {
+			continue
+		}
+
+		vcn := ea.VCN
+		if ea.Length == 0 || (eb.Length != 0 && eb.VCN < vcn) {
+			vcn = eb.VCN
+		}
+		return &ExtentMismatchError{VCN: vcn, Parsed: ea, Reported: eb}
+	}
+
+	return nil
+}
+
+// dataParts returns the non-resident $DATA attributes named stream of rec.
+func dataParts(rec *Record, stream string) []*Attribute {
+	var parts []*Attribute
+	for _, a := range rec.AttributesOf(AttrData) {
+		if a.Name == stream {
+			parts = append(parts, a)
+		}
+	}
+
+	return parts
+}
+
+func runsToExtents(runs []DataRun) []volume.Extent {
+	extents := make([]volume.Extent, len(runs))
+	for i, r := range runs {
+		extents[i] = volume.Extent{VCN: r.VCN, LCN: r.LCN, Length: r.Length}
+		if r.Sparse() {
+			extents[i].Kind = volume.ExtentSparse
+		}
+	}
+
+	return extents
+}
+
+// mergeExtents joins contiguous extents, and holes following each other,
+// so that mappings split differently compare equal.
+func mergeExtents(extents []volume.Extent) []volume.Extent {
+	var merged []volume.Extent
+	for _, e := range extents {
+		if !e.Allocated() {
+			e.LCN, e.Kind = -1, volume.ExtentSparse
+		}
+		if n := len(merged); n > 0 {
+			last := &merged[n-1]
+			contiguous := last.VCN+last.Length == e.VCN &&
+				((!last.Allocated() && !e.Allocated()) ||
+					(last.Allocated() && e.Allocated() && last.LCN+last.Length == e.LCN))
+			if contiguous {
+				last.Length += e.Length
+				continue
+			}
+		}
+		merged = append(merged, e)
+	}
+
+	return merged
+}
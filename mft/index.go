@@ -0,0 +1,120 @@
+package mft
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// Index entry flags.
+const (
+	indexEntryNode = 0x1 // INDEX_ENTRY_NODE, the entry points to a subnode
+	indexEntryEnd  = 0x2 // INDEX_ENTRY_END, the entry ends the node
+)
+
+// indexEntry is a key and its data in an index with view semantics, such
+// as the $SII and $SDH indexes of $Secure.
+type indexEntry struct {
+	key  []byte
+	data []byte
+}
+
+// indexEntries returns every entry of the index called name of rec,
+// walking the index root and all the index buffers in use, in no
+// particular order. The attributes of rec stored in extension records
+// must have been added with withExtensions.
+func (t *Table) indexEntries(rec *Record, name string) ([]indexEntry, error) {
+	root := rec.Attribute(AttrIndexRoot, name)
+	if root == nil || !root.Resident || len(root.Value) < 32 {
+		return nil, ErrCorrupt
+	}
+
+	entries, err := parseIndexNode(root.Value[16:])
+	if err != nil {
+		return nil, err
+	}
+
+	if rec.Attribute(AttrIndexAllocation, name) == nil {
+		return entries, nil
+	}
+
+	data, err := t.readAttribute(rec, AttrIndexAllocation, name)
+	if err != nil {
+		return nil, err
+	}
+	var bitmap []byte
+	if rec.Attribute(AttrBitmap, name) != nil {
+		if bitmap, err = t.readAttribute(rec, AttrBitmap, name); err != nil {
+			return nil, err
+		}
+	}
+
+	size := int(binary.LittleEndian.Uint32(root.Value[8:]))
+	if size < sectorStride {
+		return nil, ErrCorrupt
+	}
+	for i := 0; (i+1)*size <= len(data); i++ {
+		if bitmap != nil && (i/8 >= len(bitmap) || bitmap[i/8]&(1<<(i%8)) == 0) {
+			continue
+		}
+
+		block := data[i*size : (i+1)*size]
+		if string(block[0:4]) != "INDX" {
+			continue
+		}
+		if err := applyFixups(block); err != nil {
+			return nil, err
+		}
+
+		e, err := parseIndexNode(block[24:])
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e...)
+	}
+
+	return entries, nil
+}
+
+var errIndexEnd = errors.New("mft: index node without end entry")
+
+// parseIndexNode parses the entries of the node starting with the
+// INDEX_HEADER in buf.
+func parseIndexNode(buf []byte) ([]indexEntry, error) {
+	if len(buf) < 16 {
+		return nil, ErrCorrupt
+	}
+
+	first := int(binary.LittleEndian.Uint32(buf[0:]))
+	end := int(binary.LittleEndian.Uint32(buf[4:]))
+	if end > len(buf) {
+		return nil, ErrCorrupt
+	}
+
+	var entries []indexEntry
+	for off := first; off+16 <= end; {
+		e := buf[off:end]
+		length := int(binary.LittleEndian.Uint16(e[8:]))
+		flags := binary.LittleEndian.Uint16(e[12:])
+		if flags&indexEntryEnd != 0 {
+			return entries, nil
+		}
+		if length < 16 || length > len(e) {
+			return nil, ErrCorrupt
+		}
+
+		keyLen := int(binary.LittleEndian.Uint16(e[10:]))
+		dataOff := int(binary.LittleEndian.Uint16(e[0:]))
+		dataLen := int(binary.LittleEndian.Uint16(e[2:]))
+		if 16+keyLen > length || dataOff+dataLen > length {
+			return nil, ErrCorrupt
+		}
+
+		entries = append(entries, indexEntry{
+			key:  e[16 : 16+keyLen],
+			data: e[dataOff : dataOff+dataLen],
+		})
+		off += length
+	}
+
+	return nil, errIndexEnd
+}
@@ -0,0 +1,160 @@
+package mft
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// indexEntryBytes encodes an index entry with view semantics, whose data
+// follows its key.
+func indexEntryBytes(key, data []byte, flags uint16) []byte {
+	dataOff := (16 + len(key) + 3) &^ 3
+	length := (dataOff + len(data) + 7) &^ 7
+	if flags&indexEntryEnd != 0 {
+		dataOff, length = 0, 16
+	}
+
+	b := make([]byte, length)
+	if len(data) > 0 {
+		le.PutUint16(b[0:], uint16(dataOff))
+		le.PutUint16(b[2:], uint16(len(data)))
+		copy(b[dataOff:], data)
+	}
+	le.PutUint16(b[8:], uint16(length))
+	le.PutUint16(b[10:], uint16(len(key)))
+	le.PutUint16(b[12:], flags)
+	copy(b[16:], key)
+
+	return b
+}
+
+// indexNode returns an INDEX_HEADER followed at first by entries and an
+// end entry, padded to size bytes.
+func indexNode(size, first int, entries ...[]byte) []byte {
+	b := make([]byte, first, size)
+	for _, e := range entries {
+		b = append(b, e...)
+	}
+	b = append(b, indexEntryBytes(nil, nil, indexEntryEnd)...)
+
+	le.PutUint32(b[0:], uint32(first))
+	le.PutUint32(b[4:], uint32(len(b)))
+	le.PutUint32(b[8:], uint32(size))
+
+	return b[:size]
+}
+
+// indexBlock returns an INDX block of 4096 bytes holding the node.
+func indexBlock(node []byte) []byte {
+	const size = 4096
+	const usaOffset = 40
+
+	b := make([]byte, size)
+	copy(b, "INDX")
+	le.PutUint16(b[4:], usaOffset)
+	le.PutUint16(b[6:], size/sectorStride+1)
+	copy(b[24:], node)
+
+	return protect(b, usaOffset, 0x0102)
+}
+
+func TestParseIndexNode(t *testing.T) {
+	sii := indexEntryBytes([]byte{0x00, 0x01, 0, 0}, bytes.Repeat([]byte{1}, 20), 0)
+	sdh := indexEntryBytes([]byte{0xAA, 0xBB, 0xCC, 0xDD, 0x00, 0x01, 0, 0}, bytes.Repeat([]byte{2}, 20), 0)
+
+	entries, err := parseIndexNode(indexNode(256, 16, sii, sdh))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+	if le.Uint32(entries[0].key) != 0x100 || !bytes.Equal(entries[0].data, bytes.Repeat([]byte{1}, 20)) {
+		t.Errorf("entry 0 = %+v", entries[0])
+	}
+	if len(entries[1].key) != 8 || !bytes.Equal(entries[1].data, bytes.Repeat([]byte{2}, 20)) {
+		t.Errorf("entry 1 = %+v", entries[1])
+	}
+}
+
+func TestParseIndexNodeErrors(t *testing.T) {
+	entry := func() []byte {
+		return indexEntryBytes([]byte{1, 0, 0, 0}, make([]byte, 20), 0)
+	}
+
+	tests := []struct {
+		name string
+		node func() []byte
+		want error
+	}{
+		{"short header", func() []byte { return make([]byte, 8) }, ErrCorrupt},
+		{"end beyond node", func() []byte {
+			b := indexNode(128, 16, entry())
+			le.PutUint32(b[4:], 4096)
+			return b
+		}, ErrCorrupt},
+		{"entry length", func() []byte {
+			b := indexNode(128, 16, entry())
+			le.PutUint16(b[16+8:], 0x400)
+			return b
+		}, ErrCorrupt},
+		{"short entry", func() []byte {
+			b := indexNode(128, 16, entry())
+			le.PutUint16(b[16+8:], 8)
+			return b
+		}, ErrCorrupt},
+		{"key beyond entry", func() []byte {
+			b := indexNode(128, 16, entry())
+			le.PutUint16(b[16+10:], 0x100)
+			return b
+		}, ErrCorrupt},
+		{"data beyond entry", func() []byte {
+			b := indexNode(128, 16, entry())
+			le.PutUint16(b[16+2:], 0x100)
+			return b
+		}, ErrCorrupt},
+		{"no end entry", func() []byte {
+			b := indexNode(128, 16, entry())
+			le.PutUint32(b[4:], 16+uint32(len(entry())))
+			return b
+		}, errIndexEnd},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseIndexNode(tt.node()); !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestIndexBlock(t *testing.T) {
+	// The entries follow the update sequence array of the block.
+	node := indexNode(4096-24, 40, indexEntryBytes([]byte{1, 0, 0, 0}, make([]byte, 20), 0))
+
+	block := indexBlock(node)
+	if err := applyFixups(block); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := parseIndexNode(block[24:])
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("got %v, %v, want 1 entry", entries, err)
+	}
+
+	// A truncated block fails the fixups, and its node fails to parse.
+	truncated := indexBlock(node)[:1024]
+	if err := applyFixups(truncated); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("fixups of truncated block: err = %v, want %v", err, ErrCorrupt)
+	}
+	if _, err := parseIndexNode(truncated[24:40]); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("truncated node: err = %v, want %v", err, ErrCorrupt)
+	}
+
+	torn := indexBlock(node)
+	torn[3*sectorStride-2] = 0
+	if err := applyFixups(torn); !errors.Is(err, ErrFixup) {
+		t.Errorf("torn block: err = %v, want %v", err, ErrFixup)
+	}
+}
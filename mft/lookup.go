@@ -0,0 +1,252 @@
+package mft
+
+import (
+	"cmp"
+	"errors"
+	"slices"
+
+	"github.com/go-sw/ntfs/compress/lznt1"
+)
+
+var (
+	ErrRecordRange = errors.New("mft: record number beyond the end of the MFT")
+	ErrReused      = errors.New("mft: record reused by another file")
+	ErrCompressed  = errors.New("mft: attribute value is compressed")
+	ErrPartial     = errors.New("mft: attribute value continues in another record")
+)
+
+// maxAttributeSize bounds the size of the attribute values read by
+// ReadAttribute.
+const maxAttributeSize = 256 << 20
+
+// Record returns the record referenced by frn, a file reference number as
+// reported in USN records or by GetFileInformationByHandleEx. The record
+// is read directly from the MFT, so the file it describes need not exist
+// anymore.
+//
+// If frn carries a sequence number, Record fails with ErrReused when the
+// record now describes another file. A record freed by deleting the
+// referenced file has its sequence number incremented, and is returned.
+func (t *Table) Record(frn uint64) (*Record, error) {
+	ref := FileReference(frn)
+	rec, err := t.RecordNumber(ref.RecordNumber())
+	if err != nil {
+		return nil, err
+	}
+
+	if want := ref.Sequence(); want != 0 && rec.SequenceNumber != want {
+		if rec.InUse() || rec.SequenceNumber != want+1 {
+			return nil, &RecordError{Number: rec.Number, Err: ErrReused}
+		}
+	}
+
+	return rec, nil
+}
+
+// RecordNumber returns the record with the given number, whatever its
+// state.
+func (t *Table) RecordNumber(number uint64) (*Record, error) {
+	if number >= t.Count() {
+		return nil, &RecordError{Number: number, Err: ErrRecordRange}
+	}
+
+	size := int64(t.boot.RecordSize)
+	buf := make([]byte, size)
+	if err := t.readAt(buf, int64(number)*size); err != nil {
+		return nil, &RecordError{Number: number, Err: err}
+	}
+	if isEmptyRecord(buf) {
+		return nil, &RecordError{Number: number, Err: ErrBadSignature}
+	}
+
+	rec, err := ParseRecord(buf, number)
+	if err != nil {
+		return nil, &RecordError{Number: number, Err: err}
+	}
+
+	return rec, nil
+}
+
+// ReadAttribute returns the value of a, reading it from the volume if it
+// is not resident, and decompressing it if it is compressed. Values split
+// across several records are not supported.
+func (t *Table) ReadAttribute(a *Attribute) ([]byte, error) {
+	if a.Resident {
+		return a.Value, nil
+	}
+
+	return t.readParts([]*Attribute{a})
+}
+
+// withExtensions returns the base record rec along with the attributes
+// stored in the extension records listed in its $ATTRIBUTE_LIST, as those
+// of files with many attributes, or with fragmented ones, are.
+func (t *Table) withExtensions(rec *Record) (*Record, error) {
+	list := rec.Attribute(AttrAttributeList, "")
+	if list == nil {
+		return rec, nil
+	}
+
+	v, err := t.ReadAttribute(list)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := DecodeAttributeList(v)
+	if err != nil {
+		return nil, err
+	}
+
+	full := *rec
+	full.Attributes = slices.Clone(rec.Attributes)
+	seen := map[uint64]bool{rec.Number: true}
+	for _, e := range entries {
+		n := e.Record.RecordNumber()
+		if seen[n] {
+			continue
+		}
+		seen[n] = true
+
+		ext, err := t.RecordNumber(n)
+		if err != nil {
+			return nil, err
+		}
+		if ext.BaseRecord.RecordNumber() != rec.Number {
+			return nil, &RecordError{Number: n, Err: ErrCorrupt}
+		}
+		full.Attributes = append(full.Attributes, ext.Attributes...)
+	}
+
+	return &full, nil
+}
+
+// readAttribute returns the value of the attribute of rec with the given
+// type and name, gathering its parts if it is split across the records
+// added by withExtensions.
+func (t *Table) readAttribute(rec *Record, typ AttributeType, name string) ([]byte, error) {
+	var parts []*Attribute
+	for _, a := range rec.AttributesOf(typ) {
+		if a.Name == name {
+			parts = append(parts, a)
+		}
+	}
+	if len(parts) == 0 {
+		return nil, ErrCorrupt
+	}
+	if parts[0].Resident {
+		return parts[0].Value, nil
+	}
+
+	slices.SortFunc(parts, func(a, b *Attribute) int {
+		return cmp.Compare(a.LowestVCN, b.LowestVCN)
+	})
+
+	return t.readParts(parts)
+}
+
+// readParts returns the value of the non-resident attribute made of parts,
+// sorted by their first virtual cluster.
+func (t *Table) readParts(parts []*Attribute) ([]byte, error) {
+	// The sizes of the value are only valid in its first part.
+	a := parts[0]
+	compressed := a.Flags&AttrCompressed != 0
+	if compressed && a.CompressionUnit == 0 {
+		return nil, ErrCompressed
+	}
+	if a.LowestVCN != 0 {
+		return nil, ErrPartial
+	}
+	if a.DataSize > maxAttributeSize || a.DataSize < 0 {
+		return nil, ErrCorrupt
+	}
+
+	var runs []DataRun
+	highest := int64(-1)
+	for _, p := range parts {
+		if p.Resident || p.LowestVCN != highest+1 {
+			return nil, ErrPartial
+		}
+		r, err := p.DataRuns()
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, r...)
+		highest = p.HighestVCN
+	}
+
+	bpc := t.boot.BytesPerCluster()
+	clusters := (a.DataSize + bpc - 1) / bpc
+	if highest+1 < clusters {
+		return nil, ErrPartial
+	}
+
+	// Volumes are read by whole clusters, and compressed values by whole
+	// compression units.
+	unit := int64(1) << a.CompressionUnit
+	if compressed {
+		clusters = min((clusters+unit-1)/unit*unit, highest+1)
+	}
+	buf := make([]byte, clusters*bpc)
+	if err := readRuns(t.r, runs, bpc, buf, 0); err != nil {
+		return nil, err
+	}
+	if compressed {
+		var err error
+		if buf, err = decompressUnits(buf, runs, bpc, unit); err != nil {
+			return nil, err
+		}
+	}
+
+	// Data past the initialized size reads as zeros.
+	buf = buf[:a.DataSize]
+	if a.InitializedSize < a.DataSize {
+		clear(buf[max(a.InitializedSize, 0):])
+	}
+
+	return buf, nil
+}
+
+// decompressUnits returns the value held by buf, the clusters of a
+// compressed attribute described by runs, whose compression units are
+// unit clusters long. Units with all their clusters allocated are stored
+// as they are, and units with none read as zeros; the others hold LZNT1
+// data in their first clusters.
+func decompressUnits(buf []byte, runs []DataRun, bpc, unit int64) ([]byte, error) {
+	size := unit * bpc
+	out := make([]byte, 0, len(buf))
+	for off := int64(0); off < int64(len(buf)); off += size {
+		raw := buf[off:min(off+size, int64(len(buf)))]
+		allocated := allocatedClusters(runs, off/bpc, off/bpc+unit)
+		if allocated == 0 || allocated >= unit {
+			out = append(out, raw...)
+			continue
+		}
+
+		start := len(out)
+		var err error
+		out, err = lznt1.Decompress(out, raw[:min(allocated*bpc, int64(len(raw)))])
+		if err != nil {
+			return nil, err
+		}
+		n := int64(len(out) - start)
+		if n > size {
+			return nil, ErrCorrupt
+		}
+		// The tail of a unit past its last chunk is zero.
+		out = append(out, make([]byte, size-n)...)
+	}
+
+	return out, nil
+}
+
+// allocatedClusters returns the number of clusters from the virtual
+// cluster from to to which are allocated in runs.
+func allocatedClusters(runs []DataRun, from, to int64) int64 {
+	var n int64
+	for _, r := range runs {
+		if !r.Sparse() {
+			n += max(0, min(r.VCN+r.Length, to)-max(r.VCN, from))
+		}
+	}
+
+	return n
+}
@@ -0,0 +1,124 @@
+package mft
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-sw/ntfs/compress/lznt1"
+)
+
+func TestDecompressUnits(t *testing.T) {
+	const (
+		bpc  = 512
+		unit = 16
+		size = unit * bpc
+	)
+	text := []byte(strings.Repeat("compressed attribute value, ", 2*size/28+1))[:2*size]
+	stored := bytes.Repeat([]byte{0xC3}, size)
+
+	// compressedUnit returns the clusters of a unit holding data
+	// compressed, and the number of clusters it uses.
+	compressedUnit := func(data []byte) ([]byte, int64) {
+		c := lznt1.Compress(nil, data)
+		clusters := int64(len(c)+bpc-1) / bpc
+		return append(c, make([]byte, size-len(c))...), clusters
+	}
+	first, n1 := compressedUnit(text[:size])
+	second, n2 := compressedUnit(text[size:])
+	short, n3 := compressedUnit(text[:5000])
+
+	tests := []struct {
+		name string
+		buf  []byte
+		runs []DataRun
+		want []byte
+	}{
+		{
+			name: "stored unit",
+			buf:  stored,
+			runs: []DataRun{{VCN: 0, LCN: 100, Length: unit}},
+			want: stored,
+		},
+		{
+			name: "sparse unit",
+			buf:  make([]byte, size),
+			runs: []DataRun{{VCN: 0, LCN: -1, Length: unit}},
+			want: make([]byte, size),
+		},
+		{
+			name: "compressed unit",
+			buf:  first,
+			runs: []DataRun{{VCN: 0, LCN: 100, Length: n1}, {VCN: n1, LCN: -1, Length: unit - n1}},
+			want: text[:size],
+		},
+		{
+			name: "compressed units",
+			buf:  append(append([]byte(nil), first...), second...),
+			runs: []DataRun{
+				{VCN: 0, LCN: 100, Length: n1}, {VCN: n1, LCN: -1, Length: unit - n1},
+				{VCN: unit, LCN: 200, Length: n2}, {VCN: unit + n2, LCN: -1, Length: unit - n2},
+			},
+			want: text,
+		},
+		{
+			name: "stored then compressed",
+			buf:  append(append([]byte(nil), stored...), first...),
+			runs: []DataRun{
+				{VCN: 0, LCN: 100, Length: unit + n1}, {VCN: unit + n1, LCN: -1, Length: unit - n1},
+			},
+			want: append(append([]byte(nil), stored...), text[:size]...),
+		},
+		{
+			name: "unit tail",
+			buf:  short,
+			runs: []DataRun{{VCN: 0, LCN: 100, Length: n3}, {VCN: n3, LCN: -1, Length: unit - n3}},
+			want: append(append([]byte(nil), text[:5000]...), make([]byte, size-5000)...),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decompressUnits(tt.buf, tt.runs, bpc, unit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("got %d bytes, want %d bytes", len(got), len(tt.want))
+			}
+		})
+	}
+
+	corrupt := append([]byte{0x10, 0xB0, 0x01, 0x00, 0x00}, make([]byte, size-5)...)
+	corrupt[2] = 0x01
+	_, err := decompressUnits(corrupt, []DataRun{{VCN: 0, LCN: 100, Length: 1}, {VCN: 1, LCN: -1, Length: unit - 1}}, bpc, unit)
+	if !errors.Is(err, lznt1.ErrCorrupt) {
+		t.Errorf("corrupt unit: err = %v, want %v", err, lznt1.ErrCorrupt)
+	}
+}
+
+func TestAllocatedClusters(t *testing.T) {
+	runs := []DataRun{
+		{VCN: 0, LCN: 10, Length: 4},
+		{VCN: 4, LCN: -1, Length: 12},
+		{VCN: 16, LCN: 50, Length: 20},
+	}
+
+	tests := []struct {
+		from, to, want int64
+	}{
+		{0, 16, 4},
+		{2, 6, 2},
+		{4, 16, 0},
+		{16, 32, 16},
+		{30, 40, 6},
+		{40, 48, 0},
+	}
+
+	for _, tt := range tests {
+		if got := allocatedClusters(runs, tt.from, tt.to); got != tt.want {
+			t.Errorf("allocatedClusters(%d, %d) = %d, want %d", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
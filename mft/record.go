@@ -0,0 +1,185 @@
+package mft
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrBadSignature = errors.New("mft: bad FILE record signature")
+	ErrFixup        = errors.New("mft: update sequence mismatch, torn FILE record")
+	ErrCorrupt      = errors.New("mft: corrupt FILE record")
+)
+
+// sectorStride is the size of the blocks protected by the update sequence
+// array, independent of the sector size of the volume.
+const sectorStride = 512
+
+// FileReference is a reference to a FILE record: the record number in the
+// low 48 bits and the sequence number of the record in the high 16 bits.
+type FileReference uint64
+
+// NewFileReference returns the reference to record number with the given
+// sequence number.
+func NewFileReference(number uint64, seq uint16) FileReference {
+	return FileReference(number&0xFFFFFFFFFFFF | uint64(seq)<<48)
+}
+
+// RecordNumber returns the number of the referenced record.
+func (r FileReference) RecordNumber() uint64 {
+	return uint64(r) & 0xFFFFFFFFFFFF
+}
+
+// Sequence returns the sequence number expected in the referenced record.
+func (r FileReference) Sequence() uint16 {
+	return uint16(r >> 48)
+}
+
+func (r FileReference) String() string {
+	return fmt.Sprintf("%d-%d", r.RecordNumber(), r.Sequence())
+}
+
+// RecordFlags are the flags of a FILE record header.
+type RecordFlags uint16
+
+const (
+	RecordInUse     RecordFlags = 0x0001 // FILE_RECORD_SEGMENT_IN_USE
+	RecordDirectory RecordFlags = 0x0002 // FILE_FILE_NAME_INDEX_PRESENT
+	RecordSystem    RecordFlags = 0x0004
+	RecordViewIndex RecordFlags = 0x0008 // FILE_VIEW_INDEX_PRESENT
+)
+
+// Record is a parsed FILE record segment.
+type Record struct {
+	// Number is the record number, as stored in the header by Windows XP
+	// and later, or as passed to ParseRecord.
+	Number uint64
+	// LogFileSequenceNumber is the LSN of the last logged change.
+	LogFileSequenceNumber uint64
+	// SequenceNumber is incremented each time the record is reused.
+	SequenceNumber uint16
+	// HardLinkCount is the number of $FILE_NAME attributes indexed in
+	// directories, that is the number of hard links of the file.
+	HardLinkCount uint16
+	Flags         RecordFlags
+	BytesInUse    uint32
+	BytesAlloc    uint32
+	// BaseRecord references the base record of an extension record, and
+	// is zero for base records.
+	BaseRecord FileReference
+	// Attributes are the attributes stored in the record, in order.
+	Attributes []Attribute
+}
+
+// InUse reports whether the record describes an existing file.
+func (r *Record) InUse() bool {
+	return r.Flags&RecordInUse != 0
+}
+
+// IsDirectory reports whether the record describes a directory.
+func (r *Record) IsDirectory() bool {
+	return r.Flags&RecordDirectory != 0
+}
+
+// IsBase reports whether r is a base record rather than an extension
+// record holding attributes of another file.
+func (r *Record) IsBase() bool {
+	return r.BaseRecord == 0
+}
+
+// Reference returns the file reference of r.
+func (r *Record) Reference() FileReference {
+	return NewFileReference(r.Number, r.SequenceNumber)
+}
+
+// Attribute returns the first attribute of type t with the given name, or
+// nil if r has none.
+func (r *Record) Attribute(t AttributeType, name string) *Attribute {
+	for i := range r.Attributes {
+		if a := &r.Attributes[i]; a.Type == t && a.Name == name {
+			return a
+		}
+	}
+
+	return nil
+}
+
+// AttributesOf returns the attributes of type t, whatever their name.
+func (r *Record) AttributesOf(t AttributeType) []*Attribute {
+	var attrs []*Attribute
+	for i := range r.Attributes {
+		if r.Attributes[i].Type == t {
+			attrs = append(attrs, &r.Attributes[i])
+		}
+	}
+
+	return attrs
+}
+
+// ParseRecord parses the FILE record segment in buf, whose record number
+// is number. The update sequence fixups are applied to buf in place, and
+// the returned record refers to buf for the values of its resident
+// attributes.
+func ParseRecord(buf []byte, number uint64) (*Record, error) {
+	if len(buf) < 48 {
+		return nil, ErrCorrupt
+	}
+	if string(buf[0:4]) != "FILE" {
+		return nil, ErrBadSignature
+	}
+	if err := applyFixups(buf); err != nil {
+		return nil, err
+	}
+
+	r := &Record{
+		Number:                number,
+		LogFileSequenceNumber: binary.LittleEndian.Uint64(buf[8:]),
+		SequenceNumber:        binary.LittleEndian.Uint16(buf[16:]),
+		HardLinkCount:         binary.LittleEndian.Uint16(buf[18:]),
+		Flags:                 RecordFlags(binary.LittleEndian.Uint16(buf[22:])),
+		BytesInUse:            binary.LittleEndian.Uint32(buf[24:]),
+		BytesAlloc:            binary.LittleEndian.Uint32(buf[28:]),
+		BaseRecord:            FileReference(binary.LittleEndian.Uint64(buf[32:])),
+	}
+
+	// Records written since Windows XP carry their own number after the
+	// update sequence array offset moved from 42 to 48.
+	if usaOffset := binary.LittleEndian.Uint16(buf[4:]); usaOffset >= 48 {
+		r.Number = uint64(binary.LittleEndian.Uint32(buf[44:]))
+	}
+
+	if r.BytesInUse > uint32(len(buf)) {
+		return nil, ErrCorrupt
+	}
+
+	attrs, err := parseAttributes(buf[:r.BytesInUse], int(binary.LittleEndian.Uint16(buf[20:])))
+	if err != nil {
+		return nil, err
+	}
+	r.Attributes = attrs
+
+	return r, nil
+}
+
+// applyFixups checks the update sequence number at the end of every
+// 512-byte block of a multi-sector structure and restores the original
+// values saved in the update sequence array.
+func applyFixups(buf []byte) error {
+	off := int(binary.LittleEndian.Uint16(buf[4:]))
+	count := int(binary.LittleEndian.Uint16(buf[6:]))
+	if count == 0 || off+2*count > len(buf) || (count-1)*sectorStride > len(buf) {
+		return ErrCorrupt
+	}
+
+	usn := buf[off : off+2]
+	for i := 1; i < count; i++ {
+		end := i*sectorStride - 2
+		if buf[end] != usn[0] || buf[end+1] != usn[1] {
+			return ErrFixup
+		}
+		copy(buf[end:end+2], buf[off+2*i:])
+	}
+
+	return nil
+}
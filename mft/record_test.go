@@ -0,0 +1,247 @@
+package mft
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"unicode/utf16"
+)
+
+var le = binary.LittleEndian
+
+// testRecordSize is the size of the FILE records built by the tests.
+const testRecordSize = 1024
+
+// buildRecord returns a FILE record numbered 42 holding the attribute
+// records attrs. The update sequence is applied by protect.
+func buildRecord(attrs ...[]byte) []byte {
+	buf := make([]byte, testRecordSize)
+	count := testRecordSize/sectorStride + 1
+	attrOff := (48 + 2*count + 7) &^ 7
+
+	copy(buf, "FILE")
+	le.PutUint16(buf[4:], 48)
+	le.PutUint16(buf[6:], uint16(count))
+	le.PutUint64(buf[8:], 0x1234)
+	le.PutUint16(buf[16:], 3)
+	le.PutUint16(buf[18:], 1)
+	le.PutUint16(buf[20:], uint16(attrOff))
+	le.PutUint16(buf[22:], uint16(RecordInUse))
+	le.PutUint32(buf[28:], testRecordSize)
+	le.PutUint32(buf[44:], 42)
+
+	off := attrOff
+	for _, a := range attrs {
+		off += copy(buf[off:], a)
+	}
+	le.PutUint32(buf[off:], uint32(attrEnd))
+	le.PutUint32(buf[24:], uint32(off+8))
+
+	return buf
+}
+
+// protect applies the update sequence usn to the multi-sector structure in
+// buf, whose update sequence array is at off.
+func protect(buf []byte, off int, usn uint16) []byte {
+	count := int(le.Uint16(buf[6:]))
+	le.PutUint16(buf[off:], usn)
+	for i := 1; i < count; i++ {
+		end := i*sectorStride - 2
+		copy(buf[off+2*i:], buf[end:end+2])
+		le.PutUint16(buf[end:], usn)
+	}
+
+	return buf
+}
+
+func encodeName(name string) []byte {
+	u := utf16.Encode([]rune(name))
+	b := make([]byte, 2*len(u))
+	for i, c := range u {
+		le.PutUint16(b[2*i:], c)
+	}
+
+	return b
+}
+
+// residentAttr returns a resident attribute record.
+func residentAttr(t AttributeType, name string, value []byte) []byte {
+	n := encodeName(name)
+	valOff := (24 + len(n) + 7) &^ 7
+	length := (valOff + len(value) + 7) &^ 7
+
+	b := make([]byte, length)
+	le.PutUint32(b[0:], uint32(t))
+	le.PutUint32(b[4:], uint32(length))
+	b[9] = byte(len(n) / 2)
+	le.PutUint16(b[10:], 24)
+	copy(b[24:], n)
+	le.PutUint32(b[16:], uint32(len(value)))
+	le.PutUint16(b[20:], uint16(valOff))
+	copy(b[valOff:], value)
+
+	return b
+}
+
+// nonResidentAttr returns a non-resident attribute record describing the
+// virtual clusters lowest to highest with the mapping pairs pairs.
+func nonResidentAttr(t AttributeType, name string, lowest, highest, size int64, pairs []byte) []byte {
+	n := encodeName(name)
+	pairsOff := (64 + len(n) + 7) &^ 7
+	length := (pairsOff + len(pairs) + 1 + 7) &^ 7
+
+	b := make([]byte, length)
+	le.PutUint32(b[0:], uint32(t))
+	le.PutUint32(b[4:], uint32(length))
+	b[8] = 1
+	b[9] = byte(len(n) / 2)
+	le.PutUint16(b[10:], 64)
+	copy(b[64:], n)
+	le.PutUint64(b[16:], uint64(lowest))
+	le.PutUint64(b[24:], uint64(highest))
+	le.PutUint16(b[32:], uint16(pairsOff))
+	le.PutUint64(b[40:], uint64(size))
+	le.PutUint64(b[48:], uint64(size))
+	le.PutUint64(b[56:], uint64(size))
+	copy(b[pairsOff:], pairs)
+
+	return b
+}
+
+func TestParseRecord(t *testing.T) {
+	si := make([]byte, 72)
+	le.PutUint32(si[32:], 0x20)
+	le.PutUint32(si[52:], 0x100)
+	// The stream spans the end of the first sector, whose last word is
+	// replaced by the update sequence.
+	pattern := bytes.Repeat([]byte{0xA5, 0x5A, 0x3C}, 200)
+
+	buf := protect(buildRecord(
+		residentAttr(AttrStandardInformation, "", si),
+		residentAttr(AttrData, "ads", pattern),
+		nonResidentAttr(AttrData, "", 0, 15, 16*4096, []byte{0x21, 0x10, 0x00, 0x01}),
+	), 48, 0x0007)
+
+	rec, err := ParseRecord(buf, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Number != 42 || rec.SequenceNumber != 3 || rec.HardLinkCount != 1 || rec.LogFileSequenceNumber != 0x1234 {
+		t.Errorf("header = %+v", rec)
+	}
+	if !rec.InUse() || rec.IsDirectory() || !rec.IsBase() {
+		t.Errorf("flags = %#x, base = %v", rec.Flags, rec.BaseRecord)
+	}
+	if len(rec.Attributes) != 3 {
+		t.Fatalf("got %d attributes, want 3", len(rec.Attributes))
+	}
+
+	a := rec.Attribute(AttrStandardInformation, "")
+	if a == nil {
+		t.Fatal("no $STANDARD_INFORMATION")
+	}
+	s, err := a.StandardInformation()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.FileAttributes != 0x20 || s.SecurityID != 0x100 {
+		t.Errorf("$STANDARD_INFORMATION = %+v", s)
+	}
+
+	if v, ok := rec.ResidentStream("ads"); !ok || !bytes.Equal(v, pattern) {
+		t.Error("fixups not applied to the resident stream")
+	}
+
+	d, err := rec.Attribute(AttrData, "").Data()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []DataRun{{VCN: 0, LCN: 256, Length: 16}}
+	if d.Resident || d.Size != 16*4096 || !equalRuns(d.Runs, want) {
+		t.Errorf("$DATA = %+v, want runs %v", d, want)
+	}
+}
+
+func TestParseRecordErrors(t *testing.T) {
+	valid := func() []byte {
+		return buildRecord(residentAttr(AttrStandardInformation, "", make([]byte, 72)))
+	}
+
+	tests := []struct {
+		name string
+		buf  func() []byte
+		want error
+	}{
+		{"short", func() []byte { return make([]byte, 40) }, ErrCorrupt},
+		{"signature", func() []byte {
+			b := protect(valid(), 48, 1)
+			copy(b, "BAAD")
+			return b
+		}, ErrBadSignature},
+		{"fixup mismatch", func() []byte {
+			b := protect(valid(), 48, 1)
+			b[2*sectorStride-1] ^= 0xFF
+			return b
+		}, ErrFixup},
+		{"no update sequence", func() []byte {
+			b := valid()
+			le.PutUint16(b[6:], 0)
+			return b
+		}, ErrCorrupt},
+		{"update sequence beyond record", func() []byte {
+			b := valid()
+			le.PutUint16(b[6:], 4)
+			return b
+		}, ErrCorrupt},
+		{"bytes in use", func() []byte {
+			b := valid()
+			le.PutUint32(b[24:], 2*testRecordSize)
+			return protect(b, 48, 1)
+		}, ErrCorrupt},
+		{"attribute length", func() []byte {
+			b := valid()
+			off := int(le.Uint16(b[20:]))
+			le.PutUint32(b[off+4:], 0x1000)
+			return protect(b, 48, 1)
+		}, ErrCorrupt},
+		{"short attribute", func() []byte {
+			b := valid()
+			off := int(le.Uint16(b[20:]))
+			le.PutUint32(b[off+4:], 8)
+			return protect(b, 48, 1)
+		}, ErrCorrupt},
+		{"resident value", func() []byte {
+			b := valid()
+			off := int(le.Uint16(b[20:]))
+			le.PutUint32(b[off+16:], 0x200)
+			return protect(b, 48, 1)
+		}, ErrCorrupt},
+		{"no end marker", func() []byte {
+			b := valid()
+			le.PutUint32(b[24:], uint32(le.Uint16(b[20:]))+4)
+			return protect(b, 48, 1)
+		}, ErrCorrupt},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseRecord(tt.buf(), 0); !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func equalRuns(a, b []DataRun) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,73 @@
+package mft
+
+// DataRun maps Length clusters starting at the virtual cluster VCN to the
+// logical cluster LCN. LCN is -1 for sparse runs, which have no clusters.
+type DataRun struct {
+	VCN    int64
+	LCN    int64
+	Length int64
+}
+
+// Sparse reports whether r has no clusters allocated.
+func (r DataRun) Sparse() bool {
+	return r.LCN < 0
+}
+
+// DecodeDataRuns decodes the mapping pairs of a non-resident attribute
+// whose first virtual cluster is vcn.
+func DecodeDataRuns(pairs []byte, vcn int64) ([]DataRun, error) {
+	var runs []DataRun
+
+	lcn := int64(0)
+	for i := 0; i < len(pairs) && pairs[i] != 0; {
+		lenSize, offSize := int(pairs[i]&0x0F), int(pairs[i]>>4)
+		i++
+		if lenSize == 0 || lenSize > 8 || offSize > 8 || i+lenSize+offSize > len(pairs) {
+			return nil, ErrCorrupt
+		}
+
+		length := int64(readUint(pairs[i : i+lenSize]))
+		i += lenSize
+		if length <= 0 {
+			return nil, ErrCorrupt
+		}
+
+		r := DataRun{VCN: vcn, LCN: -1, Length: length}
+		// Runs without an offset are sparse; offsets are relative to the
+		// previous run.
+		if offSize > 0 {
+			lcn += readInt(pairs[i : i+offSize])
+			i += offSize
+			if lcn < 0 {
+				return nil, ErrCorrupt
+			}
+			r.LCN = lcn
+		}
+
+		runs = append(runs, r)
+		vcn += length
+	}
+
+	return runs, nil
+}
+
+// readUint decodes a little-endian unsigned integer of up to 8 bytes.
+func readUint(b []byte) uint64 {
+	var v uint64
+	for i := len(b) - 1; i >= 0; i-- {
+		v = v<<8 | uint64(b[i])
+	}
+
+	return v
+}
+
+// readInt decodes a little-endian signed integer of up to 8 bytes.
+func readInt(b []byte) int64 {
+	v := readUint(b)
+	if shift := 64 - 8*uint(len(b)); shift < 64 && b[len(b)-1]&0x80 != 0 {
+		// Sign extend.
+		return int64(v<<shift) >> shift
+	}
+
+	return int64(v)
+}
@@ -0,0 +1,89 @@
+package mft
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDecodeDataRuns(t *testing.T) {
+	tests := []struct {
+		name  string
+		pairs []byte
+		vcn   int64
+		want  []DataRun
+		err   error
+	}{
+		{
+			name:  "single",
+			pairs: []byte{0x21, 0x10, 0x00, 0x01, 0x00},
+			want:  []DataRun{{VCN: 0, LCN: 256, Length: 16}},
+		},
+		{
+			name:  "sparse",
+			pairs: []byte{0x11, 0x08, 0x40, 0x01, 0x04, 0x11, 0x08, 0x10, 0x00},
+			want: []DataRun{
+				{VCN: 0, LCN: 64, Length: 8},
+				{VCN: 8, LCN: -1, Length: 4},
+				{VCN: 12, LCN: 80, Length: 8},
+			},
+		},
+		{
+			name:  "negative delta",
+			pairs: []byte{0x21, 0x04, 0x00, 0x02, 0x11, 0x04, 0xF0, 0x00},
+			want: []DataRun{
+				{VCN: 0, LCN: 512, Length: 4},
+				{VCN: 4, LCN: 496, Length: 4},
+			},
+		},
+		{
+			name:  "wide fields",
+			pairs: []byte{0x32, 0x00, 0x01, 0x00, 0x00, 0x01, 0x00},
+			vcn:   100,
+			want:  []DataRun{{VCN: 100, LCN: 0x10000, Length: 256}},
+		},
+		{
+			name:  "unterminated",
+			pairs: []byte{0x11, 0x01, 0x10},
+			want:  []DataRun{{VCN: 0, LCN: 16, Length: 1}},
+		},
+		{name: "empty", pairs: []byte{0x00}},
+		{name: "truncated length", pairs: []byte{0x21, 0x10}, err: ErrCorrupt},
+		{name: "truncated offset", pairs: []byte{0x21, 0x10, 0x00}, err: ErrCorrupt},
+		{name: "no length", pairs: []byte{0x10, 0x01, 0x00}, err: ErrCorrupt},
+		{name: "length too wide", pairs: []byte{0x19, 1, 2, 3, 4, 5, 6, 7, 8, 9, 1}, err: ErrCorrupt},
+		{name: "zero length", pairs: []byte{0x11, 0x00, 0x10, 0x00}, err: ErrCorrupt},
+		{name: "negative length", pairs: []byte{0x18, 0, 0, 0, 0, 0, 0, 0, 0x80, 0x01, 0x00}, err: ErrCorrupt},
+		{name: "negative LCN", pairs: []byte{0x11, 0x01, 0xFF, 0x00}, err: ErrCorrupt},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runs, err := DecodeDataRuns(tt.pairs, tt.vcn)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if !equalRuns(runs, tt.want) {
+				t.Errorf("runs = %v, want %v", runs, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadInt(t *testing.T) {
+	tests := []struct {
+		b    []byte
+		want int64
+	}{
+		{[]byte{0x7F}, 127},
+		{[]byte{0x80}, -128},
+		{[]byte{0xF0, 0xFF}, -16},
+		{[]byte{0x00, 0x80, 0x00}, 0x8000},
+		{[]byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}, -1},
+	}
+
+	for _, tt := range tests {
+		if got := readInt(tt.b); got != tt.want {
+			t.Errorf("readInt(% x) = %d, want %d", tt.b, got, tt.want)
+		}
+	}
+}
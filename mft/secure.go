@@ -0,0 +1,162 @@
+package mft
+
+import (
+	"encoding/binary"
+	"errors"
+	"iter"
+	"maps"
+	"slices"
+)
+
+// secureRecord is the record number of the $Secure metafile.
+const secureRecord = 9
+
+// sdsBlockSize is the size of the blocks of the $SDS stream, each of which
+// is followed by a mirror copy.
+const sdsBlockSize = 0x40000
+
+// sdsHeaderSize is the size of SECURITY_DESCRIPTOR_HEADER.
+const sdsHeaderSize = 20
+
+var ErrUnknownSecurityID = errors.New("mft: unknown security ID")
+
+// sdsEntry locates a security descriptor in the $SDS stream.
+type sdsEntry struct {
+	hash   uint32
+	offset int64
+	length uint32
+}
+
+// Secure holds the security descriptors shared by the files of a volume,
+// as stored in the $Secure metafile: $SDS holds the descriptors, $SII
+// indexes them by security ID and $SDH by hash.
+type Secure struct {
+	sds    []byte
+	byID   map[uint32]sdsEntry
+	byHash map[uint32][]uint32
+}
+
+// Secure reads the $Secure metafile of the volume. The security ID found
+// in the $STANDARD_INFORMATION of every file can then be resolved to its
+// security descriptor without opening the file.
+func (t *Table) Secure() (*Secure, error) {
+	rec, err := t.RecordNumber(secureRecord)
+	if err != nil {
+		return nil, err
+	}
+	// The attributes of $Secure move to extension records as $SDS grows.
+	if rec, err = t.withExtensions(rec); err != nil {
+		return nil, &RecordError{Number: secureRecord, Err: err}
+	}
+
+	sds, err := t.readAttribute(rec, AttrData, "$SDS")
+	if err != nil {
+		return nil, &RecordError{Number: secureRecord, Err: err}
+	}
+
+	s := &Secure{
+		sds:    sds,
+		byID:   make(map[uint32]sdsEntry),
+		byHash: make(map[uint32][]uint32),
+	}
+
+	sii, err := t.indexEntries(rec, "$SII")
+	if err != nil {
+		return nil, &RecordError{Number: secureRecord, Err: err}
+	}
+	for _, e := range sii {
+		if len(e.key) < 4 || len(e.data) < sdsHeaderSize {
+			continue
+		}
+		id := binary.LittleEndian.Uint32(e.key)
+		s.byID[id] = sdsEntry{
+			hash:   binary.LittleEndian.Uint32(e.data[0:]),
+			offset: int64(binary.LittleEndian.Uint64(e.data[8:])),
+			length: binary.LittleEndian.Uint32(e.data[16:]),
+		}
+	}
+
+	sdh, err := t.indexEntries(rec, "$SDH")
+	if err != nil {
+		return nil, &RecordError{Number: secureRecord, Err: err}
+	}
+	for _, e := range sdh {
+		if len(e.key) < 8 {
+			continue
+		}
+		hash := binary.LittleEndian.Uint32(e.key[0:])
+		s.byHash[hash] = append(s.byHash[hash], binary.LittleEndian.Uint32(e.key[4:]))
+	}
+
+	return s, nil
+}
+
+// Len returns the number of security descriptors.
+func (s *Secure) Len() int {
+	return len(s.byID)
+}
+
+// IDs returns the security IDs in use, in ascending order.
+func (s *Secure) IDs() []uint32 {
+	return slices.Sorted(maps.Keys(s.byID))
+}
+
+// Descriptor returns the self-relative security descriptor with the
+// given security ID. The returned slice must not be modified.
+func (s *Secure) Descriptor(id uint32) ([]byte, error) {
+	e, ok := s.byID[id]
+	if !ok {
+		return nil, ErrUnknownSecurityID
+	}
+
+	end := e.offset + int64(e.length)
+	if e.length < sdsHeaderSize || e.offset < 0 || end > int64(len(s.sds)) {
+		return nil, ErrCorrupt
+	}
+	entry := s.sds[e.offset:end]
+	if binary.LittleEndian.Uint32(entry[4:]) != id {
+		return nil, ErrCorrupt
+	}
+
+	return entry[sdsHeaderSize:], nil
+}
+
+// All returns an iterator over the security IDs and their descriptors, in
+// ascending security ID order. Descriptors which cannot be read are
+// skipped.
+func (s *Secure) All() iter.Seq2[uint32, []byte] {
+	return func(yield func(uint32, []byte) bool) {
+		for _, id := range s.IDs() {
+			sd, err := s.Descriptor(id)
+			if err != nil {
+				continue
+			}
+			if !yield(id, sd) {
+				return
+			}
+		}
+	}
+}
+
+// Lookup returns the security ID of the self-relative security descriptor
+// sd, if the volume stores it.
+func (s *Secure) Lookup(sd []byte) (uint32, bool) {
+	for _, id := range s.byHash[HashDescriptor(sd)] {
+		if d, err := s.Descriptor(id); err == nil && string(d) == string(sd) {
+			return id, true
+		}
+	}
+
+	return 0, false
+}
+
+// HashDescriptor returns the hash NTFS uses to index the self-relative
+// security descriptor sd in $SDH.
+func HashDescriptor(sd []byte) uint32 {
+	var h uint32
+	for i := 0; i+4 <= len(sd); i += 4 {
+		h = (h<<3 | h>>29) + binary.LittleEndian.Uint32(sd[i:])
+	}
+
+	return h
+}
@@ -0,0 +1,223 @@
+package mft
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestHashDescriptor(t *testing.T) {
+	tests := []struct {
+		name string
+		sd   []byte
+		want uint32
+	}{
+		{"empty", nil, 0},
+		{"one word", []byte{1, 0, 0, 0}, 1},
+		{"two words", []byte{1, 0, 0, 0, 2, 0, 0, 0}, 10},
+		{"trailing bytes ignored", []byte{1, 0, 0, 0, 2, 0, 0, 0, 0xFF}, 10},
+		{"rotation", []byte{0, 0, 0, 0x80, 0, 0, 0, 0}, 4},
+		{"wraps", []byte{0xFF, 0xFF, 0xFF, 0xFF, 1, 0, 0, 0}, 0},
+	}
+
+	for _, tt := range tests {
+		if got := HashDescriptor(tt.sd); got != tt.want {
+			t.Errorf("%s: HashDescriptor = %#x, want %#x", tt.name, got, tt.want)
+		}
+	}
+}
+
+// sdsEntryBytes encodes a SECURITY_DESCRIPTOR_HEADER followed by sd.
+func sdsEntryBytes(id uint32, off int64, sd []byte) []byte {
+	b := make([]byte, sdsHeaderSize+len(sd))
+	le.PutUint32(b[0:], HashDescriptor(sd))
+	le.PutUint32(b[4:], id)
+	le.PutUint64(b[8:], uint64(off))
+	le.PutUint32(b[16:], uint32(len(b)))
+	copy(b[sdsHeaderSize:], sd)
+
+	return b
+}
+
+// testSecure returns a Secure holding the descriptors sds with the
+// security IDs 0x100 and up.
+func testSecure(sds ...[]byte) *Secure {
+	s := &Secure{
+		byID:   make(map[uint32]sdsEntry),
+		byHash: make(map[uint32][]uint32),
+	}
+	for i, sd := range sds {
+		id := uint32(0x100 + i)
+		off := int64(len(s.sds))
+		e := sdsEntryBytes(id, off, sd)
+		s.sds = append(s.sds, e...)
+		// Entries are 16-byte aligned in $SDS.
+		s.sds = append(s.sds, make([]byte, (16-len(e)%16)%16)...)
+
+		hash := HashDescriptor(sd)
+		s.byID[id] = sdsEntry{hash: hash, offset: off, length: uint32(len(e))}
+		s.byHash[hash] = append(s.byHash[hash], id)
+	}
+
+	return s
+}
+
+func TestSecureDescriptor(t *testing.T) {
+	sd1 := []byte{1, 0, 4, 0x80, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	sd2 := []byte{1, 0, 4, 0x80, 0x14, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 1, 0, 0, 0, 0, 0, 5, 0x12, 0, 0, 0}
+	s := testSecure(sd1, sd2)
+
+	if s.Len() != 2 || !slices.Equal(s.IDs(), []uint32{0x100, 0x101}) {
+		t.Errorf("IDs = %v", s.IDs())
+	}
+	for id, want := range map[uint32][]byte{0x100: sd1, 0x101: sd2} {
+		got, err := s.Descriptor(id)
+		if err != nil || string(got) != string(want) {
+			t.Errorf("Descriptor(%#x) = % x, %v, want % x", id, got, err, want)
+		}
+		if found, ok := s.Lookup(want); !ok || found != id {
+			t.Errorf("Lookup of %#x = %#x, %v", id, found, ok)
+		}
+	}
+	if _, ok := s.Lookup([]byte{1, 0, 4, 0x80}); ok {
+		t.Error("Lookup found a descriptor the volume does not store")
+	}
+
+	var ids []uint32
+	for id := range s.All() {
+		ids = append(ids, id)
+	}
+	if !slices.Equal(ids, []uint32{0x100, 0x101}) {
+		t.Errorf("All = %v", ids)
+	}
+}
+
+func TestSecureDescriptorErrors(t *testing.T) {
+	sd := make([]byte, 20)
+
+	tests := []struct {
+		name   string
+		modify func(*Secure)
+		want   error
+	}{
+		{"unknown ID", func(s *Secure) { delete(s.byID, 0x100) }, ErrUnknownSecurityID},
+		{"ID mismatch", func(s *Secure) { le.PutUint32(s.sds[4:], 0x200) }, ErrCorrupt},
+		{"beyond $SDS", func(s *Secure) {
+			e := s.byID[0x100]
+			e.offset = int64(len(s.sds)) - 8
+			s.byID[0x100] = e
+		}, ErrCorrupt},
+		{"negative offset", func(s *Secure) {
+			e := s.byID[0x100]
+			e.offset = -1
+			s.byID[0x100] = e
+		}, ErrCorrupt},
+		{"short entry", func(s *Secure) {
+			e := s.byID[0x100]
+			e.length = sdsHeaderSize - 1
+			s.byID[0x100] = e
+		}, ErrCorrupt},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := testSecure(sd)
+			tt.modify(s)
+			if _, err := s.Descriptor(0x100); !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// indexRoot returns the value of an $INDEX_ROOT attribute holding entries.
+func indexRoot(entries ...[]byte) []byte {
+	size := 16 + 16
+	for _, e := range entries {
+		size += len(e)
+	}
+	node := indexNode(size, 16, entries...)
+
+	b := make([]byte, 16, 16+len(node))
+	le.PutUint32(b[8:], 4096)
+	b[12] = 1
+
+	return append(b, node...)
+}
+
+func TestSecureAttributeList(t *testing.T) {
+	const (
+		bpc        = 4096
+		mftLCN     = 1
+		sdsLCN     = 40
+		recordSize = testRecordSize
+	)
+	img := make([]byte, 48*bpc)
+	writeRecord := func(number, base uint64, attrs ...[]byte) {
+		buf := buildRecord(attrs...)
+		le.PutUint32(buf[44:], uint32(number))
+		if base != 0 {
+			le.PutUint64(buf[32:], uint64(NewFileReference(base, 9)))
+		}
+		copy(img[mftLCN*bpc+int(number)*recordSize:], protect(buf, 48, 1))
+	}
+
+	// $SDS spans two clusters, each described by an extension record.
+	sd1, sd2 := make([]byte, 20), make([]byte, 28)
+	sd1[0], sd2[0] = 1, 2
+	e1, e2 := sdsEntryBytes(0x100, 0, sd1), sdsEntryBytes(0x101, bpc, sd2)
+	copy(img[sdsLCN*bpc:], e1)
+	copy(img[(sdsLCN+1)*bpc:], e2)
+	sdsSize := int64(bpc + len(e2))
+
+	sii := func(id uint32, e []byte) []byte {
+		return indexEntryBytes(le.AppendUint32(nil, id), e[:sdsHeaderSize], 0)
+	}
+	sdh := func(id uint32, e []byte) []byte {
+		key := le.AppendUint32(le.AppendUint32(nil, le.Uint32(e)), id)
+		return indexEntryBytes(key, e[:sdsHeaderSize], 0)
+	}
+
+	var list []byte
+	for _, e := range []AttributeListEntry{
+		{Type: AttrStandardInformation, Record: NewFileReference(secureRecord, 9)},
+		{Type: AttrData, Name: "$SDS", Record: NewFileReference(30, 1)},
+		{Type: AttrData, Name: "$SDS", LowestVCN: 1, Record: NewFileReference(31, 1)},
+		{Type: AttrIndexRoot, Name: "$SDH", Record: NewFileReference(31, 1)},
+		{Type: AttrIndexRoot, Name: "$SII", Record: NewFileReference(30, 1)},
+	} {
+		list = append(list, attributeListEntry(e)...)
+	}
+
+	writeRecord(secureRecord, 0,
+		residentAttr(AttrStandardInformation, "", make([]byte, 72)),
+		residentAttr(AttrAttributeList, "", list))
+	writeRecord(30, secureRecord,
+		nonResidentAttr(AttrData, "$SDS", 0, 0, sdsSize, []byte{0x11, 0x01, sdsLCN}),
+		residentAttr(AttrIndexRoot, "$SII", indexRoot(sii(0x100, e1), sii(0x101, e2))))
+	writeRecord(31, secureRecord,
+		nonResidentAttr(AttrData, "$SDS", 1, 1, 0, []byte{0x11, 0x01, sdsLCN + 1}),
+		residentAttr(AttrIndexRoot, "$SDH", indexRoot(sdh(0x100, e1), sdh(0x101, e2))))
+
+	tbl := &Table{
+		r:    bytes.NewReader(img),
+		boot: &BootSector{BytesPerSector: 512, SectorsPerCluster: bpc / 512, RecordSize: recordSize},
+		runs: []DataRun{{VCN: 0, LCN: mftLCN, Length: 16}},
+		size: 16 * bpc,
+	}
+
+	s, err := tbl.Secure()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id, want := range map[uint32][]byte{0x100: sd1, 0x101: sd2} {
+		got, err := s.Descriptor(id)
+		if err != nil || !bytes.Equal(got, want) {
+			t.Errorf("Descriptor(%#x) = % x, %v, want % x", id, got, err, want)
+		}
+		if found, ok := s.Lookup(want); !ok || found != id {
+			t.Errorf("Lookup of %#x = %#x, %v", id, found, ok)
+		}
+	}
+}
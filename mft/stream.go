@@ -0,0 +1,50 @@
+package mft
+
+import "bytes"
+
+// Stream describes a named data stream of a file, also known as an
+// alternate data stream.
+type Stream struct {
+	Name string
+	Size int64
+	// Resident is set if the content of the stream is stored in the FILE
+	// record itself, which is the case for small streams such as
+	// Zone.Identifier.
+	Resident bool
+	// Value is the content of a resident stream, and nil otherwise.
+	Value []byte
+}
+
+// Streams returns the named data streams stored in r. Streams of files
+// with many attributes may be stored in extension records instead of the
+// base record.
+func (r *Record) Streams() []Stream {
+	var streams []Stream
+	for _, a := range r.AttributesOf(AttrData) {
+		// Parts of a non-resident stream continued from another record
+		// are reported with the first part only.
+		if a.Name == "" || a.LowestVCN != 0 {
+			continue
+		}
+
+		s := Stream{Name: a.Name, Size: a.Size(), Resident: a.Resident}
+		if a.Resident {
+			s.Value = bytes.Clone(a.Value)
+		}
+		streams = append(streams, s)
+	}
+
+	return streams
+}
+
+// ResidentStream returns the content of the resident named data stream
+// name of r. It reports false if r has no such stream, or if its content
+// is not resident.
+func (r *Record) ResidentStream(name string) ([]byte, bool) {
+	a := r.Attribute(AttrData, name)
+	if a == nil || !a.Resident || name == "" {
+		return nil, false
+	}
+
+	return a.Value, true
+}
@@ -0,0 +1,243 @@
+package mft
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"slices"
+)
+
+// readChunk is the amount of $MFT data read at once while iterating over
+// records.
+const readChunk = 1 << 20
+
+// bootReadSize is the amount of data read from the start of the volume to
+// get the boot sector, large enough for every sector size in use.
+const bootReadSize = 4096
+
+// RecordError reports a FILE record which could not be parsed.
+type RecordError struct {
+	Number uint64
+	Err    error
+}
+
+func (e *RecordError) Error() string {
+	return fmt.Sprintf("mft: record %d: %v", e.Number, e.Err)
+}
+
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
+
+// Table reads the master file table of an NTFS volume.
+type Table struct {
+	r      io.ReaderAt
+	closer io.Closer
+	boot   *BootSector
+	runs   []DataRun // extents of the $DATA attribute of $MFT
+	size   int64     // size of the $DATA attribute of $MFT
+}
+
+// NewTable returns a Table reading the raw NTFS volume r, which must
+// support reads aligned to the sector size of the volume.
+func NewTable(r io.ReaderAt) (*Table, error) {
+	buf := make([]byte, bootReadSize)
+	if _, err := r.ReadAt(buf, 0); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	boot, err := ParseBootSector(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Table{r: r, boot: boot}
+
+	// The first record describes $MFT itself, and locates the rest of
+	// the table.
+	rec := make([]byte, boot.RecordSize)
+	if _, err := r.ReadAt(rec, boot.MftLCN*boot.BytesPerCluster()); err != nil {
+		return nil, err
+	}
+	mft, err := ParseRecord(rec, 0)
+	if err != nil {
+		return nil, &RecordError{Number: 0, Err: err}
+	}
+
+	data := mft.Attribute(AttrData, "")
+	if data == nil || data.Resident {
+		return nil, &RecordError{Number: 0, Err: ErrCorrupt}
+	}
+	if t.runs, err = DecodeDataRuns(data.mappingPairs, data.LowestVCN); err != nil {
+		return nil, &RecordError{Number: 0, Err: err}
+	}
+	t.size = data.DataSize
+
+	// A fragmented $MFT continues its $DATA attribute in extension
+	// records, listed in the $ATTRIBUTE_LIST of the first record.
+	if list := mft.Attribute(AttrAttributeList, ""); list != nil {
+		if err := t.loadExtents(list); err != nil {
+			return nil, &RecordError{Number: 0, Err: err}
+		}
+	}
+
+	return t, nil
+}
+
+// loadExtents adds the runs of $MFT stored in the extension records
+// listed by list, the $ATTRIBUTE_LIST attribute of the first record.
+func (t *Table) loadExtents(list *Attribute) error {
+	v, err := t.ReadAttribute(list)
+	if err != nil {
+		return err
+	}
+	entries, err := DecodeAttributeList(v)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		if e.Type != AttrData || e.Name != "" || e.Record.RecordNumber() == 0 {
+			continue
+		}
+
+		// Extension records are located through the runs known so far,
+		// which the first record always covers.
+		rec, err := t.RecordNumber(e.Record.RecordNumber())
+		if err != nil {
+			return err
+		}
+		for i := range rec.Attributes {
+			a := &rec.Attributes[i]
+			if a.Type != AttrData || a.Name != "" || a.Resident {
+				continue
+			}
+			runs, err := a.DataRuns()
+			if err != nil {
+				return err
+			}
+			t.runs = append(t.runs, runs...)
+		}
+	}
+
+	slices.SortFunc(t.runs, func(a, b DataRun) int {
+		return cmp.Compare(a.VCN, b.VCN)
+	})
+
+	return nil
+}
+
+// Close closes the underlying volume if the table was opened by Open.
+func (t *Table) Close() error {
+	if t.closer == nil {
+		return nil
+	}
+
+	return t.closer.Close()
+}
+
+// BootSector returns the geometry of the volume.
+func (t *Table) BootSector() *BootSector {
+	return t.boot
+}
+
+// RecordSize returns the size of a FILE record in bytes.
+func (t *Table) RecordSize() int {
+	return int(t.boot.RecordSize)
+}
+
+// Count returns the number of record slots in the table, used or not.
+func (t *Table) Count() uint64 {
+	return uint64(t.size / int64(t.boot.RecordSize))
+}
+
+// Records returns an iterator over the records of the table in record
+// number order. Slots which have never been used are skipped, and records
+// which cannot be parsed are reported as a *RecordError, after which the
+// iteration may continue.
+func (t *Table) Records() iter.Seq2[*Record, error] {
+	return func(yield func(*Record, error) bool) {
+		size := int64(t.boot.RecordSize)
+		chunk := make([]byte, readChunk/size*size)
+
+		for off := int64(0); off < t.size; {
+			n := min(int64(len(chunk)), t.size-off) / size * size
+			if n == 0 {
+				return
+			}
+			buf := chunk[:n]
+			if err := t.readAt(buf, off); err != nil {
+				yield(nil, err)
+				return
+			}
+
+			for i := int64(0); i < n; i += size {
+				number := uint64((off + i) / size)
+				if isEmptyRecord(buf[i:]) {
+					continue
+				}
+
+				// Records keep referring to their buffer, which must not
+				// be overwritten by the next chunk.
+				b := bytes.Clone(buf[i : i+size])
+
+				rec, err := ParseRecord(b, number)
+				if err != nil {
+					err = &RecordError{Number: number, Err: err}
+				}
+				if !yield(rec, err) {
+					return
+				}
+			}
+			off += n
+		}
+	}
+}
+
+// readAt reads len(buf) bytes of $MFT data starting at off.
+func (t *Table) readAt(buf []byte, off int64) error {
+	return readRuns(t.r, t.runs, t.boot.BytesPerCluster(), buf, off)
+}
+
+// readRuns reads len(buf) bytes of the data described by runs, sorted by
+// VCN, starting at off. Sparse runs read as zeros.
+func readRuns(r io.ReaderAt, runs []DataRun, bpc int64, buf []byte, off int64) error {
+	for len(buf) > 0 {
+		vcn := off / bpc
+		i, found := slices.BinarySearchFunc(runs, vcn, func(r DataRun, vcn int64) int {
+			switch {
+			case r.VCN+r.Length <= vcn:
+				return -1
+			case r.VCN > vcn:
+				return 1
+			default:
+				return 0
+			}
+		})
+		if !found {
+			return io.ErrUnexpectedEOF
+		}
+		run := runs[i]
+
+		// Read up to the end of the run.
+		within := off - run.VCN*bpc
+		n := min(int64(len(buf)), run.Length*bpc-within)
+		if run.Sparse() {
+			clear(buf[:n])
+		} else if _, err := r.ReadAt(buf[:n], run.LCN*bpc+within); err != nil {
+			return err
+		}
+
+		buf, off = buf[n:], off+n
+	}
+
+	return nil
+}
+
+// isEmptyRecord reports whether a record slot has never been written.
+func isEmptyRecord(b []byte) bool {
+	return b[0] == 0 && b[1] == 0 && b[2] == 0 && b[3] == 0
+}
@@ -0,0 +1,38 @@
+package mft
+
+import (
+	"os"
+	"strings"
+
+	"golang.org/x/sys/windows"
+
+	"github.com/go-sw/ntfs/internal/fsctl"
+	"github.com/go-sw/ntfs/volume"
+)
+
+// Open opens the master file table of the NTFS volume containing vol,
+// which may be a drive letter, a mounted folder, a volume GUID path or
+// any path on the volume. The volume is read directly through a handle
+// opened with backup semantics, which requires administrative rights.
+func Open(vol string) (*Table, error) {
+	g, err := volume.GUIDPath(vol)
+	if err != nil {
+		return nil, err
+	}
+	g = strings.TrimSuffix(g, `\`)
+
+	h, err := fsctl.Open(g, windows.GENERIC_READ, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	f := os.NewFile(uintptr(h), g)
+	t, err := NewTable(f)
+	if err != nil {
+		f.Close()
+		return nil, &os.PathError{Op: "open", Path: vol, Err: err}
+	}
+	t.closer = f
+
+	return t, nil
+}
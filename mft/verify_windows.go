@@ -0,0 +1,50 @@
+package mft
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/windows"
+
+	"github.com/go-sw/ntfs/internal/fsctl"
+	"github.com/go-sw/ntfs/volume"
+)
+
+var ErrWrongVolume = errors.New("mft: file is not on the volume of the table")
+
+// VerifyExtents compares the extents of the unnamed data stream of the
+// file at path, as parsed from the MFT, with those reported by the file
+// system through FSCTL_GET_RETRIEVAL_POINTERS. A difference points at
+// on-disk corruption, or at a change of the file between both reads. It
+// returns nil if the extents match, or an *ExtentMismatchError.
+func (t *Table) VerifyExtents(path string) error {
+	h, err := fsctl.Open(path, windows.FILE_READ_ATTRIBUTES, 0)
+	if err != nil {
+		return err
+	}
+	defer windows.CloseHandle(h)
+
+	var fi windows.ByHandleFileInformation
+	if err := windows.GetFileInformationByHandle(h, &fi); err != nil {
+		return &os.PathError{Op: "GetFileInformationByHandle", Path: path, Err: err}
+	}
+	if fi.VolumeSerialNumber != uint32(t.boot.SerialNumber) {
+		return &os.PathError{Op: "VerifyExtents", Path: path, Err: ErrWrongVolume}
+	}
+
+	reported, err := volume.FileExtentsHandle(h)
+	if err != nil {
+		return &os.PathError{Op: "FSCTL_GET_RETRIEVAL_POINTERS", Path: path, Err: err}
+	}
+
+	rec, err := t.Record(uint64(fi.FileIndexHigh)<<32 | uint64(fi.FileIndexLow))
+	if err != nil {
+		return err
+	}
+	parsed, err := t.FileExtents(rec, "")
+	if err != nil {
+		return err
+	}
+
+	return CompareExtents(parsed, reported)
+}
@@ -0,0 +1,139 @@
+package notify
+
+import (
+	"context"
+	"time"
+)
+
+// pairRenames merges the RenamedFrom events of batch followed by a
+// RenamedTo event into Renamed events. The system reports both names of a
+// renamed file in a row in the same batch.
+func pairRenames(batch []Event) []Event {
+	paired := batch[:0]
+	for i := 0; i < len(batch); i++ {
+		e := batch[i]
+		if e.Kind == RenamedFrom && i+1 < len(batch) && batch[i+1].Kind == RenamedTo {
+			to := batch[i+1]
+			to.Kind, to.OldName, to.OldPath = Renamed, e.Name, e.Path
+			e = to
+			i++
+		}
+		paired = append(paired, e)
+	}
+
+	return paired
+}
+
+// pendingEvent is a Modified event held by coalesce until its deadline.
+type pendingEvent struct {
+	event    Event
+	deadline time.Time
+	// flushed is set once the event was sent ahead of its deadline.
+	flushed bool
+}
+
+// coalesce forwards the events of in to out, merging the Modified events
+// of each file within window, and closes out once in is closed or ctx is
+// done.
+func coalesce(ctx context.Context, in <-chan Event, out chan<- Event, window time.Duration) {
+	defer close(out)
+
+	var (
+		pending = make(map[string]*pendingEvent)
+		// queue holds the pending events by deadline, which is the
+		// order in which they were first seen.
+		queue []*pendingEvent
+		timer = time.NewTimer(window)
+	)
+	defer timer.Stop()
+
+	send := func(e Event) bool {
+		select {
+		case out <- e:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+	// flush sends the pending event of path, if any.
+	flush := func(path string) bool {
+		p, ok := pending[path]
+		if !ok {
+			return true
+		}
+		delete(pending, path)
+		p.flushed = true
+
+		return send(p.event)
+	}
+	// flushAll sends the pending events in the order they were first
+	// seen.
+	flushAll := func() bool {
+		for _, p := range queue {
+			if !p.flushed && !flush(p.event.Path) {
+				return false
+			}
+		}
+		queue = queue[:0]
+
+		return true
+	}
+
+	for {
+		var expired <-chan time.Time
+		if len(queue) > 0 {
+			timer.Reset(time.Until(queue[0].deadline))
+			expired = timer.C
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+
+		case e, ok := <-in:
+			if !ok {
+				flushAll()
+				return
+			}
+
+			if e.Kind == Modified {
+				if p, ok := pending[e.Path]; ok {
+					p.event = e
+					continue
+				}
+				p := &pendingEvent{event: e, deadline: time.Now().Add(window)}
+				pending[e.Path] = p
+				queue = append(queue, p)
+				continue
+			}
+
+			// The last event of a failed watch, and those marking
+			// the loss or recovery of changes, come after all the
+			// pending modifications. Other events of a file, and of
+			// the old name of a renamed file, come after its own.
+			if e.Err != nil || e.Kind == Overflow || e.Kind == Resync {
+				if !flushAll() {
+					return
+				}
+			} else if !flush(e.Path) || e.OldPath != "" && !flush(e.OldPath) {
+				return
+			}
+			if !send(e) {
+				return
+			}
+
+		case <-expired:
+			now := time.Now()
+			for len(queue) > 0 && !queue[0].deadline.After(now) {
+				p := queue[0]
+				queue = queue[1:]
+				if p.flushed {
+					continue
+				}
+				if !flush(p.event.Path) {
+					return
+				}
+			}
+		}
+	}
+}
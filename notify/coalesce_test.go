@@ -0,0 +1,189 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+// runCoalesce feeds events to coalesce, closes its input and returns what
+// it sent.
+func runCoalesce(t *testing.T, window time.Duration, events ...Event) []Event {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	in, out := make(chan Event), make(chan Event)
+	go coalesce(ctx, in, out, window)
+	go func() {
+		defer close(in)
+		for _, e := range events {
+			select {
+			case in <- e:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var got []Event
+	for e := range out {
+		got = append(got, e)
+	}
+	if ctx.Err() != nil {
+		t.Fatal("coalesce did not close its output")
+	}
+
+	return got
+}
+
+// describe returns the kind and path of each event.
+func describe(events []Event) []string {
+	var s []string
+	for _, e := range events {
+		s = append(s, e.Kind.String()+" "+e.Path)
+		if e.Err != nil {
+			s[len(s)-1] += " err"
+		}
+	}
+
+	return s
+}
+
+func TestCoalesceFlushesBeforeMarkers(t *testing.T) {
+	errGone := errors.New("gone")
+	tests := []struct {
+		name   string
+		events []Event
+		want   []string
+	}{
+		{
+			name: "error",
+			events: []Event{
+				{Kind: Modified, Path: `C:\d\a`},
+				{Kind: Modified, Path: `C:\d\b`},
+				{Kind: Overflow, Path: `C:\d`, Err: errGone},
+			},
+			want: []string{`modified C:\d\a`, `modified C:\d\b`, `overflow C:\d err`},
+		},
+		{
+			name: "overflow",
+			events: []Event{
+				{Kind: Modified, Path: `C:\d\a`},
+				{Kind: Overflow, Path: `C:\d`},
+				{Kind: Modified, Path: `C:\d\a`},
+			},
+			want: []string{`modified C:\d\a`, `overflow C:\d`, `modified C:\d\a`},
+		},
+		{
+			name: "resync",
+			events: []Event{
+				{Kind: Modified, Path: `C:\d\a`, Rescan: true},
+				{Kind: Created, Path: `C:\d\b`, Rescan: true},
+				{Kind: Modified, Path: `C:\d\c`, Rescan: true},
+				{Kind: Resync, Path: `C:\d`},
+			},
+			want: []string{`created C:\d\b`, `modified C:\d\a`, `modified C:\d\c`, `resync C:\d`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := describe(runCoalesce(t, time.Hour, tt.events...))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("events = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPairRenames(t *testing.T) {
+	batch := []Event{
+		{Kind: RenamedFrom, Name: "a", Path: `C:\d\a`},
+		{Kind: RenamedTo, Name: "b", Path: `C:\d\b`},
+		{Kind: RenamedFrom, Name: "c", Path: `C:\d\c`},
+		{Kind: Created, Name: "e", Path: `C:\d\e`},
+		{Kind: RenamedTo, Name: "f", Path: `C:\d\f`},
+		{Kind: RenamedFrom, Name: "g", Path: `C:\d\g`},
+	}
+	want := []Event{
+		{Kind: Renamed, Name: "b", Path: `C:\d\b`, OldName: "a", OldPath: `C:\d\a`},
+		{Kind: RenamedFrom, Name: "c", Path: `C:\d\c`},
+		{Kind: Created, Name: "e", Path: `C:\d\e`},
+		{Kind: RenamedTo, Name: "f", Path: `C:\d\f`},
+		{Kind: RenamedFrom, Name: "g", Path: `C:\d\g`},
+	}
+
+	got := pairRenames(batch)
+	if !slices.EqualFunc(got, want, func(a, b Event) bool { return a == b }) {
+		t.Errorf("pairRenames = %+v, want %+v", got, want)
+	}
+}
+
+func TestCoalesce(t *testing.T) {
+	tests := []struct {
+		name   string
+		events []Event
+		want   []string
+	}{
+		{
+			name: "merged",
+			events: []Event{
+				{Kind: Modified, Path: `C:\d\a`},
+				{Kind: Modified, Path: `C:\d\b`},
+				{Kind: Modified, Path: `C:\d\a`},
+				{Kind: Modified, Path: `C:\d\a`},
+			},
+			want: []string{`modified C:\d\a`, `modified C:\d\b`},
+		},
+		{
+			name: "flushed by a later event of the file",
+			events: []Event{
+				{Kind: Modified, Path: `C:\d\a`},
+				{Kind: Modified, Path: `C:\d\b`},
+				{Kind: Removed, Path: `C:\d\b`},
+				{Kind: Modified, Path: `C:\d\b`},
+			},
+			want: []string{`modified C:\d\b`, `removed C:\d\b`, `modified C:\d\a`, `modified C:\d\b`},
+		},
+		{
+			name: "flushed by a rename from the file",
+			events: []Event{
+				{Kind: Modified, Path: `C:\d\a`},
+				{Kind: Renamed, Path: `C:\d\b`, OldPath: `C:\d\a`},
+			},
+			want: []string{`modified C:\d\a`, `renamed C:\d\b`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := describe(runCoalesce(t, time.Hour, tt.events...))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("events = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCoalesceWindow(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	in, out := make(chan Event), make(chan Event)
+	go coalesce(ctx, in, out, 10*time.Millisecond)
+	defer close(in)
+
+	in <- Event{Kind: Modified, Path: `C:\d\a`}
+	in <- Event{Kind: Modified, Path: `C:\d\a`}
+	select {
+	case e := <-out:
+		if e.Kind != Modified || e.Path != `C:\d\a` {
+			t.Errorf("event = %v %s", e.Kind, e.Path)
+		}
+	case <-ctx.Done():
+		t.Fatal("the pending event was not sent when its window ended")
+	}
+}
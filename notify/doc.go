@@ -0,0 +1,3 @@
+// Package notify watches directories for changes with
+// ReadDirectoryChangesW, delivering typed events on channels.
+package notify
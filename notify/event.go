@@ -0,0 +1,170 @@
+package notify
+
+import (
+	"encoding/binary"
+	"unicode/utf16"
+)
+
+// Mask selects the changes reported by a watch. It holds the
+// FILE_NOTIFY_CHANGE_* flags.
+type Mask uint32
+
+const (
+	FileName   Mask = 0x001 // FILE_NOTIFY_CHANGE_FILE_NAME
+	DirName    Mask = 0x002 // FILE_NOTIFY_CHANGE_DIR_NAME
+	Attributes Mask = 0x004 // FILE_NOTIFY_CHANGE_ATTRIBUTES
+	Size       Mask = 0x008 // FILE_NOTIFY_CHANGE_SIZE
+	LastWrite  Mask = 0x010 // FILE_NOTIFY_CHANGE_LAST_WRITE
+	LastAccess Mask = 0x020 // FILE_NOTIFY_CHANGE_LAST_ACCESS
+	Creation   Mask = 0x040 // FILE_NOTIFY_CHANGE_CREATION
+	Security   Mask = 0x100 // FILE_NOTIFY_CHANGE_SECURITY
+	StreamName Mask = 0x200 // FILE_NOTIFY_CHANGE_STREAM_NAME
+	StreamSize Mask = 0x400 // FILE_NOTIFY_CHANGE_STREAM_SIZE
+	StreamData Mask = 0x800 // FILE_NOTIFY_CHANGE_STREAM_WRITE
+
+	// All selects every change.
+	All Mask = 0xFFF &^ 0x080
+	// Default selects the creation, deletion, renaming and modification of
+	// files and directories.
+	Default = FileName | DirName | Size | LastWrite
+)
+
+// Kind is the kind of change reported by an event.
+type Kind int
+
+const (
+	// Overflow reports that changes were lost, as more happened than the
+	// buffer of the watch could hold, which the system reports as
+	// ERROR_NOTIFY_ENUM_DIR. The event has no name.
+	Overflow Kind = iota
+	Created
+	Removed
+	Modified
+	// RenamedFrom and RenamedTo report the old and new names of a renamed
+	// file, in this order.
+	RenamedFrom
+	RenamedTo
+	StreamAdded
+	StreamRemoved
+	StreamModified
+	// SecurityChanged reports a change of the security descriptor of a
+	// file, which is only told apart from other modifications when the
+	// watch mask includes Security.
+	SecurityChanged
+	// Renamed reports a renamed file, with its old name, when renames are
+	// paired.
+	Renamed
+	// Resync reports that a watch which rescans its directory on overflow
+	// caught up with the changes lost by the last Overflow event, the
+	// events since being derived from the rescan. The event has no name.
+	Resync
+)
+
+var kindNames = [...]string{
+	Overflow:        "overflow",
+	Created:         "created",
+	Removed:         "removed",
+	Modified:        "modified",
+	RenamedFrom:     "renamed from",
+	RenamedTo:       "renamed to",
+	StreamAdded:     "stream added",
+	StreamRemoved:   "stream removed",
+	StreamModified:  "stream modified",
+	SecurityChanged: "security changed",
+	Renamed:         "renamed",
+	Resync:          "resync",
+}
+
+func (k Kind) String() string {
+	if k < 0 || int(k) >= len(kindNames) {
+		return "unknown change"
+	}
+
+	return kindNames[k]
+}
+
+// FILE_ACTION_* values.
+const (
+	actionAdded          = 1
+	actionRemoved        = 2
+	actionModified       = 3
+	actionRenamedOldName = 4
+	actionRenamedNewName = 5
+	actionAddedStream    = 6
+	actionRemovedStream  = 7
+	actionModifiedStream = 8
+)
+
+func actionKind(action uint32) (Kind, bool) {
+	switch action {
+	case actionAdded:
+		return Created, true
+	case actionRemoved:
+		return Removed, true
+	case actionModified:
+		return Modified, true
+	case actionRenamedOldName:
+		return RenamedFrom, true
+	case actionRenamedNewName:
+		return RenamedTo, true
+	case actionAddedStream:
+		return StreamAdded, true
+	case actionRemovedStream:
+		return StreamRemoved, true
+	case actionModifiedStream:
+		return StreamModified, true
+	default:
+		return 0, false
+	}
+}
+
+// Event is a change of a file in a watched directory.
+type Event struct {
+	Kind Kind
+	// Name is the path of the file relative to the watched directory. For
+	// stream events, it is followed by a colon and the name of the stream.
+	Name string
+	// Path is the full path of the file.
+	Path string
+	// OldName and OldPath are the previous name and path of the file for
+	// Renamed events.
+	OldName string
+	OldPath string
+	// Details are set if the watch reports extended notifications.
+	Details *Details
+	// Rescan is set on the events derived from the rescan of a watched
+	// directory after an overflow, which may repeat changes already
+	// reported, and lack intermediate changes such as renames.
+	Rescan bool
+	// Err is set on the last event sent before the channel is closed if
+	// the watch failed, as when the watched directory was removed. The
+	// kind of the event is then Overflow, as later changes are lost.
+	Err error
+}
+
+// parseNotifications calls fn for every FILE_NOTIFY_INFORMATION of b, the
+// output of ReadDirectoryChangesW, until it returns false.
+func parseNotifications(b []byte, fn func(action uint32, name string) bool) {
+	for len(b) >= 12 {
+		next := binary.LittleEndian.Uint32(b)
+		action := binary.LittleEndian.Uint32(b[4:])
+		n := int(binary.LittleEndian.Uint32(b[8:]))
+		if 12+n > len(b) {
+			return
+		}
+
+		if !fn(action, decodeName(b[12:12+n])) || next == 0 || int(next) > len(b) {
+			return
+		}
+		b = b[next:]
+	}
+}
+
+func decodeName(b []byte) string {
+	u := make([]uint16, len(b)/2)
+	for i := range u {
+		u[i] = binary.LittleEndian.Uint16(b[2*i:])
+	}
+
+	return string(utf16.Decode(u))
+}
@@ -0,0 +1,119 @@
+package notify
+
+import (
+	"encoding/binary"
+	"slices"
+	"testing"
+	"unicode/utf16"
+)
+
+// encodeName returns name in UTF-16LE.
+func encodeName(name string) []byte {
+	var b []byte
+	for _, u := range utf16.Encode([]rune(name)) {
+		b = binary.LittleEndian.AppendUint16(b, u)
+	}
+
+	return b
+}
+
+// notification returns a FILE_NOTIFY_INFORMATION with the given fields,
+// padded to a multiple of 4 bytes when next is not 0.
+func notification(next, action uint32, name []byte) []byte {
+	b := binary.LittleEndian.AppendUint32(nil, next)
+	b = binary.LittleEndian.AppendUint32(b, action)
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(name)))
+	b = append(b, name...)
+	for next != 0 && len(b) < int(next) {
+		b = append(b, 0)
+	}
+
+	return b
+}
+
+type notified struct {
+	action uint32
+	name   string
+}
+
+func TestParseNotifications(t *testing.T) {
+	a, bc := encodeName("a.txt"), encodeName(`dir\bc`)
+
+	tests := []struct {
+		name string
+		b    []byte
+		want []notified
+	}{
+		{name: "empty"},
+		{
+			name: "single",
+			b:    notification(0, actionAdded, a),
+			want: []notified{{actionAdded, "a.txt"}},
+		},
+		{
+			name: "chained",
+			b: slices.Concat(
+				notification(24, actionRenamedOldName, a),
+				notification(0, actionRenamedNewName, bc)),
+			want: []notified{{actionRenamedOldName, "a.txt"}, {actionRenamedNewName, `dir\bc`}},
+		},
+		{
+			name: "surrogate pair",
+			b:    notification(0, actionModified, encodeName("😀")),
+			want: []notified{{actionModified, "😀"}},
+		},
+		{
+			name: "short header",
+			b:    notification(0, actionAdded, a)[:11],
+		},
+		{
+			name: "truncated name",
+			b:    notification(0, actionAdded, a)[:12+len(a)-1],
+		},
+		{
+			name: "truncated second record",
+			b: slices.Concat(
+				notification(24, actionRemoved, a),
+				notification(0, actionAdded, bc)[:16]),
+			want: []notified{{actionRemoved, "a.txt"}},
+		},
+		{
+			name: "next past the end",
+			b:    notification(64, actionRemoved, a)[:24],
+			want: []notified{{actionRemoved, "a.txt"}},
+		},
+		{
+			name: "odd name length",
+			b:    notification(0, actionAdded, append(encodeName("ab"), 'c')),
+			want: []notified{{actionAdded, "ab"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []notified
+			parseNotifications(tt.b, func(action uint32, name string) bool {
+				got = append(got, notified{action, name})
+				return true
+			})
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("notifications = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseNotificationsStops(t *testing.T) {
+	b := slices.Concat(
+		notification(24, actionAdded, encodeName("a.txt")),
+		notification(0, actionAdded, encodeName("b.txt")))
+
+	n := 0
+	parseNotifications(b, func(uint32, string) bool {
+		n++
+		return false
+	})
+	if n != 1 {
+		t.Errorf("fn called %d times, want 1", n)
+	}
+}
@@ -0,0 +1,102 @@
+package notify
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"time"
+)
+
+// FileID is a 128-bit file identifier, as used by ReFS. The 64-bit file
+// reference numbers of NTFS fill its first 8 bytes in little endian order,
+// the others being zero.
+type FileID [16]byte
+
+// FileID64 returns the FileID holding the 64-bit identifier id.
+func FileID64(id uint64) FileID {
+	var f FileID
+	binary.LittleEndian.PutUint64(f[:], id)
+	return f
+}
+
+// Uint64 returns the 64-bit identifier held by f, and false if f does not
+// fit in 64 bits.
+func (f FileID) Uint64() (uint64, bool) {
+	return binary.LittleEndian.Uint64(f[:]), binary.LittleEndian.Uint64(f[8:]) == 0
+}
+
+func (f FileID) String() string {
+	if id, ok := f.Uint64(); ok {
+		return "0x" + hex.EncodeToString(binary.BigEndian.AppendUint64(nil, id))
+	}
+
+	var b [16]byte
+	for i := range f {
+		b[i] = f[15-i]
+	}
+	return "0x" + hex.EncodeToString(b[:])
+}
+
+// Details are the properties of a file reported along with its changes by
+// extended notifications, as they were when the change happened.
+type Details struct {
+	FileID   FileID
+	ParentID FileID
+	// Size and AllocatedSize are the size of the unnamed data stream of
+	// the file and the space allocated to it.
+	Size          int64
+	AllocatedSize int64
+	// Attributes are the FILE_ATTRIBUTE_* flags of the file.
+	Attributes       uint32
+	CreationTime     time.Time
+	ModificationTime time.Time
+	ChangeTime       time.Time
+	AccessTime       time.Time
+}
+
+// extendedHeaderSize is the size of FILE_NOTIFY_EXTENDED_INFORMATION
+// before its file name.
+const extendedHeaderSize = 84
+
+// parseExtendedNotifications calls fn for every
+// FILE_NOTIFY_EXTENDED_INFORMATION of b, the output of
+// ReadDirectoryChangesExW, until it returns false.
+func parseExtendedNotifications(b []byte, fn func(action uint32, name string, d *Details) bool) {
+	for len(b) >= extendedHeaderSize {
+		next := binary.LittleEndian.Uint32(b)
+		n := int(binary.LittleEndian.Uint32(b[80:]))
+		if extendedHeaderSize+n > len(b) {
+			return
+		}
+
+		d := &Details{
+			CreationTime:     filetime(b[8:]),
+			ModificationTime: filetime(b[16:]),
+			ChangeTime:       filetime(b[24:]),
+			AccessTime:       filetime(b[32:]),
+			AllocatedSize:    int64(binary.LittleEndian.Uint64(b[40:])),
+			Size:             int64(binary.LittleEndian.Uint64(b[48:])),
+			Attributes:       binary.LittleEndian.Uint32(b[56:]),
+			FileID:           FileID64(binary.LittleEndian.Uint64(b[64:])),
+			ParentID:         FileID64(binary.LittleEndian.Uint64(b[72:])),
+		}
+		name := decodeName(b[extendedHeaderSize : extendedHeaderSize+n])
+		if !fn(binary.LittleEndian.Uint32(b[4:]), name, d) || next == 0 || int(next) > len(b) {
+			return
+		}
+		b = b[next:]
+	}
+}
+
+// filetimeEpoch is the number of 100ns intervals between 1601-01-01 and
+// 1970-01-01.
+const filetimeEpoch = 116444736000000000
+
+func filetime(b []byte) time.Time {
+	ft := int64(binary.LittleEndian.Uint64(b))
+	if ft == 0 {
+		return time.Time{}
+	}
+
+	ft -= filetimeEpoch
+	return time.Unix(ft/1e7, ft%1e7*100)
+}
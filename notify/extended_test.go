@@ -0,0 +1,140 @@
+package notify
+
+import (
+	"encoding/binary"
+	"slices"
+	"testing"
+	"time"
+)
+
+// extendedNotification returns a FILE_NOTIFY_EXTENDED_INFORMATION whose
+// fields hold distinct values, padded to next bytes when next is not 0.
+func extendedNotification(next, action uint32, name []byte) []byte {
+	b := make([]byte, extendedHeaderSize, extendedHeaderSize+len(name))
+	le := binary.LittleEndian
+	le.PutUint32(b[0:], next)
+	le.PutUint32(b[4:], action)
+	le.PutUint64(b[8:], filetimeEpoch+1e7)  // CreationTime
+	le.PutUint64(b[16:], filetimeEpoch+2e7) // LastModificationTime
+	le.PutUint64(b[24:], filetimeEpoch+3e7) // LastChangeTime
+	le.PutUint64(b[32:], filetimeEpoch+4e7) // LastAccessTime
+	le.PutUint64(b[40:], 8192)              // AllocatedLength
+	le.PutUint64(b[48:], 5000)              // FileSize
+	le.PutUint32(b[56:], 0x20)              // FileAttributes
+	le.PutUint32(b[60:], 0xA000000C)        // ReparsePointTag
+	le.PutUint64(b[64:], 0x0001000000000123)
+	le.PutUint64(b[72:], 0x0005000000000005)
+	le.PutUint32(b[80:], uint32(len(name)))
+	b = append(b, name...)
+	for next != 0 && len(b) < int(next) {
+		b = append(b, 0)
+	}
+
+	return b
+}
+
+func TestParseExtendedNotificationsOffsets(t *testing.T) {
+	var got *Details
+	var name string
+	parseExtendedNotifications(extendedNotification(0, actionModified, encodeName("a.txt")),
+		func(action uint32, n string, d *Details) bool {
+			if action != actionModified {
+				t.Errorf("action = %d, want %d", action, actionModified)
+			}
+			name, got = n, d
+			return true
+		})
+
+	want := &Details{
+		FileID:           FileID64(0x0001000000000123),
+		ParentID:         FileID64(0x0005000000000005),
+		Size:             5000,
+		AllocatedSize:    8192,
+		Attributes:       0x20,
+		CreationTime:     time.Unix(1, 0),
+		ModificationTime: time.Unix(2, 0),
+		ChangeTime:       time.Unix(3, 0),
+		AccessTime:       time.Unix(4, 0),
+	}
+	if name != "a.txt" {
+		t.Errorf("name = %q, want %q", name, "a.txt")
+	}
+	if got == nil {
+		t.Fatal("no notification")
+	}
+	if got.FileID != want.FileID || got.ParentID != want.ParentID || got.Size != want.Size ||
+		got.AllocatedSize != want.AllocatedSize || got.Attributes != want.Attributes ||
+		!got.CreationTime.Equal(want.CreationTime) || !got.ModificationTime.Equal(want.ModificationTime) ||
+		!got.ChangeTime.Equal(want.ChangeTime) || !got.AccessTime.Equal(want.AccessTime) {
+		t.Errorf("details = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseExtendedNotifications(t *testing.T) {
+	a, bc := encodeName("a.txt"), encodeName(`dir\bc`)
+
+	tests := []struct {
+		name string
+		b    []byte
+		want []notified
+	}{
+		{name: "empty"},
+		{
+			name: "chained",
+			b: slices.Concat(
+				extendedNotification(96, actionRenamedOldName, a),
+				extendedNotification(0, actionRenamedNewName, bc)),
+			want: []notified{{actionRenamedOldName, "a.txt"}, {actionRenamedNewName, `dir\bc`}},
+		},
+		{
+			name: "short header",
+			b:    extendedNotification(0, actionAdded, a)[:extendedHeaderSize-1],
+		},
+		{
+			name: "truncated name",
+			b:    extendedNotification(0, actionAdded, a)[:extendedHeaderSize+len(a)-1],
+		},
+		{
+			name: "truncated second record",
+			b: slices.Concat(
+				extendedNotification(96, actionRemoved, a),
+				extendedNotification(0, actionAdded, bc)[:40]),
+			want: []notified{{actionRemoved, "a.txt"}},
+		},
+		{
+			name: "next past the end",
+			b:    extendedNotification(200, actionRemoved, a)[:96],
+			want: []notified{{actionRemoved, "a.txt"}},
+		},
+		{
+			name: "odd name length",
+			b:    extendedNotification(0, actionAdded, append(encodeName("ab"), 'c')),
+			want: []notified{{actionAdded, "ab"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []notified
+			parseExtendedNotifications(tt.b, func(action uint32, name string, _ *Details) bool {
+				got = append(got, notified{action, name})
+				return true
+			})
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("notifications = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFiletime(t *testing.T) {
+	var zero [8]byte
+	if got := filetime(zero[:]); !got.IsZero() {
+		t.Errorf("filetime(0) = %v, want the zero time", got)
+	}
+
+	b := binary.LittleEndian.AppendUint64(nil, filetimeEpoch+15)
+	if got, want := filetime(b), time.Unix(0, 1500); !got.Equal(want) {
+		t.Errorf("filetime = %v, want %v", got, want)
+	}
+}
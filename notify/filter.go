@@ -0,0 +1,133 @@
+package notify
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Filter selects the events delivered by a watch by the names and
+// attributes of their files. The events of the watch itself, such as
+// Overflow events, are always delivered.
+type Filter struct {
+	// Include, if not empty, only delivers the events of files matching
+	// one of its patterns, and Exclude drops the events of files matching
+	// one of its patterns. Patterns use the syntax of filepath.Match and
+	// are matched case-insensitively. A pattern without separator is
+	// matched against every element of the name of a file relative to the
+	// watched directory, so that "node_modules" excludes a whole tree,
+	// while other patterns are matched against the name and its parent
+	// directories.
+	Include []string
+	Exclude []string
+	// Extensions, if not empty, only delivers the events of files with one
+	// of these extensions, such as ".go", compared case-insensitively.
+	// Directories usually have no extension and are dropped as well.
+	Extensions []string
+	// ExcludeAttributes drops the events of files having any of these
+	// FILE_ATTRIBUTE_* flags, such as FILE_ATTRIBUTE_TEMPORARY. It only
+	// applies to events with Details, which extended notifications
+	// report.
+	ExcludeAttributes uint32
+	// Kinds, if not empty, only delivers the events of these kinds.
+	Kinds []Kind
+}
+
+// compiledFilter is a Filter validated and normalized for matching.
+type compiledFilter struct {
+	include, exclude []string
+	extensions       []string
+	attributes       uint32
+	kinds            []Kind
+}
+
+func compileFilter(f *Filter) (*compiledFilter, error) {
+	if f == nil {
+		return nil, nil
+	}
+
+	c := &compiledFilter{attributes: f.ExcludeAttributes, kinds: f.Kinds}
+	for _, list := range []struct {
+		in  []string
+		out *[]string
+	}{{f.Include, &c.include}, {f.Exclude, &c.exclude}} {
+		for _, p := range list.in {
+			p = strings.ToLower(filepath.Clean(p))
+			if _, err := filepath.Match(p, ""); err != nil {
+				return nil, err
+			}
+			*list.out = append(*list.out, p)
+		}
+	}
+	for _, ext := range f.Extensions {
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		c.extensions = append(c.extensions, strings.ToLower(ext))
+	}
+
+	return c, nil
+}
+
+// match reports whether e must be delivered.
+func (c *compiledFilter) match(e *Event) bool {
+	if c == nil || e.Name == "" {
+		return true
+	}
+	if len(c.kinds) > 0 && !slices.Contains(c.kinds, e.Kind) {
+		return false
+	}
+	if e.Details != nil && e.Details.Attributes&c.attributes != 0 {
+		return false
+	}
+
+	// Renamed files are delivered if either name matches, as they may
+	// have entered or left the selected files.
+	if e.Kind == Renamed {
+		return c.matchName(e.Name) || c.matchName(e.OldName)
+	}
+
+	return c.matchName(e.Name)
+}
+
+func (c *compiledFilter) matchName(name string) bool {
+	// The events of named streams carry the stream name after a colon.
+	if i := strings.IndexByte(name, ':'); i >= 0 {
+		name = name[:i]
+	}
+	name = strings.ToLower(name)
+
+	if len(c.extensions) > 0 && !slices.Contains(c.extensions, filepath.Ext(name)) {
+		return false
+	}
+	if len(c.include) > 0 && !matchAny(c.include, name) {
+		return false
+	}
+
+	return !matchAny(c.exclude, name)
+}
+
+// matchAny reports whether name, or one of its parent directories, matches
+// one of patterns.
+func matchAny(patterns []string, name string) bool {
+	elems := strings.Split(name, string(filepath.Separator))
+	for _, p := range patterns {
+		if !strings.ContainsRune(p, filepath.Separator) {
+			for _, elem := range elems {
+				if ok, _ := filepath.Match(p, elem); ok {
+					return true
+				}
+			}
+			continue
+		}
+
+		for i := len(elems); i > 0; i-- {
+			prefix := strings.Join(elems[:i], string(filepath.Separator))
+			if ok, _ := filepath.Match(p, prefix); ok {
+				return true
+			}
+		}
+	}
+
+	return false
+}
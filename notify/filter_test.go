@@ -0,0 +1,130 @@
+package notify
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestFilterMatch(t *testing.T) {
+	name := filepath.FromSlash
+
+	tests := []struct {
+		name   string
+		filter Filter
+		event  Event
+		want   bool
+	}{
+		{
+			name:   "case-folded include",
+			filter: Filter{Include: []string{"*.GO"}},
+			event:  Event{Kind: Modified, Name: name("src/Main.Go")},
+			want:   true,
+		},
+		{
+			name:   "include misses",
+			filter: Filter{Include: []string{"*.go"}},
+			event:  Event{Kind: Modified, Name: name("src/main.c")},
+		},
+		{
+			name:   "bare element excludes a tree",
+			filter: Filter{Exclude: []string{"node_modules"}},
+			event:  Event{Kind: Created, Name: name("web/Node_Modules/x/index.js")},
+		},
+		{
+			name:   "bare element matches no part of an element",
+			filter: Filter{Exclude: []string{"node"}},
+			event:  Event{Kind: Created, Name: name("web/node_modules/index.js")},
+			want:   true,
+		},
+		{
+			name:   "separator pattern matches a parent",
+			filter: Filter{Exclude: []string{name("build/*")}},
+			event:  Event{Kind: Created, Name: name("build/out/a.o")},
+		},
+		{
+			name:   "separator pattern is anchored",
+			filter: Filter{Exclude: []string{name("build/*")}},
+			event:  Event{Kind: Created, Name: name("src/build/a.o")},
+			want:   true,
+		},
+		{
+			name:   "stream name is ignored",
+			filter: Filter{Extensions: []string{"txt"}},
+			event:  Event{Kind: StreamModified, Name: "a.TXT:Zone.Identifier"},
+			want:   true,
+		},
+		{
+			name:   "stream of an excluded file",
+			filter: Filter{Exclude: []string{"*.tmp"}},
+			event:  Event{Kind: StreamAdded, Name: "a.tmp:meta"},
+		},
+		{
+			name:   "extension misses",
+			filter: Filter{Extensions: []string{".go"}},
+			event:  Event{Kind: Created, Name: "dir"},
+		},
+		{
+			name:   "renamed into the selection",
+			filter: Filter{Include: []string{"*.go"}},
+			event:  Event{Kind: Renamed, Name: "a.go", OldName: "a.tmp"},
+			want:   true,
+		},
+		{
+			name:   "renamed out of the selection",
+			filter: Filter{Include: []string{"*.go"}},
+			event:  Event{Kind: Renamed, Name: "a.tmp", OldName: "a.go"},
+			want:   true,
+		},
+		{
+			name:   "renamed outside the selection",
+			filter: Filter{Include: []string{"*.go"}},
+			event:  Event{Kind: Renamed, Name: "b.tmp", OldName: "a.tmp"},
+		},
+		{
+			name:   "kind",
+			filter: Filter{Kinds: []Kind{Created, Removed}},
+			event:  Event{Kind: Modified, Name: "a"},
+		},
+		{
+			name:   "attributes",
+			filter: Filter{ExcludeAttributes: 0x100},
+			event:  Event{Kind: Modified, Name: "a", Details: &Details{Attributes: 0x120}},
+		},
+		{
+			name:   "attributes without details",
+			filter: Filter{ExcludeAttributes: 0x100},
+			event:  Event{Kind: Modified, Name: "a"},
+			want:   true,
+		},
+		{
+			name:   "watch event",
+			filter: Filter{Include: []string{"*.go"}, Kinds: []Kind{Created}},
+			event:  Event{Kind: Overflow, Path: name("/d")},
+			want:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := compileFilter(&tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := c.match(&tt.event); got != tt.want {
+				t.Errorf("match = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileFilter(t *testing.T) {
+	if c, err := compileFilter(nil); c != nil || err != nil {
+		t.Errorf("compileFilter(nil) = %v, %v", c, err)
+	}
+	if !(*compiledFilter)(nil).match(&Event{Kind: Created, Name: "a"}) {
+		t.Error("nil filter dropped an event")
+	}
+	if _, err := compileFilter(&Filter{Exclude: []string{"[a-"}}); err != filepath.ErrBadPattern {
+		t.Errorf("err = %v, want %v", err, filepath.ErrBadPattern)
+	}
+}
@@ -0,0 +1,88 @@
+package notify
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// fileState is the state of a file recorded by a snapshot.
+type fileState struct {
+	size    int64
+	modTime time.Time
+	dir     bool
+}
+
+// snapshot records the state of the files of a watched directory, to find
+// the changes lost by an overflow.
+type snapshot map[string]fileState
+
+// scan records the state of the files of dir, and of the tree below it if
+// recursive is set. Files which vanish during the scan are skipped.
+func scan(dir string, recursive bool) (snapshot, error) {
+	s := make(snapshot)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == dir {
+				return err
+			}
+			return nil
+		}
+		if path == dir {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		s[rel] = fileState{size: info.Size(), modTime: info.ModTime(), dir: d.IsDir()}
+
+		// Reparse points are reported as irregular files, and are not
+		// followed by WalkDir.
+		if d.IsDir() && !recursive {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// diff returns the events turning s into next: the creation of the files
+// only found in next, the removal of those only found in s, and the
+// modification of the files whose size or modification time differ.
+func (s snapshot) diff(dir string, next snapshot) []Event {
+	var events []Event
+	add := func(kind Kind, name string) {
+		events = append(events, Event{Kind: kind, Name: name, Path: filepath.Join(dir, name), Rescan: true})
+	}
+
+	for name, st := range s {
+		n, ok := next[name]
+		switch {
+		case !ok || n.dir != st.dir:
+			add(Removed, name)
+			if ok {
+				add(Created, name)
+			}
+		case !n.dir && (n.size != st.size || !n.modTime.Equal(st.modTime)):
+			add(Modified, name)
+		}
+	}
+	for name := range next {
+		if _, ok := s[name]; !ok {
+			add(Created, name)
+		}
+	}
+
+	return events
+}
@@ -0,0 +1,109 @@
+package notify
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSnapshotDiff(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	t1 := t0.Add(time.Second)
+	dir := filepath.FromSlash("/w")
+
+	old := snapshot{
+		"same":     {size: 1, modTime: t0},
+		"grown":    {size: 1, modTime: t0},
+		"touched":  {size: 1, modTime: t0},
+		"gone":     {size: 1, modTime: t0},
+		"sub":      {dir: true, modTime: t0},
+		"was dir":  {dir: true, modTime: t0},
+		"was file": {size: 1, modTime: t0},
+	}
+	next := snapshot{
+		"same":     {size: 1, modTime: t0},
+		"grown":    {size: 2, modTime: t0},
+		"touched":  {size: 1, modTime: t1},
+		"sub":      {dir: true, modTime: t1},
+		"was dir":  {size: 1, modTime: t0},
+		"was file": {dir: true, modTime: t0},
+		"new":      {size: 1, modTime: t1},
+	}
+	want := []string{
+		"removed gone",
+		"modified grown",
+		"created new",
+		"modified touched",
+		"removed was dir", "created was dir",
+		"removed was file", "created was file",
+	}
+
+	events := old.diff(dir, next)
+	// The events of different files come in no particular order.
+	slices.SortStableFunc(events, func(a, b Event) int { return strings.Compare(a.Name, b.Name) })
+
+	var got []string
+	for _, e := range events {
+		got = append(got, e.Kind.String()+" "+e.Name)
+		if e.Path != filepath.Join(dir, e.Name) || !e.Rescan {
+			t.Errorf("%s: Path = %q, Rescan = %v", e.Name, e.Path, e.Rescan)
+		}
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("diff = %q, want %q", got, want)
+	}
+
+	if events := next.diff(dir, next); len(events) != 0 {
+		t.Errorf("diff of a snapshot with itself = %v", events)
+	}
+}
+
+func TestScan(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a", "sub/b", "sub/deep/c"} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		recursive bool
+		want      []string
+	}{
+		{false, []string{"a", "sub"}},
+		{true, []string{"a", "sub", "sub/b", "sub/deep", "sub/deep/c"}},
+	}
+	for _, tt := range tests {
+		s, err := scan(dir, tt.recursive)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got []string
+		for name := range s {
+			got = append(got, filepath.ToSlash(name))
+		}
+		slices.Sort(got)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("scan(recursive %v) = %q, want %q", tt.recursive, got, tt.want)
+		}
+		if st := s["a"]; st.dir || st.size != 1 {
+			t.Errorf("state of a = %+v", st)
+		}
+		if !s["sub"].dir {
+			t.Error("sub is not a directory")
+		}
+	}
+
+	s, err := scan(filepath.Join(dir, "missing"), true)
+	if err != nil || len(s) != 0 {
+		t.Errorf("scan of a missing directory = %v, %v", s, err)
+	}
+}
@@ -0,0 +1,5 @@
+package notify
+
+//go:generate go run golang.org/x/sys/windows/mkwinsyscall -output zsyscall_windows.go syscall_windows.go
+
+//sys	readDirectoryChangesEx(h windows.Handle, buf *byte, size uint32, watchSubtree bool, filter uint32, returned *uint32, overlapped *windows.Overlapped, completionRoutine uintptr, class uint32) (err error) = kernel32.ReadDirectoryChangesExW
@@ -0,0 +1,394 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+
+	"github.com/go-sw/ntfs/internal/fsctl"
+)
+
+const (
+	// DefaultBufferSize is the size of the notification buffer of a watch
+	// of a local directory.
+	DefaultBufferSize = 256 << 10
+	// maxRemoteBufferSize is the largest buffer accepted for directories
+	// of network shares.
+	maxRemoteBufferSize = 64 << 10
+)
+
+// Options control a watch.
+type Options struct {
+	// Recursive watches the whole tree below the directory.
+	Recursive bool
+	// Mask selects the changes reported, or Default if zero.
+	Mask Mask
+	// BufferSize is the size of the notification buffer, which bounds the
+	// changes held between two reads of the watch before they overflow.
+	// It is DefaultBufferSize if zero, and is capped to 64KiB for network
+	// shares.
+	BufferSize int
+	// Extended reports the Details of the files along with their events,
+	// which lets consumers identify files by ID rather than by a path
+	// which may have changed since. It is ignored on systems older than
+	// Windows 10 1709 and on file systems lacking extended notifications.
+	Extended bool
+	// PairRenames reports the renames of files as single Renamed events,
+	// rather than as RenamedFrom and RenamedTo events.
+	PairRenames bool
+	// Coalesce, if not zero, merges the Modified events of a file
+	// following its first one within this window into a single event,
+	// sent once the window ends with the details of the last one. Other
+	// events of the file flush its pending modification first, keeping
+	// the events of each file in order, but the events of different files
+	// may be reordered.
+	Coalesce time.Duration
+	// Filter, if not nil, drops the events of the files it does not
+	// select before they are sent, so that busy directories which are of
+	// no interest, such as build outputs, cost nothing to consumers.
+	Filter *Filter
+	// Rescan recovers from overflows by rescanning the watched directory
+	// and reporting the differences with its previous scan, followed by a
+	// Resync event. The directory is scanned once before WatchWith
+	// returns, and the size and modification time of every file are kept
+	// in memory.
+	Rescan bool
+}
+
+// Watch watches the directory dir, and the tree below it if recursive is
+// set, for the changes selected by filter. The events are sent on the
+// returned channel, which is closed once ctx is done or the watch fails.
+func Watch(ctx context.Context, dir string, recursive bool, filter Mask) (<-chan Event, error) {
+	return WatchWith(ctx, dir, &Options{Recursive: recursive, Mask: filter})
+}
+
+// WatchWith is like Watch, with the watch controlled by opts. A nil opts is
+// the same as the zero Options.
+func WatchWith(ctx context.Context, dir string, opts *Options) (<-chan Event, error) {
+	if opts == nil {
+		opts = new(Options)
+	}
+
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	names, err := compileFilter(opts.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := opts.Mask
+	if filter == 0 {
+		filter = Default
+	}
+	size := opts.BufferSize
+	if size <= 0 {
+		size = DefaultBufferSize
+	}
+	if isRemote(dir) {
+		size = min(size, maxRemoteBufferSize)
+	}
+
+	// Security changes are reported as modifications, and are told
+	// apart by watching them separately.
+	filters := []Mask{filter}
+	if filter&Security != 0 && filter != Security {
+		filters = []Mask{filter &^ Security, Security}
+	}
+
+	var files snapshot
+	if opts.Rescan {
+		if files, err = scan(dir, opts.Recursive); err != nil {
+			return nil, err
+		}
+	}
+
+	var readers []*reader
+	for _, f := range filters {
+		r, err := newReader(dir, opts.Recursive, f, size)
+		if err != nil {
+			for _, r := range readers {
+				r.close()
+			}
+			return nil, err
+		}
+		r.extended = opts.Extended && procReadDirectoryChangesExW.Find() == nil
+		r.pairRenames = opts.PairRenames
+		r.names = names
+		if len(readers) == 0 {
+			// Rescans are done once, by the reader of the main
+			// filter.
+			r.files = files
+		}
+		readers = append(readers, r)
+	}
+
+	// The first reads are issued before returning, so that the changes
+	// made once WatchWith returns are all reported.
+	for _, r := range readers {
+		if err := r.begin(); err != nil {
+			for _, r := range readers {
+				r.close()
+			}
+			return nil, &os.PathError{Op: "watch", Path: dir, Err: err}
+		}
+	}
+
+	// A failing reader stops the others, so that the watch ends as a
+	// whole.
+	rctx, cancel := context.WithCancel(ctx)
+	events := make(chan Event)
+	var wg sync.WaitGroup
+	for _, r := range readers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer cancel()
+			defer r.close()
+			r.run(rctx, events)
+		}()
+	}
+	go func() {
+		wg.Wait()
+		cancel()
+		close(events)
+	}()
+
+	if opts.Coalesce <= 0 {
+		return events, nil
+	}
+
+	out := make(chan Event)
+	go coalesce(ctx, events, out, opts.Coalesce)
+
+	return out, nil
+}
+
+// isRemote reports whether path is on a network share.
+func isRemote(path string) bool {
+	if strings.HasPrefix(path, `\\?\`) || strings.HasPrefix(path, `\\.\`) {
+		return strings.HasPrefix(strings.ToUpper(path[4:]), `UNC\`)
+	}
+	if strings.HasPrefix(path, `\\`) {
+		return true
+	}
+
+	root, err := windows.UTF16PtrFromString(filepath.VolumeName(path) + `\`)
+	if err != nil {
+		return false
+	}
+
+	return windows.GetDriveType(root) == windows.DRIVE_REMOTE
+}
+
+// reader reads the changes of a directory with overlapped I/O.
+type reader struct {
+	dir       string
+	h         windows.Handle
+	recursive bool
+	filter    Mask
+	extended  bool
+	// pairRenames merges RenamedFrom and RenamedTo events.
+	pairRenames bool
+	names       *compiledFilter
+	// files is the last scan of the directory if it is rescanned on
+	// overflow.
+	files snapshot
+	// buf is DWORD aligned, as ReadDirectoryChangesW requires.
+	buf []byte
+	ov  windows.Overlapped
+}
+
+func newReader(dir string, recursive bool, filter Mask, size int) (*reader, error) {
+	h, err := fsctl.Open(dir, windows.FILE_LIST_DIRECTORY, windows.FILE_FLAG_OVERLAPPED)
+	if err != nil {
+		return nil, err
+	}
+
+	ev, err := windows.CreateEvent(nil, 1, 0, nil)
+	if err != nil {
+		windows.CloseHandle(h)
+		return nil, err
+	}
+
+	words := make([]uint32, (size+3)/4)
+	r := &reader{
+		dir:       dir,
+		h:         h,
+		recursive: recursive,
+		filter:    filter,
+		buf:       unsafe.Slice((*byte)(unsafe.Pointer(&words[0])), 4*len(words)),
+	}
+	r.ov.HEvent = ev
+
+	return r, nil
+}
+
+func (r *reader) close() {
+	windows.CloseHandle(r.ov.HEvent)
+	windows.CloseHandle(r.h)
+}
+
+// run sends the changes read until ctx is done or reading fails. The
+// first read must have been issued with begin.
+func (r *reader) run(ctx context.Context, out chan<- Event) {
+	// The pending read is cancelled when ctx is done.
+	stop := context.AfterFunc(ctx, func() {
+		windows.CancelIoEx(r.h, &r.ov)
+	})
+	defer stop()
+
+	for {
+		n, err := r.wait()
+		if err != nil {
+			r.fail(ctx, out, err)
+			return
+		}
+
+		// An empty read means that the changes overflowed the buffer.
+		if n == 0 {
+			if !r.send(ctx, out, Event{Kind: Overflow, Path: r.dir}) || !r.resync(ctx, out) {
+				return
+			}
+		} else if !r.deliver(ctx, out, r.parse(n)) {
+			return
+		}
+
+		if err := r.begin(); err != nil {
+			r.fail(ctx, out, err)
+			return
+		}
+	}
+}
+
+// fail reports the error ending the watch, unless ctx is done.
+func (r *reader) fail(ctx context.Context, out chan<- Event, err error) {
+	if ctx.Err() == nil {
+		r.send(ctx, out, Event{Kind: Overflow, Path: r.dir, Err: &os.PathError{Op: "watch", Path: r.dir, Err: err}})
+	}
+}
+
+// parse returns the events of the n bytes of notifications read.
+func (r *reader) parse(n int) []Event {
+	var batch []Event
+	add := func(action uint32, name string, d *Details) bool {
+		kind, known := actionKind(action)
+		if !known {
+			return true
+		}
+		if kind == Modified && r.filter == Security {
+			kind = SecurityChanged
+		}
+		batch = append(batch, Event{Kind: kind, Name: name, Path: filepath.Join(r.dir, name), Details: d})
+		return true
+	}
+	if r.extended {
+		parseExtendedNotifications(r.buf[:n], add)
+	} else {
+		parseNotifications(r.buf[:n], func(action uint32, name string) bool {
+			return add(action, name, nil)
+		})
+	}
+	if r.pairRenames {
+		batch = pairRenames(batch)
+	}
+
+	return batch
+}
+
+// resync rescans the directory after an overflow, if the watch was asked
+// to, and reports the changes found followed by a Resync event.
+func (r *reader) resync(ctx context.Context, out chan<- Event) bool {
+	if r.files == nil {
+		return true
+	}
+
+	files, err := scan(r.dir, r.recursive)
+	if err != nil {
+		// The directory itself is failing, which the next read
+		// reports.
+		return true
+	}
+	events := r.files.diff(r.dir, files)
+	r.files = files
+
+	return r.deliver(ctx, out, events) && r.send(ctx, out, Event{Kind: Resync, Path: r.dir})
+}
+
+// deliver sends the events of batch selected by the filter of the watch.
+func (r *reader) deliver(ctx context.Context, out chan<- Event, batch []Event) bool {
+	for _, e := range batch {
+		if r.names.match(&e) && !r.send(ctx, out, e) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// begin issues the read of the next batch of changes.
+func (r *reader) begin() error {
+	if err := windows.ResetEvent(r.ov.HEvent); err != nil {
+		return err
+	}
+
+	err := r.start()
+	if err != nil && r.extended && (errors.Is(err, windows.ERROR_INVALID_FUNCTION) ||
+		errors.Is(err, windows.ERROR_INVALID_PARAMETER) || errors.Is(err, windows.ERROR_NOT_SUPPORTED)) {
+		// The file system only supports basic notifications.
+		r.extended = false
+		err = r.start()
+	}
+	if err != nil && !errors.Is(err, windows.ERROR_IO_PENDING) {
+		return err
+	}
+
+	return nil
+}
+
+// wait waits for the read issued by begin, and returns its size.
+func (r *reader) wait() (int, error) {
+	var n uint32
+	if err := windows.GetOverlappedResult(r.h, &r.ov, &n, true); err != nil {
+		if errors.Is(err, windows.ERROR_NOTIFY_ENUM_DIR) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	return int(n), nil
+}
+
+// readDirectoryNotifyExtendedInformation is the
+// READ_DIRECTORY_NOTIFY_INFORMATION_CLASS of
+// FILE_NOTIFY_EXTENDED_INFORMATION.
+const readDirectoryNotifyExtendedInformation = 2
+
+// start starts reading the next batch of changes.
+func (r *reader) start() error {
+	if r.extended {
+		return readDirectoryChangesEx(r.h, &r.buf[0], uint32(len(r.buf)), r.recursive,
+			uint32(r.filter), nil, &r.ov, 0, readDirectoryNotifyExtendedInformation)
+	}
+
+	return windows.ReadDirectoryChanges(r.h, &r.buf[0], uint32(len(r.buf)), r.recursive,
+		uint32(r.filter), nil, &r.ov, 0)
+}
+
+func (r *reader) send(ctx context.Context, out chan<- Event, e Event) bool {
+	select {
+	case out <- e:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,4 @@
+// Package objectid manages the object IDs of files, the identifiers which
+// distributed link tracking uses to find the targets of shortcuts and OLE
+// links after they moved, along with the birth IDs recorded with them.
+package objectid
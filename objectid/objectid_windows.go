@@ -0,0 +1,158 @@
+package objectid
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/windows"
+
+	"github.com/go-sw/ntfs/internal/fsctl"
+	"github.com/go-sw/ntfs/security"
+)
+
+// fsctlSetObjectIDExtended is FSCTL_SET_OBJECT_ID_EXTENDED.
+const fsctlSetObjectIDExtended = 0x900BC
+
+// ErrNoObjectID is returned by Get for files which have no object ID.
+var ErrNoObjectID = errors.New("objectid: file has no object ID")
+
+// BirthIDs are the IDs recorded with the object ID of a file when it is
+// created, which distributed link tracking keeps when the file moves to
+// another volume, so that links to it can still be resolved.
+type BirthIDs struct {
+	// VolumeID is the object ID of the volume the file was created on.
+	VolumeID windows.GUID
+	// ObjectID is the object ID the file was created with.
+	ObjectID windows.GUID
+	// DomainID is reserved, and zero.
+	DomainID windows.GUID
+}
+
+// ObjectID is the object ID of a file with its birth IDs, as stored in
+// FILE_OBJECTID_BUFFER.
+type ObjectID struct {
+	ID windows.GUID
+	BirthIDs
+}
+
+// Get returns the object ID of the file at path. It fails with
+// ErrNoObjectID if the file has none.
+func Get(path string) (*ObjectID, error) {
+	var id *ObjectID
+	err := withFile(path, windows.FILE_READ_ATTRIBUTES, "FSCTL_GET_OBJECT_ID", func(h windows.Handle) error {
+		var err error
+		id, err = GetHandle(h)
+		return err
+	})
+
+	return id, err
+}
+
+// GetHandle is like Get for the file opened as h.
+func GetHandle(h windows.Handle) (*ObjectID, error) {
+	var id ObjectID
+	err := fsctl.CallStruct[byte](h, windows.FSCTL_GET_OBJECT_ID, nil, &id)
+	if errors.Is(err, windows.ERROR_FILE_NOT_FOUND) {
+		return nil, ErrNoObjectID
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &id, nil
+}
+
+// CreateOrGet returns the object ID of the file at path, giving it a new
+// one if it has none.
+func CreateOrGet(path string) (*ObjectID, error) {
+	var id *ObjectID
+	err := withFile(path, windows.FILE_READ_ATTRIBUTES|windows.FILE_WRITE_ATTRIBUTES, "FSCTL_CREATE_OR_GET_OBJECT_ID", func(h windows.Handle) error {
+		var err error
+		id, err = CreateOrGetHandle(h)
+		return err
+	})
+
+	return id, err
+}
+
+// CreateOrGetHandle is like CreateOrGet for the file opened as h.
+func CreateOrGetHandle(h windows.Handle) (*ObjectID, error) {
+	var id ObjectID
+	if err := fsctl.CallStruct[byte](h, windows.FSCTL_CREATE_OR_GET_OBJECT_ID, nil, &id); err != nil {
+		return nil, err
+	}
+
+	return &id, nil
+}
+
+// Set sets the object ID of the file at path to id, as backup and
+// migration tools do to preserve the links to the files they restore.
+// The file must not have an object ID already, and id must not be used by
+// another file of the volume. Setting object IDs requires
+// SeRestorePrivilege, which Set enables, returning a
+// *security.PrivilegeError if the caller does not hold it.
+func Set(path string, id *ObjectID) error {
+	return withRestore(func() error {
+		return withFile(path, windows.FILE_WRITE_ATTRIBUTES, "FSCTL_SET_OBJECT_ID", func(h windows.Handle) error {
+			return SetHandle(h, id)
+		})
+	})
+}
+
+// SetHandle is like Set for the file opened as h, with SeRestorePrivilege
+// already enabled.
+func SetHandle(h windows.Handle, id *ObjectID) error {
+	return fsctl.CallStruct[ObjectID, byte](h, windows.FSCTL_SET_OBJECT_ID, id, nil)
+}
+
+// SetBirthIDs replaces the birth IDs recorded with the object ID of the
+// file at path, enabling SeRestorePrivilege like Set.
+func SetBirthIDs(path string, ids *BirthIDs) error {
+	return withRestore(func() error {
+		return withFile(path, windows.FILE_WRITE_ATTRIBUTES, "FSCTL_SET_OBJECT_ID_EXTENDED", func(h windows.Handle) error {
+			return SetBirthIDsHandle(h, ids)
+		})
+	})
+}
+
+// SetBirthIDsHandle is like SetBirthIDs for the file opened as h, with
+// SeRestorePrivilege already enabled.
+func SetBirthIDsHandle(h windows.Handle, ids *BirthIDs) error {
+	return fsctl.CallStruct[BirthIDs, byte](h, fsctlSetObjectIDExtended, ids, nil)
+}
+
+// Delete removes the object ID of the file at path. Links to the file
+// can no longer be resolved once it moves. Files without an object ID are
+// left alone.
+func Delete(path string) error {
+	return withFile(path, windows.FILE_WRITE_ATTRIBUTES, "FSCTL_DELETE_OBJECT_ID", DeleteHandle)
+}
+
+// DeleteHandle is like Delete for the file opened as h.
+func DeleteHandle(h windows.Handle) error {
+	_, err := fsctl.Call(h, windows.FSCTL_DELETE_OBJECT_ID, nil, nil)
+	if errors.Is(err, windows.ERROR_FILE_NOT_FOUND) {
+		return nil
+	}
+
+	return err
+}
+
+// withRestore runs fn with SeRestorePrivilege enabled.
+func withRestore(fn func() error) error {
+	return security.WithPrivileges([]string{security.PrivilegeRestore}, fn)
+}
+
+func withFile(path string, access uint32, op string, fn func(windows.Handle) error) error {
+	h, err := fsctl.Open(path, access, windows.FILE_FLAG_OPEN_REPARSE_POINT)
+	if err != nil {
+		return err
+	}
+	defer windows.CloseHandle(h)
+
+	if err := fn(h); err != nil {
+		return &os.PathError{Op: op, Path: path, Err: err}
+	}
+
+	return nil
+}
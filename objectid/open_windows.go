@@ -0,0 +1,100 @@
+package objectid
+
+import (
+	"os"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+
+	"github.com/go-sw/ntfs/internal/fsctl"
+	"github.com/go-sw/ntfs/volume"
+)
+
+// objectIDType is the ObjectIdType FILE_ID_TYPE.
+const objectIDType = 1
+
+// Flags of GetFinalPathNameByHandle.
+const (
+	volumeNameDOS  = 0x0 // VOLUME_NAME_DOS
+	volumeNameGUID = 0x1 // VOLUME_NAME_GUID
+)
+
+// fileIDDescriptor is FILE_ID_DESCRIPTOR holding an object ID.
+type fileIDDescriptor struct {
+	Size     uint32
+	Type     uint32
+	ObjectID windows.GUID
+}
+
+// Open opens for reading the file of the volume containing vol whose
+// object ID is oid.ID, wherever it now is on the volume. The name of the
+// returned file is its current path.
+func Open(vol string, oid ObjectID) (*os.File, error) {
+	root, err := volume.Root(vol)
+	if err != nil {
+		return nil, err
+	}
+	hint, err := fsctl.Open(root, windows.FILE_READ_ATTRIBUTES, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer windows.CloseHandle(hint)
+
+	desc := fileIDDescriptor{Type: objectIDType, ObjectID: oid.ID}
+	desc.Size = uint32(unsafe.Sizeof(desc))
+	h, err := openFileByID(hint, &desc, windows.GENERIC_READ, fsctl.ShareAll, nil, windows.FILE_FLAG_BACKUP_SEMANTICS)
+	if err != nil {
+		return nil, &os.PathError{Op: "OpenFileById", Path: oid.ID.String(), Err: err}
+	}
+
+	path, err := finalPath(h)
+	if err != nil {
+		windows.CloseHandle(h)
+		return nil, &os.PathError{Op: "GetFinalPathNameByHandle", Path: oid.ID.String(), Err: err}
+	}
+
+	return os.NewFile(uintptr(h), path), nil
+}
+
+// Path returns the current path of the file of the volume containing vol
+// whose object ID is oid.ID.
+func Path(vol string, oid ObjectID) (string, error) {
+	f, err := Open(vol, oid)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	return f.Name(), nil
+}
+
+// finalPath returns the path of the file opened as h, through its drive
+// letter if it has one and its volume GUID path otherwise.
+func finalPath(h windows.Handle) (string, error) {
+	path, err := finalPathName(h, volumeNameDOS)
+	if err != nil {
+		return finalPathName(h, volumeNameGUID)
+	}
+
+	// Paths through drive letters are returned with the \\?\ prefix.
+	if p := strings.TrimPrefix(path, `\\?\`); len(p) >= 2 && p[1] == ':' {
+		return p, nil
+	}
+
+	return path, nil
+}
+
+func finalPathName(h windows.Handle, flags uint32) (string, error) {
+	buf := make([]uint16, windows.MAX_PATH)
+	for {
+		n, err := windows.GetFinalPathNameByHandle(h, &buf[0], uint32(len(buf)), flags)
+		if err != nil {
+			return "", err
+		}
+		if int(n) < len(buf) {
+			return windows.UTF16ToString(buf[:n]), nil
+		}
+		buf = make([]uint16, n)
+	}
+}
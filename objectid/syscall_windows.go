@@ -0,0 +1,5 @@
+package objectid
+
+//go:generate go run golang.org/x/sys/windows/mkwinsyscall -output zsyscall_windows.go syscall_windows.go
+
+//sys	openFileByID(volumeHint windows.Handle, id *fileIDDescriptor, access uint32, share uint32, sa *windows.SecurityAttributes, flags uint32) (h windows.Handle, err error) [failretval==windows.InvalidHandle] = kernel32.OpenFileById
@@ -0,0 +1,5 @@
+// Package oplock requests leases on files, the opportunistic locks which
+// let a program cache the content and handles of a file until another
+// open needs it to stop, and delivers the breaks of those leases so that
+// the program can yield promptly.
+package oplock
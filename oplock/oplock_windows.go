@@ -0,0 +1,389 @@
+package oplock
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+
+	"github.com/go-sw/ntfs/internal/fsctl"
+)
+
+// fsctlRequestOplock is FSCTL_REQUEST_OPLOCK.
+const fsctlRequestOplock = 0x00090240
+
+const (
+	// requestOplockCurrentVersion is REQUEST_OPLOCK_CURRENT_VERSION.
+	requestOplockCurrentVersion = 1
+
+	// REQUEST_OPLOCK_INPUT_FLAG_*
+	inputFlagRequest = 0x1
+	inputFlagAck     = 0x2
+
+	// REQUEST_OPLOCK_OUTPUT_FLAG_*
+	outputFlagAckRequired   = 0x1
+	outputFlagModesProvided = 0x2
+)
+
+var (
+	// ErrNotGranted is returned by Acquire when the lease conflicts with
+	// the other opens of the file.
+	ErrNotGranted = errors.New("oplock: lease not granted")
+	// ErrClosed is returned when using a lease which was closed.
+	ErrClosed = errors.New("oplock: lease closed")
+	// ErrNoBreak is returned by Lease.Acknowledge when no break awaits an
+	// acknowledgement.
+	ErrNoBreak = errors.New("oplock: no break to acknowledge")
+)
+
+// Level is the caching level of a lease, made of the OPLOCK_LEVEL_CACHE_*
+// flags.
+type Level uint32
+
+const (
+	None Level = 0
+	// Read caches the content read from the file.
+	Read Level = 0x1
+	// Handle caches the handle to the file, delaying the opens which would
+	// conflict with it.
+	Handle Level = 0x2
+	// Write caches the changes made to the file.
+	Write Level = 0x4
+
+	RH  = Read | Handle
+	RW  = Read | Write
+	RWH = Read | Write | Handle
+)
+
+func (l Level) String() string {
+	if l == None {
+		return "None"
+	}
+
+	var b strings.Builder
+	for _, f := range []struct {
+		level Level
+		name  string
+	}{{Read, "R"}, {Write, "W"}, {Handle, "H"}} {
+		if l&f.level != 0 {
+			b.WriteString(f.name)
+		}
+	}
+
+	return b.String()
+}
+
+// requestOplockInput is REQUEST_OPLOCK_INPUT_BUFFER.
+type requestOplockInput struct {
+	StructureVersion     uint16
+	StructureLength      uint16
+	RequestedOplockLevel uint32
+	Flags                uint32
+}
+
+// requestOplockOutput is REQUEST_OPLOCK_OUTPUT_BUFFER.
+type requestOplockOutput struct {
+	StructureVersion    uint16
+	StructureLength     uint16
+	OriginalOplockLevel uint32
+	NewOplockLevel      uint32
+	Flags               uint32
+	AccessMode          uint32
+	ShareMode           uint16
+}
+
+// Break is the break of a lease.
+type Break struct {
+	// From is the level of the lease before the break, and To is the
+	// level it breaks to.
+	From, To Level
+	// AckRequired is set if the open which broke the lease waits until
+	// the break is acknowledged with Lease.Acknowledge, or the lease
+	// closed.
+	AckRequired bool
+	// Access and Share are the access and sharing modes of the open which
+	// broke the lease, when the system reports them.
+	Access, Share uint32
+}
+
+// Lease is a lease on a file, along with the handle it was requested on.
+// The content of the file is read through the lease with ReadAt, as the
+// other opens of the file, including those of this process, break it.
+type Lease struct {
+	path   string
+	h      windows.Handle
+	breaks chan Break
+	// acks wakes the watcher once a break was acknowledged.
+	acks chan struct{}
+	stop chan struct{}
+	done chan struct{}
+
+	mu sync.Mutex
+	// ov, in and out are those of the pending request, and must not move
+	// until it completes.
+	ov      windows.Overlapped
+	in      requestOplockInput
+	out     requestOplockOutput
+	level   Level
+	pending bool
+	closed  bool
+
+	readMu sync.Mutex
+	readOv windows.Overlapped
+}
+
+// Acquire opens the file or directory at path and requests a lease of the
+// given level on it, such as RH for a reader caching the file, or RWH for
+// its only user. The breaks of the lease are delivered on the channel
+// returned by Breaks.
+func Acquire(path string, level Level) (*Lease, error) {
+	h, err := fsctl.Open(path, windows.GENERIC_READ, windows.FILE_FLAG_OVERLAPPED)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &Lease{
+		path:   path,
+		h:      h,
+		level:  level,
+		breaks: make(chan Break, 1),
+		acks:   make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	if l.ov.HEvent, err = windows.CreateEvent(nil, 1, 0, nil); err != nil {
+		windows.CloseHandle(h)
+		return nil, err
+	}
+	if l.readOv.HEvent, err = windows.CreateEvent(nil, 1, 0, nil); err != nil {
+		windows.CloseHandle(l.ov.HEvent)
+		windows.CloseHandle(h)
+		return nil, err
+	}
+
+	if err := l.request(level, inputFlagRequest); err != nil {
+		l.closeHandles()
+		if errors.Is(err, windows.ERROR_OPLOCK_NOT_GRANTED) || errors.Is(err, windows.ERROR_CANNOT_GRANT_REQUESTED_OPLOCK) {
+			err = ErrNotGranted
+		}
+		return nil, &os.PathError{Op: "FSCTL_REQUEST_OPLOCK", Path: path, Err: err}
+	}
+
+	go l.watch()
+
+	return l, nil
+}
+
+// request issues FSCTL_REQUEST_OPLOCK, which stays pending until the
+// lease breaks. It returns nil if the request is pending or already
+// completed.
+func (l *Lease) request(level Level, flags uint32) error {
+	l.in = requestOplockInput{
+		StructureVersion:     requestOplockCurrentVersion,
+		StructureLength:      uint16(unsafe.Sizeof(l.in)),
+		RequestedOplockLevel: uint32(level),
+		Flags:                flags,
+	}
+	l.out = requestOplockOutput{
+		StructureVersion: requestOplockCurrentVersion,
+		StructureLength:  uint16(unsafe.Sizeof(l.out)),
+	}
+	if err := windows.ResetEvent(l.ov.HEvent); err != nil {
+		return err
+	}
+
+	var n uint32
+	err := windows.DeviceIoControl(l.h, fsctlRequestOplock,
+		(*byte)(unsafe.Pointer(&l.in)), uint32(unsafe.Sizeof(l.in)),
+		(*byte)(unsafe.Pointer(&l.out)), uint32(unsafe.Sizeof(l.out)), &n, &l.ov)
+	if errors.Is(err, windows.ERROR_IO_PENDING) {
+		return nil
+	}
+
+	return err
+}
+
+// watch waits for the breaks of the lease and sends them, until the lease
+// is gone or closed.
+func (l *Lease) watch() {
+	defer close(l.done)
+	defer close(l.breaks)
+	defer l.setLevel(None)
+
+	for {
+		var n uint32
+		if err := windows.GetOverlappedResult(l.h, &l.ov, &n, true); err != nil {
+			// The request was cancelled by Close.
+			return
+		}
+
+		l.mu.Lock()
+		b := Break{
+			From:        Level(l.out.OriginalOplockLevel),
+			To:          Level(l.out.NewOplockLevel),
+			AckRequired: l.out.Flags&outputFlagAckRequired != 0,
+		}
+		if l.out.Flags&outputFlagModesProvided != 0 {
+			b.Access, b.Share = l.out.AccessMode, uint32(l.out.ShareMode)
+		}
+		l.level, l.pending = b.To, b.AckRequired
+		l.mu.Unlock()
+
+		select {
+		case l.breaks <- b:
+		case <-l.stop:
+			return
+		}
+		if !b.AckRequired {
+			// Breaks which need no acknowledgement end the lease.
+			return
+		}
+
+		select {
+		case <-l.acks:
+		case <-l.stop:
+			return
+		}
+	}
+}
+
+func (l *Lease) setLevel(level Level) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.level, l.pending = level, false
+}
+
+// Breaks returns the channel on which the breaks of the lease are sent.
+// It is closed once the lease is gone, after a break which needs no
+// acknowledgement, or once the lease is closed.
+func (l *Lease) Breaks() <-chan Break {
+	return l.breaks
+}
+
+// Level returns the current level of the lease.
+func (l *Lease) Level() Level {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.level
+}
+
+// Acknowledge acknowledges the last break of the lease, letting the open
+// which broke it proceed while the lease keeps the level it broke to. A
+// break to None is acknowledged by closing the lease.
+func (l *Lease) Acknowledge() error {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return ErrClosed
+	}
+	if !l.pending {
+		l.mu.Unlock()
+		return ErrNoBreak
+	}
+	level := l.level
+	if level == None {
+		l.mu.Unlock()
+		return l.Close()
+	}
+	l.pending = false
+	err := l.request(level, inputFlagAck)
+	l.mu.Unlock()
+
+	if err != nil {
+		l.Close()
+		return &os.PathError{Op: "FSCTL_REQUEST_OPLOCK", Path: l.path, Err: err}
+	}
+	l.acks <- struct{}{}
+
+	return nil
+}
+
+// Handle returns the handle the lease was requested on, which is opened
+// for reading with overlapped I/O.
+func (l *Lease) Handle() windows.Handle {
+	return l.h
+}
+
+// ReadAt reads len(p) bytes of the file at offset off through the lease.
+func (l *Lease) ReadAt(p []byte, off int64) (int, error) {
+	l.readMu.Lock()
+	defer l.readMu.Unlock()
+
+	if l.isClosed() {
+		return 0, &os.PathError{Op: "read", Path: l.path, Err: ErrClosed}
+	}
+
+	total := 0
+	for len(p) > 0 {
+		l.readOv.Offset, l.readOv.OffsetHigh = uint32(off), uint32(off>>32)
+
+		var n uint32
+		err := windows.ReadFile(l.h, p, &n, &l.readOv)
+		if err == nil || errors.Is(err, windows.ERROR_IO_PENDING) {
+			err = windows.GetOverlappedResult(l.h, &l.readOv, &n, true)
+		}
+		if errors.Is(err, windows.ERROR_HANDLE_EOF) || err == nil && n == 0 {
+			return total, io.EOF
+		}
+		if err != nil {
+			return total, &os.PathError{Op: "read", Path: l.path, Err: err}
+		}
+
+		total += int(n)
+		p = p[n:]
+		off += int64(n)
+	}
+
+	return total, nil
+}
+
+// Size returns the size of the file.
+func (l *Lease) Size() (int64, error) {
+	var fi windows.ByHandleFileInformation
+	if err := windows.GetFileInformationByHandle(l.h, &fi); err != nil {
+		return 0, &os.PathError{Op: "GetFileInformationByHandle", Path: l.path, Err: err}
+	}
+
+	return int64(fi.FileSizeHigh)<<32 | int64(fi.FileSizeLow), nil
+}
+
+func (l *Lease) isClosed() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.closed
+}
+
+// Close releases the lease and closes its handle, which acknowledges any
+// pending break.
+func (l *Lease) Close() error {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return ErrClosed
+	}
+	l.closed = true
+	l.mu.Unlock()
+
+	close(l.stop)
+	windows.CancelIoEx(l.h, &l.ov)
+	<-l.done
+
+	l.readMu.Lock()
+	defer l.readMu.Unlock()
+
+	return l.closeHandles()
+}
+
+func (l *Lease) closeHandles() error {
+	windows.CloseHandle(l.readOv.HEvent)
+	windows.CloseHandle(l.ov.HEvent)
+
+	return windows.CloseHandle(l.h)
+}
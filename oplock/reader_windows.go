@@ -0,0 +1,138 @@
+package oplock
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// retryInterval is the interval at which a Reader requests its lease
+// again while the file is in use.
+const retryInterval = 250 * time.Millisecond
+
+// Reader reads a file sequentially through an RH lease, giving way to the
+// other users of the file: the lease is closed as soon as it breaks, even
+// between reads, so that the open which broke it proceeds once the read
+// in progress if any completes. The next read then resumes from the same
+// offset once a new lease is granted, that is once the file is no longer
+// open for writing. Readers of live files thus neither fail with sharing
+// violations nor hold writers up.
+//
+// The content read before and after giving way may belong to different
+// versions of the file, which Yields tells.
+type Reader struct {
+	ctx   context.Context
+	path  string
+	lease *Lease
+	// gone is closed once the lease is released.
+	gone   chan struct{}
+	off    int64
+	yields int
+}
+
+// NewReader returns a Reader of the file at path, waiting until a lease is
+// granted or ctx is done. The Reader stops waiting for new leases once ctx
+// is done.
+func NewReader(ctx context.Context, path string) (*Reader, error) {
+	r := &Reader{ctx: ctx, path: path}
+	if err := r.acquire(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// acquire requests a lease until it is granted.
+func (r *Reader) acquire() error {
+	for {
+		l, err := Acquire(r.path, RH)
+		if err == nil {
+			r.lease, r.gone = l, release(l)
+			return nil
+		}
+		if !errors.Is(err, ErrNotGranted) {
+			return err
+		}
+
+		t := time.NewTimer(retryInterval)
+		select {
+		case <-t.C:
+		case <-r.ctx.Done():
+			t.Stop()
+			return r.ctx.Err()
+		}
+	}
+}
+
+// release closes l as soon as it breaks, and returns a channel closed once
+// l is released.
+func release(l *Lease) chan struct{} {
+	gone := make(chan struct{})
+	go func() {
+		defer close(gone)
+		for range l.Breaks() {
+			l.Close()
+		}
+	}()
+
+	return gone
+}
+
+// Read reads the file from the current offset, first waiting for a new
+// lease if the previous one broke.
+func (r *Reader) Read(p []byte) (int, error) {
+	if r.lease == nil {
+		return 0, ErrClosed
+	}
+
+	for {
+		select {
+		case <-r.gone:
+			r.lease = nil
+			r.yields++
+			if err := r.acquire(); err != nil {
+				return 0, err
+			}
+		default:
+		}
+
+		n, err := r.lease.ReadAt(p, r.off)
+		r.off += int64(n)
+		if errors.Is(err, ErrClosed) {
+			// The lease broke during the read.
+			if n > 0 {
+				return n, nil
+			}
+			<-r.gone
+			continue
+		}
+
+		return n, err
+	}
+}
+
+// Offset returns the offset of the next read.
+func (r *Reader) Offset() int64 {
+	return r.off
+}
+
+// Yields returns the number of times the Reader gave way to other users of
+// the file.
+func (r *Reader) Yields() int {
+	return r.yields
+}
+
+// Close closes the lease of the Reader.
+func (r *Reader) Close() error {
+	if r.lease == nil {
+		return ErrClosed
+	}
+	err := r.lease.Close()
+	r.lease = nil
+	if errors.Is(err, ErrClosed) {
+		// The lease was already released by a break.
+		return nil
+	}
+
+	return err
+}
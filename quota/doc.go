@@ -0,0 +1,5 @@
+// Package quota manages the disk quotas of NTFS volumes: the settings
+// enabling and enforcing them, and the per-user entries recording the
+// space charged to each owner of files along with their warning threshold
+// and limit.
+package quota
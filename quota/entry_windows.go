@@ -0,0 +1,207 @@
+package quota
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"strings"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+
+	"github.com/go-sw/ntfs/internal/fsctl"
+	"github.com/go-sw/ntfs/security"
+	"github.com/go-sw/ntfs/volume"
+)
+
+// ErrNotFound is returned by Get for the SIDs which have no quota entry.
+var ErrNotFound = errors.New("quota: no quota entry for the SID")
+
+// quotaBufferSize is the size of the buffer entries are read into, which
+// holds hundreds of them.
+const quotaBufferSize = 64 << 10
+
+// quotaHeaderSize is the size of FILE_QUOTA_INFORMATION up to the SID.
+const quotaHeaderSize = 40
+
+// Entry is the quota entry of a user of a volume. The space used by the
+// files of the user is charged to the entry of their owner.
+type Entry struct {
+	SID *windows.SID
+	// Name is the account of SID in the DOMAIN\name form, or the string
+	// form of SID if it cannot be resolved, as for deleted accounts.
+	Name       string
+	ChangeTime time.Time
+	Used       int64
+	// Threshold is the usage above which events are logged, and Limit the
+	// usage above which writes fail if quotas are enforced. Either is
+	// NoLimit if unset.
+	Threshold int64
+	Limit     int64
+}
+
+// Entries returns the quota entries of the volume containing vol, with
+// their SIDs resolved by r. A nil r uses a resolver of its own. This
+// requires administrative rights.
+func Entries(vol string, r *security.Resolver) ([]Entry, error) {
+	h, err := openVolume(vol, windows.GENERIC_READ)
+	if err != nil {
+		return nil, err
+	}
+	defer windows.CloseHandle(h)
+
+	if r == nil {
+		if r, err = security.NewResolver(nil); err != nil {
+			return nil, err
+		}
+	}
+
+	var entries []Entry
+	buf := alignedBuffer(quotaBufferSize)
+	for restart := true; ; restart = false {
+		var iosb windows.IO_STATUS_BLOCK
+		err := ntQueryQuotaInformationFile(h, &iosb, &buf[0], uint32(len(buf)), false, nil, 0, nil, restart)
+		if errors.Is(err, windows.STATUS_NO_MORE_ENTRIES) {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, &os.PathError{Op: "NtQueryQuotaInformationFile", Path: vol, Err: err}
+		}
+
+		batch, err := parseEntries(buf[:iosb.Information], r)
+		if err != nil {
+			return nil, &os.PathError{Op: "NtQueryQuotaInformationFile", Path: vol, Err: err}
+		}
+		entries = append(entries, batch...)
+	}
+}
+
+// Get returns the quota entry of sid on the volume containing vol, with
+// its SID resolved by r. A nil r uses a resolver of its own. It returns
+// ErrNotFound if the volume has no entry for sid.
+func Get(vol string, sid *windows.SID, r *security.Resolver) (*Entry, error) {
+	h, err := openVolume(vol, windows.GENERIC_READ)
+	if err != nil {
+		return nil, err
+	}
+	defer windows.CloseHandle(h)
+
+	if r == nil {
+		if r, err = security.NewResolver(nil); err != nil {
+			return nil, err
+		}
+	}
+
+	// FILE_GET_QUOTA_INFORMATION naming sid.
+	sidLen := windows.GetLengthSid(sid)
+	list := alignedBuffer(8 + int(sidLen))
+	binary.LittleEndian.PutUint32(list[4:], sidLen)
+	copy(list[8:], unsafe.Slice((*byte)(unsafe.Pointer(sid)), sidLen))
+
+	buf := alignedBuffer(quotaHeaderSize + int(sidLen))
+	var iosb windows.IO_STATUS_BLOCK
+	err = ntQueryQuotaInformationFile(h, &iosb, &buf[0], uint32(len(buf)), true, &list[0], uint32(len(list)), nil, true)
+	if errors.Is(err, windows.STATUS_NO_MORE_ENTRIES) {
+		return nil, &os.PathError{Op: "NtQueryQuotaInformationFile", Path: vol, Err: ErrNotFound}
+	}
+	if err != nil {
+		return nil, &os.PathError{Op: "NtQueryQuotaInformationFile", Path: vol, Err: err}
+	}
+
+	entries, err := parseEntries(buf[:iosb.Information], r)
+	if err != nil {
+		return nil, &os.PathError{Op: "NtQueryQuotaInformationFile", Path: vol, Err: err}
+	}
+	if len(entries) == 0 {
+		return nil, &os.PathError{Op: "NtQueryQuotaInformationFile", Path: vol, Err: ErrNotFound}
+	}
+
+	return &entries[0], nil
+}
+
+// Set sets the threshold and limit of the quota entry of sid on the volume
+// containing vol, creating the entry if needed. Either may be NoLimit.
+// This requires administrative rights.
+func Set(vol string, sid *windows.SID, threshold, limit int64) error {
+	h, err := openVolume(vol, windows.GENERIC_READ|windows.GENERIC_WRITE)
+	if err != nil {
+		return err
+	}
+	defer windows.CloseHandle(h)
+
+	sidLen := windows.GetLengthSid(sid)
+	buf := alignedBuffer(quotaHeaderSize + int(sidLen))
+	binary.LittleEndian.PutUint32(buf[4:], sidLen)
+	binary.LittleEndian.PutUint64(buf[24:], uint64(threshold))
+	binary.LittleEndian.PutUint64(buf[32:], uint64(limit))
+	copy(buf[quotaHeaderSize:], unsafe.Slice((*byte)(unsafe.Pointer(sid)), sidLen))
+
+	var iosb windows.IO_STATUS_BLOCK
+	if err := ntSetQuotaInformationFile(h, &iosb, &buf[0], uint32(len(buf))); err != nil {
+		return &os.PathError{Op: "NtSetQuotaInformationFile", Path: vol, Err: err}
+	}
+
+	return nil
+}
+
+// parseEntries parses a list of FILE_QUOTA_INFORMATION.
+func parseEntries(b []byte, r *security.Resolver) ([]Entry, error) {
+	var entries []Entry
+	for len(b) >= quotaHeaderSize {
+		next := binary.LittleEndian.Uint32(b)
+		sidLen := int(binary.LittleEndian.Uint32(b[4:]))
+		if sidLen < 8 || quotaHeaderSize+sidLen > len(b) {
+			return nil, windows.ERROR_INVALID_DATA
+		}
+
+		sid, err := (*windows.SID)(unsafe.Pointer(&b[quotaHeaderSize])).Copy()
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, Entry{
+			SID:        sid,
+			Name:       r.Name(sid),
+			ChangeTime: filetime(int64(binary.LittleEndian.Uint64(b[8:]))),
+			Used:       int64(binary.LittleEndian.Uint64(b[16:])),
+			Threshold:  int64(binary.LittleEndian.Uint64(b[24:])),
+			Limit:      int64(binary.LittleEndian.Uint64(b[32:])),
+		})
+
+		if next == 0 || int(next) > len(b) {
+			break
+		}
+		b = b[next:]
+	}
+
+	return entries, nil
+}
+
+// openVolume opens the volume containing path, through which its quotas
+// are queried and set.
+func openVolume(path string, access uint32) (windows.Handle, error) {
+	g, err := volume.GUIDPath(path)
+	if err != nil {
+		return windows.InvalidHandle, err
+	}
+
+	return fsctl.Open(strings.TrimSuffix(g, `\`), access, 0)
+}
+
+// alignedBuffer returns a zeroed buffer of size bytes, 8-byte aligned as
+// the quota structures require.
+func alignedBuffer(size int) []byte {
+	words := make([]uint64, (size+7)/8)
+
+	return unsafe.Slice((*byte)(unsafe.Pointer(&words[0])), size)
+}
+
+// filetime converts a FILETIME, counting 100ns intervals since 1601, to a
+// time. Zero converts to the zero time.
+func filetime(ft int64) time.Time {
+	if ft == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(0, (ft-116444736000000000)*100)
+}
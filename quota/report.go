@@ -0,0 +1,147 @@
+package quota
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// NoLimit is the threshold and limit of the entries which have none.
+const NoLimit int64 = -1
+
+// Usage is the usage of a user in a UsageReport.
+type Usage struct {
+	SID       string `json:"sid"`
+	Name      string `json:"name"`
+	Used      int64  `json:"used"`
+	Threshold int64  `json:"threshold"`
+	Limit     int64  `json:"limit"`
+}
+
+// OverThreshold reports whether the user uses more than their threshold.
+func (u Usage) OverThreshold() bool {
+	return u.Threshold != NoLimit && u.Used > u.Threshold
+}
+
+// OverLimit reports whether the user uses more than their limit.
+func (u Usage) OverLimit() bool {
+	return u.Limit != NoLimit && u.Used > u.Limit
+}
+
+// UsageReport is the usage of the users of a volume, returned by Report.
+type UsageReport struct {
+	Volume string    `json:"volume"`
+	Time   time.Time `json:"time"`
+	// Used is the total usage of the users.
+	Used int64 `json:"used"`
+	// Users are sorted by decreasing usage.
+	Users []Usage `json:"users"`
+}
+
+// WriteJSON writes r to w as a JSON object.
+func (r *UsageReport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+
+	return enc.Encode(r)
+}
+
+// csvHeader is the header of the CSV form of reports.
+var csvHeader = []string{"sid", "name", "used", "threshold", "limit", "over_threshold", "over_limit"}
+
+// WriteCSV writes the users of r to w as CSV, with a header row. Unset
+// thresholds and limits are written as empty fields.
+func (r *UsageReport) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, u := range r.Users {
+		err := cw.Write([]string{
+			u.SID, u.Name, strconv.FormatInt(u.Used, 10), limitField(u.Threshold), limitField(u.Limit),
+			strconv.FormatBool(u.OverThreshold()), strconv.FormatBool(u.OverLimit()),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+
+	return cw.Error()
+}
+
+func limitField(v int64) string {
+	if v == NoLimit {
+		return ""
+	}
+
+	return strconv.FormatInt(v, 10)
+}
+
+// Kind is the kind of an Event.
+type Kind int
+
+const (
+	// Error reports that the usage could not be read, in Event.Err.
+	Error Kind = iota
+	OverThreshold
+	UnderThreshold
+	OverLimit
+	UnderLimit
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Error:
+		return "error"
+	case OverThreshold:
+		return "over threshold"
+	case UnderThreshold:
+		return "under threshold"
+	case OverLimit:
+		return "over limit"
+	case UnderLimit:
+		return "under limit"
+	default:
+		return fmt.Sprintf("Kind(%d)", int(k))
+	}
+}
+
+// Event reports that a user crossed their threshold or limit, with their
+// usage after crossing it.
+type Event struct {
+	Kind  Kind
+	Usage Usage
+	Err   error
+}
+
+// crossings returns the events of the users of cur who crossed their
+// threshold or limit since prev, indexed by SID. The users new to cur are
+// compared to an empty usage.
+func crossings(prev map[string]Usage, cur []Usage) []Event {
+	var events []Event
+	for _, u := range cur {
+		p, ok := prev[u.SID]
+		if !ok {
+			p = Usage{Threshold: NoLimit, Limit: NoLimit}
+		}
+
+		switch was, is := p.OverThreshold(), u.OverThreshold(); {
+		case !was && is:
+			events = append(events, Event{Kind: OverThreshold, Usage: u})
+		case was && !is:
+			events = append(events, Event{Kind: UnderThreshold, Usage: u})
+		}
+		switch was, is := p.OverLimit(), u.OverLimit(); {
+		case !was && is:
+			events = append(events, Event{Kind: OverLimit, Usage: u})
+		case was && !is:
+			events = append(events, Event{Kind: UnderLimit, Usage: u})
+		}
+	}
+
+	return events
+}
@@ -0,0 +1,106 @@
+package quota
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"github.com/go-sw/ntfs/security"
+)
+
+// Report returns the usage of the users of the volume containing vol,
+// sorted by decreasing usage. This requires administrative rights.
+func Report(vol string) (*UsageReport, error) {
+	return report(vol, nil)
+}
+
+func report(vol string, r *security.Resolver) (*UsageReport, error) {
+	entries, err := Entries(vol, r)
+	if err != nil {
+		return nil, err
+	}
+
+	rep := &UsageReport{Volume: vol, Time: time.Now()}
+	for _, e := range entries {
+		rep.Used += e.Used
+		rep.Users = append(rep.Users, Usage{
+			SID:       e.SID.String(),
+			Name:      e.Name,
+			Used:      e.Used,
+			Threshold: e.Threshold,
+			Limit:     e.Limit,
+		})
+	}
+	slices.SortStableFunc(rep.Users, func(a, b Usage) int {
+		return cmp.Compare(b.Used, a.Used)
+	})
+
+	return rep, nil
+}
+
+// DefaultInterval is the interval between the reads of the usage of a
+// watch, unless set otherwise.
+const DefaultInterval = time.Minute
+
+// Watch reads the usage of the users of the volume containing vol every
+// interval, DefaultInterval if zero, and sends an event on the returned
+// channel whenever a user crosses their threshold or limit. The users
+// already over them when Watch is called are not reported. Failed reads
+// are reported as Error events, and the watch goes on. The channel is
+// closed once ctx is done.
+func Watch(ctx context.Context, vol string, interval time.Duration) (<-chan Event, error) {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	r, err := security.NewResolver(nil)
+	if err != nil {
+		return nil, err
+	}
+	rep, err := report(vol, r)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan Event)
+	go func() {
+		defer close(events)
+
+		prev := usageBySID(rep)
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+			}
+
+			rep, err := report(vol, r)
+			batch := []Event{{Kind: Error, Err: err}}
+			if err == nil {
+				batch = crossings(prev, rep.Users)
+				prev = usageBySID(rep)
+			}
+			for _, e := range batch {
+				select {
+				case events <- e:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+func usageBySID(rep *UsageReport) map[string]Usage {
+	m := make(map[string]Usage, len(rep.Users))
+	for _, u := range rep.Users {
+		m[u.SID] = u
+	}
+
+	return m
+}
@@ -0,0 +1,172 @@
+package quota
+
+import (
+	"fmt"
+	"os"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// fileFsControlInformation is the FS_INFORMATION_CLASS of
+// FILE_FS_CONTROL_INFORMATION.
+const fileFsControlInformation = 6
+
+// FILE_VC_* flags of FILE_FS_CONTROL_INFORMATION.
+const (
+	vcQuotaTrack        = 0x001
+	vcQuotaEnforce      = 0x002
+	vcQuotaMask         = 0x003
+	vcLogQuotaThreshold = 0x010
+	vcLogQuotaLimit     = 0x020
+	vcQuotasIncomplete  = 0x100
+	vcQuotasRebuilding  = 0x200
+)
+
+// fsControlInformation is FILE_FS_CONTROL_INFORMATION.
+type fsControlInformation struct {
+	FreeSpaceStartFiltering int64
+	FreeSpaceThreshold      int64
+	FreeSpaceStopFiltering  int64
+	DefaultQuotaThreshold   int64
+	DefaultQuotaLimit       int64
+	FileSystemControlFlags  uint32
+	// Brings the structure to the 48 bytes of FILE_FS_CONTROL_INFORMATION
+	// on 386 too, where it would otherwise end after the flags.
+	_ uint32
+}
+
+// Mode is the state of the quotas of a volume.
+type Mode int
+
+const (
+	// Disabled does not track the space used by users.
+	Disabled Mode = iota
+	// Track tracks the space used by users without limiting it.
+	Track
+	// Enforce tracks the space used by users and fails the writes of the
+	// users over their limit.
+	Enforce
+)
+
+func (m Mode) String() string {
+	switch m {
+	case Disabled:
+		return "disabled"
+	case Track:
+		return "track"
+	case Enforce:
+		return "enforce"
+	default:
+		return fmt.Sprintf("Mode(%d)", int(m))
+	}
+}
+
+// Settings are the quota settings of a volume.
+type Settings struct {
+	Mode Mode
+	// DefaultThreshold and DefaultLimit are given to the entries created
+	// for the users who have none when they first own files on the
+	// volume. Either may be NoLimit.
+	DefaultThreshold int64
+	DefaultLimit     int64
+	// LogThreshold and LogLimit log an event when a user goes over their
+	// threshold or limit.
+	LogThreshold bool
+	LogLimit     bool
+	// Rebuilding is set while the usage of the users is recomputed after
+	// quotas were enabled, and Incomplete while the usage is not
+	// accurate. Both are ignored by SetSettings.
+	Rebuilding bool
+	Incomplete bool
+}
+
+// GetSettings returns the quota settings of the volume containing vol.
+// This requires administrative rights.
+func GetSettings(vol string) (*Settings, error) {
+	h, err := openVolume(vol, windows.GENERIC_READ)
+	if err != nil {
+		return nil, err
+	}
+	defer windows.CloseHandle(h)
+
+	info, err := queryControl(h, vol)
+	if err != nil {
+		return nil, err
+	}
+
+	flags := info.FileSystemControlFlags
+	s := &Settings{
+		DefaultThreshold: info.DefaultQuotaThreshold,
+		DefaultLimit:     info.DefaultQuotaLimit,
+		LogThreshold:     flags&vcLogQuotaThreshold != 0,
+		LogLimit:         flags&vcLogQuotaLimit != 0,
+		Rebuilding:       flags&vcQuotasRebuilding != 0,
+		Incomplete:       flags&vcQuotasIncomplete != 0,
+	}
+	switch {
+	case flags&vcQuotaEnforce != 0:
+		s.Mode = Enforce
+	case flags&vcQuotaTrack != 0:
+		s.Mode = Track
+	}
+
+	return s, nil
+}
+
+// SetSettings sets the quota settings of the volume containing vol,
+// leaving the other settings of the volume alone. Enabling quotas starts
+// rebuilding the usage of the users in the background. This requires
+// administrative rights.
+func SetSettings(vol string, s *Settings) error {
+	h, err := openVolume(vol, windows.GENERIC_READ|windows.GENERIC_WRITE)
+	if err != nil {
+		return err
+	}
+	defer windows.CloseHandle(h)
+
+	info, err := queryControl(h, vol)
+	if err != nil {
+		return err
+	}
+
+	flags := info.FileSystemControlFlags &^ (vcQuotaMask | vcLogQuotaThreshold | vcLogQuotaLimit)
+	switch s.Mode {
+	case Track:
+		flags |= vcQuotaTrack
+	case Enforce:
+		flags |= vcQuotaTrack | vcQuotaEnforce
+	}
+	if s.LogThreshold {
+		flags |= vcLogQuotaThreshold
+	}
+	if s.LogLimit {
+		flags |= vcLogQuotaLimit
+	}
+	info.FileSystemControlFlags = flags
+	info.DefaultQuotaThreshold = s.DefaultThreshold
+	info.DefaultQuotaLimit = s.DefaultLimit
+
+	var iosb windows.IO_STATUS_BLOCK
+	err = ntSetVolumeInformationFile(h, &iosb, (*byte)(unsafe.Pointer(info)), uint32(unsafe.Sizeof(*info)),
+		fileFsControlInformation)
+	if err != nil {
+		return &os.PathError{Op: "NtSetVolumeInformationFile", Path: vol, Err: err}
+	}
+
+	return nil
+}
+
+func queryControl(h windows.Handle, vol string) (*fsControlInformation, error) {
+	var (
+		info fsControlInformation
+		iosb windows.IO_STATUS_BLOCK
+	)
+	err := ntQueryVolumeInformationFile(h, &iosb, (*byte)(unsafe.Pointer(&info)), uint32(unsafe.Sizeof(info)),
+		fileFsControlInformation)
+	if err != nil {
+		return nil, &os.PathError{Op: "NtQueryVolumeInformationFile", Path: vol, Err: err}
+	}
+
+	return &info, nil
+}
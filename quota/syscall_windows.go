@@ -0,0 +1,8 @@
+package quota
+
+//go:generate go run golang.org/x/sys/windows/mkwinsyscall -output zsyscall_windows.go syscall_windows.go
+
+//sys	ntQueryQuotaInformationFile(h windows.Handle, iosb *windows.IO_STATUS_BLOCK, buf *byte, size uint32, single bool, sidList *byte, sidListSize uint32, startSID *windows.SID, restart bool) (ntstatus error) = ntdll.NtQueryQuotaInformationFile
+//sys	ntSetQuotaInformationFile(h windows.Handle, iosb *windows.IO_STATUS_BLOCK, buf *byte, size uint32) (ntstatus error) = ntdll.NtSetQuotaInformationFile
+//sys	ntQueryVolumeInformationFile(h windows.Handle, iosb *windows.IO_STATUS_BLOCK, buf *byte, size uint32, class uint32) (ntstatus error) = ntdll.NtQueryVolumeInformationFile
+//sys	ntSetVolumeInformationFile(h windows.Handle, iosb *windows.IO_STATUS_BLOCK, buf *byte, size uint32, class uint32) (ntstatus error) = ntdll.NtSetVolumeInformationFile
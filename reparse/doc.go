@@ -0,0 +1,5 @@
+// Package reparse builds, parses and manages reparse points carrying
+// third-party reparse tags, which are stored as REPARSE_GUID_DATA_BUFFER.
+//
+// https://learn.microsoft.com/en-us/windows-hardware/drivers/ddi/ntifs/ns-ntifs-_reparse_guid_data_buffer
+package reparse
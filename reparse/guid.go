@@ -0,0 +1,145 @@
+package reparse
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+const (
+	// MaxBufferSize is the largest reparse buffer accepted by the file
+	// system, header included (MAXIMUM_REPARSE_DATA_BUFFER_SIZE).
+	MaxBufferSize = 16 << 10
+
+	// GUIDHeaderSize is the size of the fixed part of a
+	// REPARSE_GUID_DATA_BUFFER (REPARSE_GUID_DATA_BUFFER_HEADER_SIZE).
+	GUIDHeaderSize = 24
+
+	// MaxGUIDDataSize is the largest amount of opaque data a
+	// REPARSE_GUID_DATA_BUFFER can carry.
+	MaxGUIDDataSize = MaxBufferSize - GUIDHeaderSize
+)
+
+var (
+	ErrInvalidTag    = errors.New("reparse: invalid reparse tag")
+	ErrMicrosoftTag  = errors.New("reparse: Microsoft reparse tags are not stored in a REPARSE_GUID_DATA_BUFFER")
+	ErrDataTooLarge  = fmt.Errorf("reparse: reparse data exceeds %d bytes", MaxGUIDDataSize)
+	ErrShortBuffer   = errors.New("reparse: buffer too short for REPARSE_GUID_DATA_BUFFER")
+	ErrLengthInvalid = errors.New("reparse: ReparseDataLength exceeds the buffer size")
+)
+
+// GUID is a GUID laid out like windows.GUID, to and from which it can be
+// converted.
+type GUID struct {
+	Data1 uint32
+	Data2 uint16
+	Data3 uint16
+	Data4 [8]byte
+}
+
+// GUIDBuffer holds the contents of a REPARSE_GUID_DATA_BUFFER, the
+// layout used by every reparse point with a non-Microsoft tag.
+type GUIDBuffer struct {
+	Tag  Tag
+	GUID GUID
+	Data []byte
+}
+
+// NewGUIDBuffer returns a GUIDBuffer for a third-party tag, after
+// checking that the tag may be used and that data fits in a reparse
+// point.
+func NewGUIDBuffer(tag Tag, guid GUID, data []byte) (*GUIDBuffer, error) {
+	b := &GUIDBuffer{Tag: tag, GUID: guid, Data: data}
+	if err := b.Validate(); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// Validate checks that b can be stored as a reparse point.
+func (b *GUIDBuffer) Validate() error {
+	if !b.Tag.Valid() {
+		return ErrInvalidTag
+	}
+	if b.Tag.IsMicrosoft() {
+		return ErrMicrosoftTag
+	}
+	if len(b.Data) > MaxGUIDDataSize {
+		return ErrDataTooLarge
+	}
+
+	return nil
+}
+
+// Size returns the encoded size of b.
+func (b *GUIDBuffer) Size() int {
+	return GUIDHeaderSize + len(b.Data)
+}
+
+// MarshalBinary encodes b as a REPARSE_GUID_DATA_BUFFER.
+func (b *GUIDBuffer) MarshalBinary() ([]byte, error) {
+	if err := b.Validate(); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, b.Size())
+	putHeader(buf, b.Tag, uint16(len(b.Data)), b.GUID)
+	copy(buf[GUIDHeaderSize:], b.Data)
+
+	return buf, nil
+}
+
+// UnmarshalBinary decodes a REPARSE_GUID_DATA_BUFFER into b. The data is
+// copied, so buf may be reused afterwards.
+func (b *GUIDBuffer) UnmarshalBinary(buf []byte) error {
+	if len(buf) < 8 {
+		return ErrShortBuffer
+	}
+
+	tag := Tag(binary.LittleEndian.Uint32(buf[0:]))
+	if tag.IsMicrosoft() {
+		return ErrMicrosoftTag
+	}
+	if len(buf) < GUIDHeaderSize {
+		return ErrShortBuffer
+	}
+
+	n := int(binary.LittleEndian.Uint16(buf[4:]))
+	if GUIDHeaderSize+n > len(buf) {
+		return ErrLengthInvalid
+	}
+
+	b.Tag = tag
+	b.GUID = GUID{
+		Data1: binary.LittleEndian.Uint32(buf[8:]),
+		Data2: binary.LittleEndian.Uint16(buf[12:]),
+		Data3: binary.LittleEndian.Uint16(buf[14:]),
+	}
+	copy(b.GUID.Data4[:], buf[16:24])
+	b.Data = append([]byte(nil), buf[GUIDHeaderSize:GUIDHeaderSize+n]...)
+
+	return nil
+}
+
+// ParseGUIDBuffer decodes a REPARSE_GUID_DATA_BUFFER, as returned by
+// FSCTL_GET_REPARSE_POINT for a non-Microsoft tag.
+func ParseGUIDBuffer(buf []byte) (*GUIDBuffer, error) {
+	b := new(GUIDBuffer)
+	if err := b.UnmarshalBinary(buf); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// putHeader writes the fixed part of a REPARSE_GUID_DATA_BUFFER to buf.
+func putHeader(buf []byte, tag Tag, dataLen uint16, guid GUID) {
+	binary.LittleEndian.PutUint32(buf[0:], uint32(tag))
+	binary.LittleEndian.PutUint16(buf[4:], dataLen)
+	binary.LittleEndian.PutUint16(buf[6:], 0)
+	binary.LittleEndian.PutUint32(buf[8:], guid.Data1)
+	binary.LittleEndian.PutUint16(buf[12:], guid.Data2)
+	binary.LittleEndian.PutUint16(buf[14:], guid.Data3)
+	copy(buf[16:24], guid.Data4[:])
+}
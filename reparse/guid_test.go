@@ -0,0 +1,130 @@
+package reparse
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+var testGUID = GUID{
+	Data1: 0x01234567,
+	Data2: 0x89AB,
+	Data3: 0xCDEF,
+	Data4: [8]byte{0xFE, 0xDC, 0xBA, 0x98, 0x76, 0x54, 0x32, 0x10},
+}
+
+func TestGUIDBufferRoundTrip(t *testing.T) {
+	for _, data := range [][]byte{nil, {0x42}, bytes.Repeat([]byte{0xA5}, MaxGUIDDataSize)} {
+		b, err := NewGUIDBuffer(0x00001234, testGUID, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		buf, err := b.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(buf) != b.Size() {
+			t.Errorf("%d bytes, want %d", len(buf), b.Size())
+		}
+
+		got, err := ParseGUIDBuffer(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Tag != b.Tag || got.GUID != b.GUID || !bytes.Equal(got.Data, b.Data) {
+			t.Errorf("round trip of %d bytes = %+v", len(data), got.GUID)
+		}
+	}
+}
+
+func TestGUIDBufferLayout(t *testing.T) {
+	b, err := NewGUIDBuffer(0x00001234, testGUID, []byte{0xAA, 0xBB})
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf, err := b.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []byte{
+		0x34, 0x12, 0x00, 0x00, // ReparseTag
+		0x02, 0x00, // ReparseDataLength
+		0x00, 0x00, // Reserved
+		0x67, 0x45, 0x23, 0x01, 0xAB, 0x89, 0xEF, 0xCD,
+		0xFE, 0xDC, 0xBA, 0x98, 0x76, 0x54, 0x32, 0x10, // ReparseGuid
+		0xAA, 0xBB,
+	}
+	if !bytes.Equal(buf, want) {
+		t.Errorf("buffer = % x, want % x", buf, want)
+	}
+}
+
+func TestGUIDBufferValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		tag  Tag
+		size int
+		err  error
+	}{
+		{name: "valid", tag: 0x00001234},
+		{name: "reserved value", tag: 2, err: ErrInvalidTag},
+		{name: "reserved bits", tag: 0x00011234, err: ErrInvalidTag},
+		{name: "reserved bit 30", tag: 0x40001234, err: ErrInvalidTag},
+		{name: "microsoft", tag: 0x80000017, err: ErrMicrosoftTag},
+		{name: "too large", tag: 0x00001234, size: MaxGUIDDataSize + 1, err: ErrDataTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewGUIDBuffer(tt.tag, testGUID, make([]byte, tt.size))
+			if !errors.Is(err, tt.err) {
+				t.Errorf("err = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestParseGUIDBufferErrors(t *testing.T) {
+	header := func(tag uint32, n uint16) []byte {
+		buf := make([]byte, GUIDHeaderSize)
+		putHeader(buf, Tag(tag), n, testGUID)
+		return buf
+	}
+
+	tests := []struct {
+		name string
+		buf  []byte
+		err  error
+	}{
+		{"empty", nil, ErrShortBuffer},
+		{"short tag", []byte{0x34, 0x12, 0, 0, 0, 0, 0}, ErrShortBuffer},
+		{"microsoft", header(0xA000000C, 0)[:8], ErrMicrosoftTag},
+		{"short header", header(0x00001234, 0)[:GUIDHeaderSize-1], ErrShortBuffer},
+		{"length past end", append(header(0x00001234, 3), 1, 2), ErrLengthInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseGUIDBuffer(tt.buf); !errors.Is(err, tt.err) {
+				t.Errorf("err = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestParseGUIDBufferCopies(t *testing.T) {
+	buf := make([]byte, GUIDHeaderSize, GUIDHeaderSize+4)
+	putHeader(buf, 0x00001234, 2, testGUID)
+	buf = append(buf, 1, 2, 0xFF, 0xFF)
+
+	b, err := ParseGUIDBuffer(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf[GUIDHeaderSize] = 9
+	if !bytes.Equal(b.Data, []byte{1, 2}) {
+		t.Errorf("data = % x, want 01 02", b.Data)
+	}
+}
@@ -0,0 +1,72 @@
+package reparse
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+
+	"github.com/go-sw/ntfs/internal/fsctl"
+)
+
+// Set stores b as the reparse point of the file or directory at path,
+// replacing an existing reparse point with the same tag and GUID.
+func Set(path string, b *GUIDBuffer) error {
+	buf, err := b.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	h, err := fsctl.Open(path, windows.GENERIC_WRITE, windows.FILE_FLAG_OPEN_REPARSE_POINT)
+	if err != nil {
+		return err
+	}
+	defer windows.CloseHandle(h)
+
+	if _, err := fsctl.Call(h, windows.FSCTL_SET_REPARSE_POINT, buf, nil); err != nil {
+		return &os.PathError{Op: "FSCTL_SET_REPARSE_POINT", Path: path, Err: err}
+	}
+
+	return nil
+}
+
+// Get reads the reparse point of the file or directory at path. It
+// returns ErrMicrosoftTag if the reparse point has a Microsoft tag.
+func Get(path string) (*GUIDBuffer, error) {
+	h, err := fsctl.Open(path, windows.FILE_READ_ATTRIBUTES, windows.FILE_FLAG_OPEN_REPARSE_POINT)
+	if err != nil {
+		return nil, err
+	}
+	defer windows.CloseHandle(h)
+
+	buf := make([]byte, MaxBufferSize)
+	n, err := fsctl.Call(h, windows.FSCTL_GET_REPARSE_POINT, nil, buf)
+	if err != nil {
+		return nil, &os.PathError{Op: "FSCTL_GET_REPARSE_POINT", Path: path, Err: err}
+	}
+
+	return ParseGUIDBuffer(buf[:n])
+}
+
+// Delete removes the reparse point with the given tag and GUID from the
+// file or directory at path.
+func Delete(path string, tag Tag, guid GUID) error {
+	if tag.IsMicrosoft() {
+		return ErrMicrosoftTag
+	}
+
+	h, err := fsctl.Open(path, windows.GENERIC_WRITE, windows.FILE_FLAG_OPEN_REPARSE_POINT)
+	if err != nil {
+		return err
+	}
+	defer windows.CloseHandle(h)
+
+	// FSCTL_DELETE_REPARSE_POINT takes a header-only buffer.
+	buf := make([]byte, GUIDHeaderSize)
+	putHeader(buf, tag, 0, guid)
+
+	if _, err := fsctl.Call(h, windows.FSCTL_DELETE_REPARSE_POINT, buf, nil); err != nil {
+		return &os.PathError{Op: "FSCTL_DELETE_REPARSE_POINT", Path: path, Err: err}
+	}
+
+	return nil
+}
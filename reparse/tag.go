@@ -0,0 +1,41 @@
+package reparse
+
+// Tag is a reparse point tag.
+//
+// https://learn.microsoft.com/en-us/openspecs/windows_protocols/ms-fscc/c8e77b37-3909-4fe6-a4ea-2b9d423b1ee4
+type Tag uint32
+
+const (
+	tagMicrosoft     Tag = 0x80000000 // M bit
+	tagNameSurrogate Tag = 0x20000000 // N bit
+	tagDirectory     Tag = 0x10000000 // D bit
+	tagReserved      Tag = 0x40000000 // R bit
+	tagReservedBits  Tag = 0x0FFF0000 // bits 16-27
+)
+
+// IsMicrosoft reports whether t is owned by Microsoft. Microsoft tags are
+// stored as REPARSE_DATA_BUFFER rather than REPARSE_GUID_DATA_BUFFER.
+func (t Tag) IsMicrosoft() bool {
+	return t&tagMicrosoft != 0
+}
+
+// IsNameSurrogate reports whether t represents another named entity in
+// the system, as mount points and symbolic links do.
+func (t Tag) IsNameSurrogate() bool {
+	return t&tagNameSurrogate != 0
+}
+
+// IsDirectory reports whether a directory carrying t may have children.
+func (t Tag) IsDirectory() bool {
+	return t&tagDirectory != 0
+}
+
+// Valid reports whether t may be set on a file. The values 0, 1 and 2 are
+// reserved, as are bits 16 to 27 and, for non-Microsoft tags, bit 30.
+func (t Tag) Valid() bool {
+	if t <= 2 || t&tagReservedBits != 0 {
+		return false
+	}
+
+	return t.IsMicrosoft() || t&tagReserved == 0
+}
@@ -0,0 +1,129 @@
+package security
+
+import (
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// authzHandle is an AUTHZ_*_HANDLE.
+type authzHandle uintptr
+
+// AUTHZ_ACCESS_REQUEST
+type authzAccessRequest struct {
+	DesiredAccess        AccessMask
+	PrincipalSelfSid     *windows.SID
+	ObjectTypeList       uintptr
+	ObjectTypeListLength uint32
+	OptionalArguments    uintptr
+}
+
+// AUTHZ_ACCESS_REPLY
+type authzAccessReply struct {
+	ResultListLength      uint32
+	GrantedAccessMask     *AccessMask
+	SaclEvaluationResults *uint32
+	Error                 *uint32
+}
+
+const (
+	authzRMFlagNoAudit    = 0x1 // AUTHZ_RM_FLAG_NO_AUDIT
+	authzSkipTokenGroups  = 0x2 // AUTHZ_SKIP_TOKEN_GROUPS
+	effectiveAccessPolicy = windows.OWNER_SECURITY_INFORMATION | windows.GROUP_SECURITY_INFORMATION |
+		windows.DACL_SECURITY_INFORMATION | windows.LABEL_SECURITY_INFORMATION
+)
+
+// resourceManager is the AuthZ resource manager shared by all the access
+// checks, which is never freed.
+var resourceManager = sync.OnceValues(func() (authzHandle, error) {
+	var rm authzHandle
+	err := authzInitializeResourceManager(authzRMFlagNoAudit, 0, 0, 0, nil, &rm)
+
+	return rm, err
+})
+
+// EffectiveAccess returns the rights principal, a user or a group, is
+// granted on the file or directory at path by its security descriptor,
+// as computed by the AuthZ API. The group memberships of users are
+// resolved, which for domain users requires access to a domain
+// controller. Privileges of principal, such as SeBackupPrivilege, are not
+// taken into account.
+func EffectiveAccess(path string, principal *windows.SID) (AccessMask, error) {
+	d, err := Get(path, effectiveAccessPolicy)
+	if err != nil {
+		return 0, err
+	}
+
+	return d.EffectiveAccess(principal)
+}
+
+// EffectiveAccess returns the rights principal is granted by d, like the
+// function EffectiveAccess does for the descriptor of a file. d must have
+// an owner.
+func (d *Descriptor) EffectiveAccess(principal *windows.SID) (AccessMask, error) {
+	rm, err := resourceManager()
+	if err != nil {
+		return 0, err
+	}
+
+	sd, err := d.Windows()
+	if err != nil {
+		return 0, err
+	}
+
+	// Group SIDs have no group memberships to expand.
+	var flags uint32
+	if _, _, use, err := principal.LookupAccount(""); err == nil && use != windows.SidTypeUser {
+		flags |= authzSkipTokenGroups
+	}
+
+	var ctx authzHandle
+	err = authzInitializeContextFromSid(flags, principal, rm, &ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer authzFreeContext(ctx)
+
+	var (
+		granted AccessMask
+		status  uint32
+		req     = authzAccessRequest{DesiredAccess: MaximumAllowed}
+		reply   = authzAccessReply{ResultListLength: 1, GrantedAccessMask: &granted, Error: &status}
+	)
+	if err := authzAccessCheck(0, ctx, &req, 0, sd, nil, 0, &reply, nil); err != nil {
+		return 0, err
+	}
+
+	if status != 0 && status != uint32(windows.ERROR_ACCESS_DENIED) {
+		return 0, windows.Errno(status)
+	}
+
+	return granted, nil
+}
+
+var procAuthzInitializeContextFromSid = modauthz.NewProc("AuthzInitializeContextFromSid")
+
+// authzInitializeContextFromSid calls AuthzInitializeContextFromSid with
+// no expiration time nor dynamic groups. It is not generated, as the LUID
+// identifier passed by value takes two words on 32-bit platforms.
+func authzInitializeContextFromSid(flags uint32, sid *windows.SID, rm authzHandle, ctx *authzHandle) error {
+	addr := procAuthzInitializeContextFromSid.Addr()
+	var (
+		r1 uintptr
+		e1 syscall.Errno
+	)
+	if unsafe.Sizeof(uintptr(0)) == 4 {
+		r1, _, e1 = syscall.SyscallN(addr, uintptr(flags), uintptr(unsafe.Pointer(sid)), uintptr(rm), 0, 0, 0, 0,
+			uintptr(unsafe.Pointer(ctx)))
+	} else {
+		r1, _, e1 = syscall.SyscallN(addr, uintptr(flags), uintptr(unsafe.Pointer(sid)), uintptr(rm), 0, 0, 0,
+			uintptr(unsafe.Pointer(ctx)))
+	}
+	if r1 == 0 {
+		return e1
+	}
+
+	return nil
+}
@@ -0,0 +1,226 @@
+package security
+
+import (
+	"golang.org/x/sys/windows"
+)
+
+// AceType is the type of an access control entry.
+type AceType uint8
+
+const (
+	AccessAllowed               AceType = 0x00 // ACCESS_ALLOWED_ACE_TYPE
+	AccessDenied                AceType = 0x01 // ACCESS_DENIED_ACE_TYPE
+	SystemAudit                 AceType = 0x02 // SYSTEM_AUDIT_ACE_TYPE
+	SystemAlarm                 AceType = 0x03 // SYSTEM_ALARM_ACE_TYPE
+	AccessAllowedCompound       AceType = 0x04 // ACCESS_ALLOWED_COMPOUND_ACE_TYPE
+	AccessAllowedObject         AceType = 0x05 // ACCESS_ALLOWED_OBJECT_ACE_TYPE
+	AccessDeniedObject          AceType = 0x06 // ACCESS_DENIED_OBJECT_ACE_TYPE
+	SystemAuditObject           AceType = 0x07 // SYSTEM_AUDIT_OBJECT_ACE_TYPE
+	SystemAlarmObject           AceType = 0x08 // SYSTEM_ALARM_OBJECT_ACE_TYPE
+	AccessAllowedCallback       AceType = 0x09 // ACCESS_ALLOWED_CALLBACK_ACE_TYPE
+	AccessDeniedCallback        AceType = 0x0A // ACCESS_DENIED_CALLBACK_ACE_TYPE
+	AccessAllowedCallbackObject AceType = 0x0B // ACCESS_ALLOWED_CALLBACK_OBJECT_ACE_TYPE
+	AccessDeniedCallbackObject  AceType = 0x0C // ACCESS_DENIED_CALLBACK_OBJECT_ACE_TYPE
+	SystemAuditCallback         AceType = 0x0D // SYSTEM_AUDIT_CALLBACK_ACE_TYPE
+	SystemAlarmCallback         AceType = 0x0E // SYSTEM_ALARM_CALLBACK_ACE_TYPE
+	SystemAuditCallbackObject   AceType = 0x0F // SYSTEM_AUDIT_CALLBACK_OBJECT_ACE_TYPE
+	SystemAlarmCallbackObject   AceType = 0x10 // SYSTEM_ALARM_CALLBACK_OBJECT_ACE_TYPE
+	SystemMandatoryLabel        AceType = 0x11 // SYSTEM_MANDATORY_LABEL_ACE_TYPE
+	SystemResourceAttribute     AceType = 0x12 // SYSTEM_RESOURCE_ATTRIBUTE_ACE_TYPE
+	SystemScopedPolicyID        AceType = 0x13 // SYSTEM_SCOPED_POLICY_ID_ACE_TYPE
+	SystemProcessTrustLabel     AceType = 0x14 // SYSTEM_PROCESS_TRUST_LABEL_ACE_TYPE
+	SystemAccessFilter          AceType = 0x15 // SYSTEM_ACCESS_FILTER_ACE_TYPE
+)
+
+// IsObject reports whether entries of type t carry object type GUIDs.
+func (t AceType) IsObject() bool {
+	switch t {
+	case AccessAllowedObject, AccessDeniedObject, SystemAuditObject, SystemAlarmObject,
+		AccessAllowedCallbackObject, AccessDeniedCallbackObject,
+		SystemAuditCallbackObject, SystemAlarmCallbackObject:
+		return true
+	default:
+		return false
+	}
+}
+
+// known reports whether entries of type t have the layout of an
+// ACCESS_ALLOWED_ACE or ACCESS_ALLOWED_OBJECT_ACE, optionally followed by
+// application data, and can be decoded.
+func (t AceType) known() bool {
+	return t <= SystemAccessFilter && t != AccessAllowedCompound
+}
+
+// AceFlags are the inheritance and audit flags of an access control
+// entry.
+type AceFlags uint8
+
+const (
+	ObjectInherit      AceFlags = 0x01 // OBJECT_INHERIT_ACE
+	ContainerInherit   AceFlags = 0x02 // CONTAINER_INHERIT_ACE
+	NoPropagateInherit AceFlags = 0x04 // NO_PROPAGATE_INHERIT_ACE
+	InheritOnly        AceFlags = 0x08 // INHERIT_ONLY_ACE
+	Inherited          AceFlags = 0x10 // INHERITED_ACE
+	SuccessfulAccess   AceFlags = 0x40 // SUCCESSFUL_ACCESS_ACE_FLAG
+	FailedAccess       AceFlags = 0x80 // FAILED_ACCESS_ACE_FLAG
+)
+
+// AccessMask is the set of rights granted, denied or audited by an access
+// control entry.
+type AccessMask uint32
+
+// Standard and generic rights.
+const (
+	Delete               AccessMask = 0x00010000 // DELETE
+	ReadControl          AccessMask = 0x00020000 // READ_CONTROL
+	WriteDAC             AccessMask = 0x00040000 // WRITE_DAC
+	WriteOwner           AccessMask = 0x00080000 // WRITE_OWNER
+	Synchronize          AccessMask = 0x00100000 // SYNCHRONIZE
+	AccessSystemSecurity AccessMask = 0x01000000 // ACCESS_SYSTEM_SECURITY
+	MaximumAllowed       AccessMask = 0x02000000 // MAXIMUM_ALLOWED
+	GenericAll           AccessMask = 0x10000000 // GENERIC_ALL
+	GenericExecute       AccessMask = 0x20000000 // GENERIC_EXECUTE
+	GenericWrite         AccessMask = 0x40000000 // GENERIC_WRITE
+	GenericRead          AccessMask = 0x80000000 // GENERIC_READ
+
+	StandardRightsRequired AccessMask = Delete | ReadControl | WriteDAC | WriteOwner // STANDARD_RIGHTS_REQUIRED
+)
+
+// File and directory rights.
+const (
+	FileReadData        AccessMask = 0x0001 // FILE_READ_DATA
+	FileListDirectory   AccessMask = 0x0001 // FILE_LIST_DIRECTORY
+	FileWriteData       AccessMask = 0x0002 // FILE_WRITE_DATA
+	FileAddFile         AccessMask = 0x0002 // FILE_ADD_FILE
+	FileAppendData      AccessMask = 0x0004 // FILE_APPEND_DATA
+	FileAddSubdirectory AccessMask = 0x0004 // FILE_ADD_SUBDIRECTORY
+	FileReadEA          AccessMask = 0x0008 // FILE_READ_EA
+	FileWriteEA         AccessMask = 0x0010 // FILE_WRITE_EA
+	FileExecute         AccessMask = 0x0020 // FILE_EXECUTE
+	FileTraverse        AccessMask = 0x0020 // FILE_TRAVERSE
+	FileDeleteChild     AccessMask = 0x0040 // FILE_DELETE_CHILD
+	FileReadAttributes  AccessMask = 0x0080 // FILE_READ_ATTRIBUTES
+	FileWriteAttributes AccessMask = 0x0100 // FILE_WRITE_ATTRIBUTES
+
+	// FILE_ALL_ACCESS
+	FileAllAccess AccessMask = StandardRightsRequired | Synchronize | 0x1FF
+	// FILE_GENERIC_READ
+	FileGenericRead AccessMask = ReadControl | FileReadData | FileReadAttributes | FileReadEA | Synchronize
+	// FILE_GENERIC_WRITE
+	FileGenericWrite AccessMask = ReadControl | FileWriteData | FileWriteAttributes | FileWriteEA |
+		FileAppendData | Synchronize
+	// FILE_GENERIC_EXECUTE
+	FileGenericExecute AccessMask = ReadControl | FileReadAttributes | FileExecute | Synchronize
+)
+
+// Rights granted by the simple permissions of Explorer and icacls.
+const (
+	ReadAndExecute = FileGenericRead | FileGenericExecute       // RX
+	Modify         = ReadAndExecute | FileGenericWrite | Delete // M
+	FullControl    = FileAllAccess                              // F
+)
+
+// Has reports whether m includes all the rights of rights.
+func (m AccessMask) Has(rights AccessMask) bool {
+	return m&rights == rights
+}
+
+// fileGenericMapping maps the generic rights to file rights.
+var fileGenericMapping = [4]AccessMask{
+	FileGenericRead, FileGenericWrite, FileGenericExecute, FileAllAccess,
+}
+
+// Expand returns m with its generic rights replaced by the file rights
+// they stand for.
+func (m AccessMask) Expand() AccessMask {
+	for i, g := range []AccessMask{GenericRead, GenericWrite, GenericExecute, GenericAll} {
+		if m&g != 0 {
+			m = m&^g | fileGenericMapping[i]
+		}
+	}
+
+	return m
+}
+
+// Object type flags of object ACEs.
+const (
+	aceObjectTypePresent          = 0x1 // ACE_OBJECT_TYPE_PRESENT
+	aceInheritedObjectTypePresent = 0x2 // ACE_INHERITED_OBJECT_TYPE_PRESENT
+)
+
+// ACE is an access control entry.
+type ACE struct {
+	Type  AceType
+	Flags AceFlags
+	Mask  AccessMask
+	SID   *windows.SID
+
+	// ObjectType and InheritedObjectType are the optional GUIDs of
+	// object ACEs.
+	ObjectType          *windows.GUID
+	InheritedObjectType *windows.GUID
+
+	// ApplicationData follows the SID in callback ACEs, where it holds
+	// the conditional expression, and in resource attribute ACEs.
+	ApplicationData []byte
+
+	// raw holds the body of entries whose type cannot be decoded, which
+	// is preserved as is.
+	raw []byte
+}
+
+// ACL is an access control list.
+type ACL struct {
+	// Revision is ACL_REVISION (2), or ACL_REVISION_DS (4) for lists
+	// holding object ACEs.
+	Revision uint8
+	Entries  []ACE
+}
+
+// ACL revisions.
+const (
+	ACLRevision   = 2 // ACL_REVISION
+	ACLRevisionDS = 4 // ACL_REVISION_DS
+)
+
+// Clone returns a deep copy of a.
+func (a *ACL) Clone() *ACL {
+	if a == nil {
+		return nil
+	}
+
+	c := &ACL{Revision: a.Revision, Entries: make([]ACE, len(a.Entries))}
+	for i, e := range a.Entries {
+		c.Entries[i] = e.Clone()
+	}
+
+	return c
+}
+
+// Clone returns a deep copy of e.
+func (e ACE) Clone() ACE {
+	c := e
+	if e.SID != nil {
+		c.SID = cloneSID(e.SID)
+	}
+	if e.ObjectType != nil {
+		g := *e.ObjectType
+		c.ObjectType = &g
+	}
+	if e.InheritedObjectType != nil {
+		g := *e.InheritedObjectType
+		c.InheritedObjectType = &g
+	}
+	c.ApplicationData = cloneBytes(e.ApplicationData)
+	c.raw = cloneBytes(e.raw)
+
+	return c
+}
+
+func cloneBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+
+	return append([]byte(nil), b...)
+}
@@ -0,0 +1,179 @@
+package security
+
+import (
+	"slices"
+
+	"golang.org/x/sys/windows"
+)
+
+// NewACL returns an access control list holding entries, in this order.
+func NewACL(entries ...ACE) *ACL {
+	return &ACL{Entries: entries}
+}
+
+// Allow returns an entry granting mask to sid.
+func Allow(sid *windows.SID, mask AccessMask, flags AceFlags) ACE {
+	return ACE{Type: AccessAllowed, Flags: flags, Mask: mask, SID: cloneSID(sid)}
+}
+
+// Deny returns an entry denying mask to sid.
+func Deny(sid *windows.SID, mask AccessMask, flags AceFlags) ACE {
+	return ACE{Type: AccessDenied, Flags: flags, Mask: mask, SID: cloneSID(sid)}
+}
+
+// Audit returns an entry auditing the uses of mask by sid, whose flags
+// should include SuccessfulAccess, FailedAccess or both.
+func Audit(sid *windows.SID, mask AccessMask, flags AceFlags) ACE {
+	return ACE{Type: SystemAudit, Flags: flags, Mask: mask, SID: cloneSID(sid)}
+}
+
+// IsInherited reports whether e was inherited from a parent container.
+func (e *ACE) IsInherited() bool {
+	return e.Flags&Inherited != 0
+}
+
+// IsDeny reports whether e denies access.
+func (e *ACE) IsDeny() bool {
+	switch e.Type {
+	case AccessDenied, AccessDeniedObject, AccessDeniedCallback, AccessDeniedCallbackObject:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsAllow reports whether e grants access.
+func (e *ACE) IsAllow() bool {
+	switch e.Type {
+	case AccessAllowed, AccessAllowedObject, AccessAllowedCallback, AccessAllowedCallbackObject:
+		return true
+	default:
+		return false
+	}
+}
+
+// Equal reports whether e and o are the same entry.
+func (e *ACE) Equal(o *ACE) bool {
+	return e.Type == o.Type && e.Flags == o.Flags && e.Mask == o.Mask &&
+		equalSID(e.SID, o.SID) &&
+		equalGUID(e.ObjectType, o.ObjectType) &&
+		equalGUID(e.InheritedObjectType, o.InheritedObjectType) &&
+		slices.Equal(e.ApplicationData, o.ApplicationData) &&
+		slices.Equal(e.raw, o.raw)
+}
+
+func equalGUID(a, b *windows.GUID) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return *a == *b
+}
+
+// Len returns the number of entries of a, a nil list having none.
+func (a *ACL) Len() int {
+	if a == nil {
+		return 0
+	}
+
+	return len(a.Entries)
+}
+
+// Add inserts e at its canonical position in a: explicit deny entries come
+// first, then explicit allow entries, then inherited entries in the order
+// they were inherited. e goes after the entries of its own class. Explicit
+// entries of other types, such as audit entries, are classed with the
+// explicit allow entries, so that the explicit entries of a SACL still
+// come before its inherited ones.
+func (a *ACL) Add(e ACE) {
+	a.Insert(a.canonicalIndex(&e), e)
+}
+
+func (a *ACL) canonicalIndex(e *ACE) int {
+	rank := canonicalRank(e)
+	for i := range a.Entries {
+		if canonicalRank(&a.Entries[i]) > rank {
+			return i
+		}
+	}
+
+	return len(a.Entries)
+}
+
+// canonicalRank returns the class of e in the canonical order of entries,
+// explicit entries which do not deny access ranking as allow entries.
+func canonicalRank(e *ACE) int {
+	switch {
+	case e.IsInherited():
+		return 2
+	case e.IsDeny():
+		return 0
+	default:
+		return 1
+	}
+}
+
+// Insert inserts e at index i of a. It panics if i is out of range.
+func (a *ACL) Insert(i int, e ACE) {
+	a.Entries = slices.Insert(a.Entries, i, e)
+}
+
+// Remove removes the entry at index i of a. It panics if i is out of
+// range.
+func (a *ACL) Remove(i int) {
+	a.Entries = slices.Delete(a.Entries, i, i+1)
+}
+
+// RemoveFunc removes the entries of a for which del returns true, and
+// returns their number.
+func (a *ACL) RemoveFunc(del func(*ACE) bool) int {
+	n := len(a.Entries)
+	a.Entries = slices.DeleteFunc(a.Entries, func(e ACE) bool { return del(&e) })
+
+	return n - len(a.Entries)
+}
+
+// RemoveSID removes the explicit entries of a whose trustee is sid, and
+// returns their number. Inherited entries are left alone, as they would be
+// inherited again.
+func (a *ACL) RemoveSID(sid *windows.SID) int {
+	return a.RemoveFunc(func(e *ACE) bool {
+		return !e.IsInherited() && equalSID(e.SID, sid)
+	})
+}
+
+// Index returns the index of the first entry of a for which match returns
+// true, or -1.
+func (a *ACL) Index(match func(*ACE) bool) int {
+	for i := range a.Entries {
+		if match(&a.Entries[i]) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// Move moves the entry at index from of a to index to, shifting the
+// entries in between. It panics if either index is out of range.
+func (a *ACL) Move(from, to int) {
+	e := a.Entries[from]
+	a.Entries = slices.Delete(a.Entries, from, from+1)
+	a.Entries = slices.Insert(a.Entries, to, e)
+}
+
+// IsCanonical reports whether the entries of a are in canonical order:
+// explicit deny entries, explicit allow entries, then inherited entries.
+func (a *ACL) IsCanonical() bool {
+	return slices.IsSortedFunc(a.Entries, compareCanonical)
+}
+
+// Canonicalize sorts the entries of a in canonical order, keeping the
+// relative order of the entries of each class.
+func (a *ACL) Canonicalize() {
+	slices.SortStableFunc(a.Entries, compareCanonical)
+}
+
+func compareCanonical(x, y ACE) int {
+	return canonicalRank(&x) - canonicalRank(&y)
+}
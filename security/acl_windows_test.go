@@ -0,0 +1,85 @@
+package security
+
+import "testing"
+
+func TestACLAdd(t *testing.T) {
+	var (
+		everyone = mustSID(t, "S-1-1-0")
+		users    = mustSID(t, "S-1-5-32-545")
+	)
+	allow := Allow(everyone, ReadAndExecute, 0)
+	deny := Deny(everyone, Delete, 0)
+	inherited := Allow(users, FileGenericRead, Inherited)
+	inheritedDeny := Deny(users, Delete, Inherited)
+	audit := Audit(everyone, Delete, FailedAccess)
+
+	tests := []struct {
+		name    string
+		entries []ACE
+		add     ACE
+		want    int
+	}{
+		{"empty", nil, allow, 0},
+		{"deny first", []ACE{allow, inherited}, deny, 0},
+		{"deny after denies", []ACE{deny, allow}, deny, 1},
+		{"allow after explicit", []ACE{deny, allow, inherited}, allow, 2},
+		{"allow before inherited", []ACE{inheritedDeny}, allow, 0},
+		{"inherited last", []ACE{deny, inherited}, inheritedDeny, 2},
+		{"audit with allow entries", []ACE{deny, allow, inherited}, audit, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewACL(append([]ACE(nil), tt.entries...)...)
+			a.Add(tt.add)
+			if a.Len() != len(tt.entries)+1 || !a.Entries[tt.want].Equal(&tt.add) {
+				t.Errorf("entries = %v, want the new entry at %d", a.Entries, tt.want)
+			}
+			if !a.IsCanonical() {
+				t.Errorf("entries = %v, not canonical", a.Entries)
+			}
+		})
+	}
+}
+
+func TestACLCanonicalize(t *testing.T) {
+	everyone := mustSID(t, "S-1-1-0")
+	entries := []ACE{
+		Allow(everyone, FileGenericRead, Inherited),
+		Allow(everyone, FileGenericWrite, 0),
+		Deny(everyone, Delete, Inherited),
+		Deny(everyone, WriteDAC, 0),
+		Allow(everyone, FileTraverse, 0),
+	}
+	want := NewACL(entries[3], entries[1], entries[4], entries[0], entries[2])
+
+	a := NewACL(entries...)
+	if a.IsCanonical() {
+		t.Error("IsCanonical = true before Canonicalize")
+	}
+	a.Canonicalize()
+	if !equalEntries(a, want) {
+		t.Errorf("Canonicalize =\n%v\nwant\n%v", a.Entries, want.Entries)
+	}
+	if !a.IsCanonical() {
+		t.Error("IsCanonical = false after Canonicalize")
+	}
+}
+
+func TestACLRemoveSID(t *testing.T) {
+	everyone, users := mustSID(t, "S-1-1-0"), mustSID(t, "S-1-5-32-545")
+	a := NewACL(
+		Allow(everyone, FileGenericRead, 0),
+		Allow(users, FileGenericRead, 0),
+		Deny(everyone, Delete, 0),
+		Allow(everyone, FileGenericRead, Inherited),
+	)
+
+	if n := a.RemoveSID(everyone); n != 2 {
+		t.Errorf("RemoveSID = %d, want 2", n)
+	}
+	want := NewACL(Allow(users, FileGenericRead, 0), Allow(everyone, FileGenericRead, Inherited))
+	if !equalEntries(a, want) {
+		t.Errorf("entries = %v, want %v", a.Entries, want.Entries)
+	}
+}
@@ -0,0 +1,325 @@
+package security
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	// ErrInvalidDescriptor is returned when decoding a malformed
+	// self-relative security descriptor.
+	ErrInvalidDescriptor = errors.New("security: invalid security descriptor")
+	// ErrTooLarge is returned when an access control list or entry exceeds
+	// the 64KiB size limit of its binary form.
+	ErrTooLarge = errors.New("security: access control list too large")
+)
+
+const (
+	descriptorRevision   = 1  // SECURITY_DESCRIPTOR_REVISION
+	descriptorHeaderSize = 20 // SECURITY_DESCRIPTOR_RELATIVE
+	aclHeaderSize        = 8  // ACL
+	aceHeaderSize        = 4  // ACE_HEADER
+	guidSize             = 16
+)
+
+// FromBinary decodes the self-relative security descriptor b, as returned
+// by GetFileSecurity or stored in BackupRead streams and $Secure. b is not
+// retained.
+func FromBinary(b []byte) (*Descriptor, error) {
+	d := new(Descriptor)
+	if err := d.UnmarshalBinary(b); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// UnmarshalBinary decodes the self-relative security descriptor b into d.
+func (d *Descriptor) UnmarshalBinary(b []byte) error {
+	if len(b) < descriptorHeaderSize || b[0] != descriptorRevision {
+		return ErrInvalidDescriptor
+	}
+
+	control := windows.SECURITY_DESCRIPTOR_CONTROL(binary.LittleEndian.Uint16(b[2:]))
+	if control&windows.SE_SELF_RELATIVE == 0 {
+		return ErrInvalidDescriptor
+	}
+
+	var (
+		nd  = Descriptor{control: control &^ windows.SE_SELF_RELATIVE, rmControl: b[1]}
+		err error
+	)
+	if off := binary.LittleEndian.Uint32(b[4:]); off != 0 {
+		if nd.owner, err = sidAt(b, off); err != nil {
+			return err
+		}
+	}
+	if off := binary.LittleEndian.Uint32(b[8:]); off != 0 {
+		if nd.group, err = sidAt(b, off); err != nil {
+			return err
+		}
+	}
+	if off := binary.LittleEndian.Uint32(b[12:]); off != 0 && control&windows.SE_SACL_PRESENT != 0 {
+		if nd.sacl, err = aclAt(b, off); err != nil {
+			return err
+		}
+	}
+	if off := binary.LittleEndian.Uint32(b[16:]); off != 0 && control&windows.SE_DACL_PRESENT != 0 {
+		if nd.dacl, err = aclAt(b, off); err != nil {
+			return err
+		}
+	}
+
+	*d = nd
+	return nil
+}
+
+// MarshalBinary returns d in self-relative form, laid out like the
+// descriptors returned by Windows: the SACL, DACL, owner and group follow
+// the header in this order.
+func (d *Descriptor) MarshalBinary() ([]byte, error) {
+	b := make([]byte, descriptorHeaderSize)
+	b[0] = descriptorRevision
+	b[1] = d.rmControl
+	binary.LittleEndian.PutUint16(b[2:], uint16(d.control|windows.SE_SELF_RELATIVE))
+
+	var err error
+	if d.sacl != nil && d.HasSACL() {
+		binary.LittleEndian.PutUint32(b[12:], uint32(len(b)))
+		if b, err = d.sacl.appendBinary(b); err != nil {
+			return nil, err
+		}
+	}
+	if d.dacl != nil && d.HasDACL() {
+		binary.LittleEndian.PutUint32(b[16:], uint32(len(b)))
+		if b, err = d.dacl.appendBinary(b); err != nil {
+			return nil, err
+		}
+	}
+	if d.owner != nil {
+		binary.LittleEndian.PutUint32(b[4:], uint32(len(b)))
+		b = append(b, sidBytes(d.owner)...)
+	}
+	if d.group != nil {
+		binary.LittleEndian.PutUint32(b[8:], uint32(len(b)))
+		b = append(b, sidBytes(d.group)...)
+	}
+
+	return b, nil
+}
+
+func sidAt(b []byte, off uint32) (*windows.SID, error) {
+	if uint64(off) >= uint64(len(b)) {
+		return nil, ErrInvalidDescriptor
+	}
+
+	sid, _, err := sidFromBytes(b[off:])
+	if err != nil {
+		return nil, ErrInvalidDescriptor
+	}
+
+	return sid, nil
+}
+
+func aclAt(b []byte, off uint32) (*ACL, error) {
+	if uint64(off)+aclHeaderSize > uint64(len(b)) {
+		return nil, ErrInvalidDescriptor
+	}
+	b = b[off:]
+
+	size := int(binary.LittleEndian.Uint16(b[2:]))
+	count := int(binary.LittleEndian.Uint16(b[4:]))
+	if size < aclHeaderSize || size > len(b) {
+		return nil, ErrInvalidDescriptor
+	}
+
+	acl := &ACL{Revision: b[0], Entries: make([]ACE, 0, count)}
+	b = b[aclHeaderSize:size]
+	for range count {
+		if len(b) < aceHeaderSize {
+			return nil, ErrInvalidDescriptor
+		}
+		n := int(binary.LittleEndian.Uint16(b[2:]))
+		if n < aceHeaderSize || n > len(b) {
+			return nil, ErrInvalidDescriptor
+		}
+
+		e, err := parseACE(b[:n])
+		if err != nil {
+			return nil, err
+		}
+		acl.Entries = append(acl.Entries, e)
+		b = b[n:]
+	}
+
+	return acl, nil
+}
+
+// parseACE decodes the access control entry b, header included.
+func parseACE(b []byte) (ACE, error) {
+	e := ACE{Type: AceType(b[0]), Flags: AceFlags(b[1])}
+	body := b[aceHeaderSize:]
+	if !e.Type.known() {
+		e.raw = cloneBytes(body)
+		return e, nil
+	}
+
+	if len(body) < 4 {
+		return ACE{}, ErrInvalidDescriptor
+	}
+	e.Mask = AccessMask(binary.LittleEndian.Uint32(body))
+	body = body[4:]
+
+	if e.Type.IsObject() {
+		if len(body) < 4 {
+			return ACE{}, ErrInvalidDescriptor
+		}
+		flags := binary.LittleEndian.Uint32(body)
+		body = body[4:]
+		if flags&aceObjectTypePresent != 0 {
+			if len(body) < guidSize {
+				return ACE{}, ErrInvalidDescriptor
+			}
+			e.ObjectType = guidFromBytes(body)
+			body = body[guidSize:]
+		}
+		if flags&aceInheritedObjectTypePresent != 0 {
+			if len(body) < guidSize {
+				return ACE{}, ErrInvalidDescriptor
+			}
+			e.InheritedObjectType = guidFromBytes(body)
+			body = body[guidSize:]
+		}
+	}
+
+	sid, n, err := sidFromBytes(body)
+	if err != nil {
+		return ACE{}, ErrInvalidDescriptor
+	}
+	e.SID = sid
+	if rest := body[n:]; len(rest) > 0 && e.Type.hasApplicationData() {
+		e.ApplicationData = cloneBytes(rest)
+	}
+
+	return e, nil
+}
+
+// appendBinary appends the binary form of a to b.
+func (a *ACL) appendBinary(b []byte) ([]byte, error) {
+	if len(a.Entries) > 0xFFFF {
+		return nil, ErrTooLarge
+	}
+
+	start := len(b)
+	revision := a.Revision
+	if revision == 0 {
+		revision = a.minRevision()
+	}
+	b = append(b, revision, 0, 0, 0, 0, 0, 0, 0)
+	binary.LittleEndian.PutUint16(b[start+4:], uint16(len(a.Entries)))
+
+	for i := range a.Entries {
+		var err error
+		if b, err = a.Entries[i].appendBinary(b); err != nil {
+			return nil, err
+		}
+	}
+
+	size := len(b) - start
+	if size > 0xFFFF {
+		return nil, ErrTooLarge
+	}
+	binary.LittleEndian.PutUint16(b[start+2:], uint16(size))
+
+	return b, nil
+}
+
+// minRevision returns the lowest revision able to hold the entries of a.
+func (a *ACL) minRevision() uint8 {
+	for _, e := range a.Entries {
+		if e.Type.IsObject() {
+			return ACLRevisionDS
+		}
+	}
+
+	return ACLRevision
+}
+
+// appendBinary appends the binary form of e, header included, to b.
+func (e *ACE) appendBinary(b []byte) ([]byte, error) {
+	start := len(b)
+	b = append(b, byte(e.Type), byte(e.Flags), 0, 0)
+
+	if e.Type.known() {
+		if e.SID == nil {
+			return nil, errInvalidSID
+		}
+		b = binary.LittleEndian.AppendUint32(b, uint32(e.Mask))
+		if e.Type.IsObject() {
+			var flags uint32
+			if e.ObjectType != nil {
+				flags |= aceObjectTypePresent
+			}
+			if e.InheritedObjectType != nil {
+				flags |= aceInheritedObjectTypePresent
+			}
+			b = binary.LittleEndian.AppendUint32(b, flags)
+			if e.ObjectType != nil {
+				b = appendGUID(b, e.ObjectType)
+			}
+			if e.InheritedObjectType != nil {
+				b = appendGUID(b, e.InheritedObjectType)
+			}
+		}
+		b = append(b, sidBytes(e.SID)...)
+		b = append(b, e.ApplicationData...)
+	} else {
+		b = append(b, e.raw...)
+	}
+
+	// Entries are DWORD aligned.
+	for (len(b)-start)%4 != 0 {
+		b = append(b, 0)
+	}
+
+	size := len(b) - start
+	if size > 0xFFFF {
+		return nil, ErrTooLarge
+	}
+	binary.LittleEndian.PutUint16(b[start+2:], uint16(size))
+
+	return b, nil
+}
+
+// hasApplicationData reports whether entries of type t may carry data
+// after their SID.
+func (t AceType) hasApplicationData() bool {
+	switch t {
+	case AccessAllowedCallback, AccessDeniedCallback,
+		AccessAllowedCallbackObject, AccessDeniedCallbackObject,
+		SystemAuditCallback, SystemAlarmCallback,
+		SystemAuditCallbackObject, SystemAlarmCallbackObject,
+		SystemResourceAttribute, SystemAccessFilter:
+		return true
+	default:
+		return false
+	}
+}
+
+func guidFromBytes(b []byte) *windows.GUID {
+	return &windows.GUID{
+		Data1: binary.LittleEndian.Uint32(b),
+		Data2: binary.LittleEndian.Uint16(b[4:]),
+		Data3: binary.LittleEndian.Uint16(b[6:]),
+		Data4: [8]byte(b[8:16]),
+	}
+}
+
+func appendGUID(b []byte, g *windows.GUID) []byte {
+	b = binary.LittleEndian.AppendUint32(b, g.Data1)
+	b = binary.LittleEndian.AppendUint16(b, g.Data2)
+	b = binary.LittleEndian.AppendUint16(b, g.Data3)
+	return append(b, g.Data4[:]...)
+}
@@ -0,0 +1,176 @@
+package security
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/sys/windows"
+)
+
+// CompareOptions control CompareTrees.
+type CompareOptions struct {
+	// Sections are the sections of the descriptors compared; the owner,
+	// group and DACL if zero. Comparing the SACL requires
+	// SeSecurityPrivilege.
+	Sections Sections
+	// IgnoreInherited leaves the inherited entries of the access control
+	// lists out of the comparison, for trees whose parents differ.
+	IgnoreInherited bool
+}
+
+// TreeDifference describes a file whose security differs between two
+// trees compared by CompareTrees.
+type TreeDifference struct {
+	// Path is the path of the file relative to the roots of the trees.
+	Path string
+	// Diff holds the differences between the descriptors of the file in
+	// the first and second tree.
+	Diff *DescriptorDiff
+	// Missing is set if the file is missing from the second tree, and
+	// Extra if it is only found in the second tree.
+	Missing bool
+	Extra   bool
+	// Err is set if the descriptors of the file could not be read.
+	Err error
+}
+
+// CompareTrees compares the security descriptors of the files of the
+// trees rooted at a and b, the roots included, and calls fn for every file
+// which differs, in lexical order. If fn returns filepath.SkipDir for a
+// directory, its children are skipped, and if it returns any other error,
+// CompareTrees stops and returns it, unless it is filepath.SkipAll.
+// Reparse points are compared but not followed. A nil opts is the same as
+// the zero CompareOptions.
+func CompareTrees(a, b string, opts *CompareOptions, fn func(TreeDifference) error) error {
+	if opts == nil {
+		opts = new(CompareOptions)
+	}
+
+	c := &treeComparer{a: a, b: b, opts: *opts, fn: fn}
+	if c.opts.Sections == 0 {
+		c.opts.Sections = windows.OWNER_SECURITY_INFORMATION | windows.GROUP_SECURITY_INFORMATION |
+			windows.DACL_SECURITY_INFORMATION
+	}
+
+	err := c.compare(".", true)
+	if errors.Is(err, filepath.SkipAll) || errors.Is(err, filepath.SkipDir) {
+		err = nil
+	}
+
+	return err
+}
+
+type treeComparer struct {
+	a, b string
+	opts CompareOptions
+	fn   func(TreeDifference) error
+}
+
+// compare compares the file at the relative path rel, and the children of
+// rel if it is a directory which must be walked.
+func (c *treeComparer) compare(rel string, walk bool) error {
+	d := TreeDifference{Path: rel}
+	da, err := c.read(c.a, rel)
+	if err == nil {
+		var db *Descriptor
+		if db, err = c.read(c.b, rel); err == nil {
+			d.Diff = Diff(da, db)
+		}
+	}
+	d.Err = err
+
+	if d.Diff != nil || d.Err != nil {
+		err := c.fn(d)
+		if errors.Is(err, filepath.SkipDir) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if !walk || d.Err != nil {
+		return nil
+	}
+
+	return c.children(rel)
+}
+
+// children compares the children of the directory rel.
+func (c *treeComparer) children(rel string) error {
+	inA, err := os.ReadDir(filepath.Join(c.a, rel))
+	if err != nil {
+		return c.report(TreeDifference{Path: rel, Err: err})
+	}
+	inB, err := os.ReadDir(filepath.Join(c.b, rel))
+	if err != nil {
+		return c.report(TreeDifference{Path: rel, Err: err})
+	}
+
+	// The listings are sorted by name, and merged to report the files in
+	// lexical order.
+	for i, j := 0, 0; i < len(inA) || j < len(inB); {
+		switch {
+		case j == len(inB) || i < len(inA) && inA[i].Name() < inB[j].Name():
+			if err := c.report(TreeDifference{Path: filepath.Join(rel, inA[i].Name()), Missing: true}); err != nil {
+				return err
+			}
+			i++
+		case i == len(inA) || inB[j].Name() < inA[i].Name():
+			if err := c.report(TreeDifference{Path: filepath.Join(rel, inB[j].Name()), Extra: true}); err != nil {
+				return err
+			}
+			j++
+		default:
+			e := inA[i]
+			walk := e.IsDir() && e.Type()&(fs.ModeSymlink|fs.ModeIrregular) == 0
+			if err := c.compare(filepath.Join(rel, e.Name()), walk); err != nil {
+				return err
+			}
+			i++
+			j++
+		}
+	}
+
+	return nil
+}
+
+// report calls fn for d, ignoring filepath.SkipDir.
+func (c *treeComparer) report(d TreeDifference) error {
+	if err := c.fn(d); err != nil && !errors.Is(err, filepath.SkipDir) {
+		return err
+	}
+
+	return nil
+}
+
+func (c *treeComparer) read(root, rel string) (*Descriptor, error) {
+	d, err := getLink(filepath.Join(root, rel), c.opts.Sections)
+	if err != nil {
+		return nil, err
+	}
+	if c.opts.IgnoreInherited {
+		d.dacl = explicitEntries(d.dacl)
+		d.sacl = explicitEntries(d.sacl)
+	}
+
+	return d, nil
+}
+
+// explicitEntries returns the entries of acl which were not inherited.
+func explicitEntries(acl *ACL) *ACL {
+	if acl == nil {
+		return nil
+	}
+
+	c := NewACL()
+	c.Revision = acl.Revision
+	for _, e := range acl.Entries {
+		if !e.IsInherited() {
+			c.Entries = append(c.Entries, e)
+		}
+	}
+
+	return c
+}
@@ -0,0 +1,105 @@
+package security
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/sys/windows"
+)
+
+// ErrNotConditional is returned by ACE.Condition for entries without a
+// conditional expression.
+var ErrNotConditional = errors.New("security: entry has no condition")
+
+// conditionSignature starts the binary form of conditional expressions.
+var conditionSignature = []byte("artx")
+
+// AllowIf returns a conditional entry granting mask to sid when condition,
+// a conditional expression in SDDL syntax such as
+// `(@User.Department == "Sales")`, holds.
+func AllowIf(sid *windows.SID, mask AccessMask, flags AceFlags, condition string) (ACE, error) {
+	return conditional("XA", sid, mask, flags, condition)
+}
+
+// DenyIf returns a conditional entry denying mask to sid when condition
+// holds.
+func DenyIf(sid *windows.SID, mask AccessMask, flags AceFlags, condition string) (ACE, error) {
+	return conditional("XD", sid, mask, flags, condition)
+}
+
+// AuditIf returns a conditional entry auditing the uses of mask by sid
+// when condition holds.
+func AuditIf(sid *windows.SID, mask AccessMask, flags AceFlags, condition string) (ACE, error) {
+	return conditional("XU", sid, mask, flags, condition)
+}
+
+// conditional compiles condition by having Windows parse an SDDL entry of
+// the given type holding it.
+func conditional(typ string, sid *windows.SID, mask AccessMask, flags AceFlags, condition string) (ACE, error) {
+	condition = strings.TrimSpace(condition)
+	if !strings.HasPrefix(condition, "(") {
+		condition = "(" + condition + ")"
+	}
+
+	section := "D:"
+	if typ == "XU" {
+		section = "S:"
+	}
+	d, err := Parse(fmt.Sprintf("%s(%s;;0x%x;;;%s;%s)", section, typ, uint32(mask), sid, condition))
+	if err != nil {
+		return ACE{}, fmt.Errorf("security: invalid condition %q: %w", condition, err)
+	}
+
+	acl := d.DACL()
+	if typ == "XU" {
+		acl = d.SACL()
+	}
+	if acl.Len() != 1 {
+		return ACE{}, fmt.Errorf("security: invalid condition %q", condition)
+	}
+
+	e := acl.Entries[0]
+	e.Flags = flags
+
+	return e, nil
+}
+
+// IsConditional reports whether e holds a conditional expression.
+func (e *ACE) IsConditional() bool {
+	return e.Type.hasApplicationData() && bytes.HasPrefix(e.ApplicationData, conditionSignature)
+}
+
+// Condition returns the conditional expression of e in SDDL syntax,
+// enclosed in parentheses.
+func (e *ACE) Condition() (string, error) {
+	if !e.IsConditional() {
+		return "", ErrNotConditional
+	}
+
+	// Format a descriptor holding e alone, whose entry reads
+	// (type;flags;rights;object;inherited object;sid;(condition)).
+	var d Descriptor
+	acl := NewACL(*e)
+	if e.Type == SystemAuditCallback || e.Type == SystemAuditCallbackObject {
+		d.SetSACL(acl)
+	} else {
+		d.SetDACL(acl)
+	}
+	s, err := d.SDDL(windows.DACL_SECURITY_INFORMATION | windows.SACL_SECURITY_INFORMATION)
+	if err != nil {
+		return "", err
+	}
+
+	start := strings.IndexByte(s, '(')
+	if start < 0 || !strings.HasSuffix(s, ")") {
+		return "", fmt.Errorf("security: unexpected SDDL %q", s)
+	}
+	fields := strings.SplitN(s[start+1:len(s)-1], ";", 7)
+	if len(fields) != 7 {
+		return "", fmt.Errorf("security: unexpected SDDL %q", s)
+	}
+
+	return fields[6], nil
+}
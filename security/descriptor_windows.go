@@ -0,0 +1,218 @@
+package security
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// sddlRevision is SDDL_REVISION_1, the only SDDL revision.
+const sddlRevision = 1
+
+// Sections selects the parts of a security descriptor which are read,
+// written or formatted.
+type Sections = windows.SECURITY_INFORMATION
+
+// AllSections selects the owner, group, DACL, SACL and mandatory label of
+// a security descriptor.
+const AllSections = windows.OWNER_SECURITY_INFORMATION | windows.GROUP_SECURITY_INFORMATION |
+	windows.DACL_SECURITY_INFORMATION | windows.SACL_SECURITY_INFORMATION |
+	windows.LABEL_SECURITY_INFORMATION
+
+// Descriptor is a security descriptor. Its owner, group and access control
+// lists are held in memory owned by the Go runtime, and can be edited
+// before being formatted or written back.
+//
+// The zero Descriptor is an empty descriptor with neither owner, group,
+// DACL nor SACL.
+type Descriptor struct {
+	control   windows.SECURITY_DESCRIPTOR_CONTROL
+	rmControl uint8
+	owner     *windows.SID
+	group     *windows.SID
+	dacl      *ACL
+	sacl      *ACL
+}
+
+// Parse parses the security descriptor described by the SDDL string sddl.
+func Parse(sddl string) (*Descriptor, error) {
+	sd, err := windows.SecurityDescriptorFromString(sddl)
+	if err != nil {
+		return nil, err
+	}
+
+	return FromWindows(sd)
+}
+
+// FromWindows returns the descriptor equivalent to sd, which may be in
+// either absolute or self-relative form. sd is not retained.
+func FromWindows(sd *windows.SECURITY_DESCRIPTOR) (*Descriptor, error) {
+	control, _, err := sd.Control()
+	if err != nil {
+		return nil, err
+	}
+	if control&windows.SE_SELF_RELATIVE == 0 {
+		if sd, err = sd.ToSelfRelative(); err != nil {
+			return nil, err
+		}
+	}
+
+	b := unsafe.Slice((*byte)(unsafe.Pointer(sd)), sd.Length())
+
+	return FromBinary(b)
+}
+
+// Windows returns d as a self-relative security descriptor allocated on the
+// Go heap, suitable for the functions of golang.org/x/sys/windows.
+func (d *Descriptor) Windows() (*windows.SECURITY_DESCRIPTOR, error) {
+	b, err := d.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	// The descriptor must be aligned like the absolute form, which holds
+	// pointers, and be at least as large.
+	n := max(len(b), int(unsafe.Sizeof(windows.SECURITY_DESCRIPTOR{})))
+	const psize = int(unsafe.Sizeof(uintptr(0)))
+	alloc := make([]uintptr, (n+psize-1)/psize)
+	copy(unsafe.Slice((*byte)(unsafe.Pointer(&alloc[0])), n), b)
+
+	return (*windows.SECURITY_DESCRIPTOR)(unsafe.Pointer(&alloc[0])), nil
+}
+
+// String returns the SDDL form of all the sections of d, or an empty
+// string if d cannot be formatted.
+func (d *Descriptor) String() string {
+	s, _ := d.SDDL(AllSections)
+	return s
+}
+
+// SDDL returns the SDDL form of the sections of d selected by sections.
+func (d *Descriptor) SDDL(sections Sections) (string, error) {
+	sd, err := d.Windows()
+	if err != nil {
+		return "", err
+	}
+
+	var (
+		str *uint16
+		n   uint32
+	)
+	err = convertSecurityDescriptorToStringSecurityDescriptor(sd, sddlRevision, sections, &str, &n)
+	if err != nil {
+		return "", err
+	}
+	defer windows.LocalFree(windows.Handle(unsafe.Pointer(str)))
+
+	return windows.UTF16PtrToString(str), nil
+}
+
+// Clone returns a deep copy of d.
+func (d *Descriptor) Clone() *Descriptor {
+	c := *d
+	if d.owner != nil {
+		c.owner = cloneSID(d.owner)
+	}
+	if d.group != nil {
+		c.group = cloneSID(d.group)
+	}
+	c.dacl = d.dacl.Clone()
+	c.sacl = d.sacl.Clone()
+
+	return &c
+}
+
+// Control returns the control flags of d. SE_SELF_RELATIVE is never set,
+// as it only describes the in-memory form of a descriptor.
+func (d *Descriptor) Control() windows.SECURITY_DESCRIPTOR_CONTROL {
+	return d.control
+}
+
+// SetControl sets the control flags of d selected by mask to those of
+// bits. The flags tracking the presence of the owner, group and access
+// control lists are managed by the corresponding setters and are ignored.
+func (d *Descriptor) SetControl(mask, bits windows.SECURITY_DESCRIPTOR_CONTROL) {
+	const managed = windows.SE_SELF_RELATIVE | windows.SE_DACL_PRESENT | windows.SE_SACL_PRESENT
+	mask &^= managed
+	d.control = d.control&^mask | bits&mask
+}
+
+// Owner returns the owner of d, or nil if d has no owner.
+func (d *Descriptor) Owner() *windows.SID {
+	return d.owner
+}
+
+// SetOwner sets the owner of d to a copy of sid. A nil sid removes the
+// owner.
+func (d *Descriptor) SetOwner(sid *windows.SID) {
+	d.owner = nil
+	if sid != nil {
+		d.owner = cloneSID(sid)
+	}
+	d.control &^= windows.SE_OWNER_DEFAULTED
+}
+
+// Group returns the primary group of d, or nil if d has no group.
+func (d *Descriptor) Group() *windows.SID {
+	return d.group
+}
+
+// SetGroup sets the primary group of d to a copy of sid. A nil sid removes
+// the group.
+func (d *Descriptor) SetGroup(sid *windows.SID) {
+	d.group = nil
+	if sid != nil {
+		d.group = cloneSID(sid)
+	}
+	d.control &^= windows.SE_GROUP_DEFAULTED
+}
+
+// DACL returns the discretionary access control list of d. It returns nil
+// if d has no DACL, or a NULL DACL granting full access to everyone, which
+// HasDACL tells apart.
+func (d *Descriptor) DACL() *ACL {
+	return d.dacl
+}
+
+// HasDACL reports whether d has a DACL, which may be a NULL DACL.
+func (d *Descriptor) HasDACL() bool {
+	return d.control&windows.SE_DACL_PRESENT != 0
+}
+
+// SetDACL sets the DACL of d to acl, which is retained. A nil acl sets a
+// NULL DACL, granting full access to everyone.
+func (d *Descriptor) SetDACL(acl *ACL) {
+	d.dacl = acl
+	d.control |= windows.SE_DACL_PRESENT
+	d.control &^= windows.SE_DACL_DEFAULTED
+}
+
+// RemoveDACL removes the DACL of d.
+func (d *Descriptor) RemoveDACL() {
+	d.dacl = nil
+	d.control &^= windows.SE_DACL_PRESENT | windows.SE_DACL_DEFAULTED
+}
+
+// SACL returns the system access control list of d, holding its audit
+// entries and mandatory label, or nil if d has none.
+func (d *Descriptor) SACL() *ACL {
+	return d.sacl
+}
+
+// HasSACL reports whether d has a SACL, which may be a NULL SACL.
+func (d *Descriptor) HasSACL() bool {
+	return d.control&windows.SE_SACL_PRESENT != 0
+}
+
+// SetSACL sets the SACL of d to acl, which is retained.
+func (d *Descriptor) SetSACL(acl *ACL) {
+	d.sacl = acl
+	d.control |= windows.SE_SACL_PRESENT
+	d.control &^= windows.SE_SACL_DEFAULTED
+}
+
+// RemoveSACL removes the SACL of d.
+func (d *Descriptor) RemoveSACL() {
+	d.sacl = nil
+	d.control &^= windows.SE_SACL_PRESENT | windows.SE_SACL_DEFAULTED
+}
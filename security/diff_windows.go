@@ -0,0 +1,191 @@
+package security
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/sys/windows"
+)
+
+// comparedControl are the control flags compared by Diff, the others
+// describing how descriptors were built rather than what they grant.
+const comparedControl = windows.SE_DACL_PRESENT | windows.SE_SACL_PRESENT |
+	windows.SE_DACL_PROTECTED | windows.SE_SACL_PROTECTED
+
+// DescriptorDiff holds the differences between two descriptors. The fields
+// of sections which are equal are nil.
+type DescriptorDiff struct {
+	Owner   *SIDChange
+	Group   *SIDChange
+	Control *ControlChange
+	DACL    *ACLDiff
+	SACL    *ACLDiff
+}
+
+// SIDChange is a change of the owner or group of a descriptor. Old or New
+// is nil if the descriptor had no owner or group.
+type SIDChange struct {
+	Old, New *windows.SID
+}
+
+// ControlChange is a change of the presence or protection of the access
+// control lists of a descriptor.
+type ControlChange struct {
+	Old, New windows.SECURITY_DESCRIPTOR_CONTROL
+}
+
+// ACLDiff holds the differences between two access control lists.
+type ACLDiff struct {
+	// Removed are the entries of the first list missing from the second,
+	// and Added those of the second list missing from the first, both in
+	// the order of their list.
+	Removed []ACE
+	Added   []ACE
+	// Reordered is set if the lists hold the same entries in a different
+	// order, which changes the access they grant.
+	Reordered bool
+	// OldNull and NewNull are set if the first or the second list is nil,
+	// which for a DACL present in its descriptor is a NULL DACL granting
+	// full access to everyone, unlike an empty DACL granting none. They
+	// are never both set.
+	OldNull, NewNull bool
+}
+
+// Diff returns the differences between a and b, or nil if they are the
+// same: same owner and group, same entries in the same order in their
+// DACLs and SACLs, and same protection of those lists.
+func Diff(a, b *Descriptor) *DescriptorDiff {
+	var d DescriptorDiff
+	empty := true
+
+	if !equalSID(a.owner, b.owner) {
+		d.Owner, empty = &SIDChange{Old: a.owner, New: b.owner}, false
+	}
+	if !equalSID(a.group, b.group) {
+		d.Group, empty = &SIDChange{Old: a.group, New: b.group}, false
+	}
+	if a.control&comparedControl != b.control&comparedControl {
+		d.Control = &ControlChange{Old: a.control & comparedControl, New: b.control & comparedControl}
+		empty = false
+	}
+	if d.DACL = diffACL(a.dacl, b.dacl); d.DACL != nil {
+		empty = false
+	}
+	if d.SACL = diffACL(a.sacl, b.sacl); d.SACL != nil {
+		empty = false
+	}
+
+	if empty {
+		return nil
+	}
+
+	return &d
+}
+
+// diffACL returns the differences between a and b, or nil if both are nil
+// or both hold the same entries in the same order.
+func diffACL(a, b *ACL) *ACLDiff {
+	if equalEntries(a, b) {
+		return nil
+	}
+
+	d := ACLDiff{OldNull: a == nil, NewNull: b == nil}
+	matched := make([]bool, b.Len())
+	for i := range a.Len() {
+		e := &a.Entries[i]
+		found := false
+		for j := range b.Len() {
+			if !matched[j] && e.Equal(&b.Entries[j]) {
+				matched[j], found = true, true
+				break
+			}
+		}
+		if !found {
+			d.Removed = append(d.Removed, *e)
+		}
+	}
+	for j, ok := range matched {
+		if !ok {
+			d.Added = append(d.Added, b.Entries[j])
+		}
+	}
+	d.Reordered = !d.OldNull && !d.NewNull && len(d.Removed) == 0 && len(d.Added) == 0
+
+	return &d
+}
+
+// String returns a line per difference of d, naming accounts by SID.
+func (d *DescriptorDiff) String() string {
+	if d == nil {
+		return ""
+	}
+
+	var b strings.Builder
+	for _, c := range []struct {
+		name   string
+		change *SIDChange
+	}{{"owner", d.Owner}, {"group", d.Group}} {
+		if c.change != nil {
+			fmt.Fprintf(&b, "%s: %s -> %s\n", c.name, sidString(c.change.Old), sidString(c.change.New))
+		}
+	}
+	if d.Control != nil {
+		fmt.Fprintf(&b, "control: %#04x -> %#04x\n", d.Control.Old, d.Control.New)
+	}
+	for _, c := range []struct {
+		name string
+		diff *ACLDiff
+	}{{"dacl", d.DACL}, {"sacl", d.SACL}} {
+		if c.diff == nil {
+			continue
+		}
+		switch {
+		case c.diff.OldNull:
+			fmt.Fprintf(&b, "%s: null -> list\n", c.name)
+		case c.diff.NewNull:
+			fmt.Fprintf(&b, "%s: list -> null\n", c.name)
+		}
+		for _, e := range c.diff.Removed {
+			fmt.Fprintf(&b, "%s: - %s\n", c.name, e.String())
+		}
+		for _, e := range c.diff.Added {
+			fmt.Fprintf(&b, "%s: + %s\n", c.name, e.String())
+		}
+		if c.diff.Reordered {
+			fmt.Fprintf(&b, "%s: entries reordered\n", c.name)
+		}
+	}
+
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func sidString(sid *windows.SID) string {
+	if sid == nil {
+		return "none"
+	}
+
+	return sid.String()
+}
+
+// String returns a short description of e, similar to the entries of
+// SDDL but with numeric fields.
+func (e ACE) String() string {
+	if !e.Type.known() {
+		return fmt.Sprintf("(type %#x;flags %#x)", uint8(e.Type), uint8(e.Flags))
+	}
+
+	s := fmt.Sprintf("(type %#x;flags %#x;mask %#x;%s", uint8(e.Type), uint8(e.Flags), uint32(e.Mask), sidString(e.SID))
+	if e.ObjectType != nil {
+		s += ";object " + e.ObjectType.String()
+	}
+	if e.InheritedObjectType != nil {
+		s += ";inherited object " + e.InheritedObjectType.String()
+	}
+	if e.IsConditional() {
+		if c, err := e.Condition(); err == nil {
+			s += ";" + c
+		}
+	}
+
+	return s + ")"
+}
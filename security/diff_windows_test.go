@@ -0,0 +1,113 @@
+package security
+
+import (
+	"testing"
+
+	"golang.org/x/sys/windows"
+)
+
+func TestDiffNullDACL(t *testing.T) {
+	everyone := mustSID(t, "S-1-1-0")
+	withDACL := func(acl *ACL) *Descriptor {
+		d := new(Descriptor)
+		d.SetDACL(acl)
+		return d
+	}
+
+	tests := []struct {
+		name             string
+		a, b             *ACL
+		oldNull, newNull bool
+		added, removed   int
+	}{
+		{name: "null empty", a: nil, b: NewACL(), oldNull: true},
+		{name: "empty null", a: NewACL(), b: nil, newNull: true},
+		{name: "null one", a: nil, b: NewACL(Allow(everyone, GenericRead, 0)), oldNull: true, added: 1},
+		{name: "one null", a: NewACL(Allow(everyone, GenericRead, 0)), b: nil, newNull: true, removed: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := Diff(withDACL(tt.a), withDACL(tt.b))
+			if d == nil || d.DACL == nil {
+				t.Fatalf("Diff = %v, want a DACL difference", d)
+			}
+			if d.Control != nil {
+				t.Errorf("Control = %v, want nil", d.Control)
+			}
+			got := d.DACL
+			if got.OldNull != tt.oldNull || got.NewNull != tt.newNull {
+				t.Errorf("OldNull, NewNull = %v, %v, want %v, %v", got.OldNull, got.NewNull, tt.oldNull, tt.newNull)
+			}
+			if len(got.Added) != tt.added || len(got.Removed) != tt.removed {
+				t.Errorf("%d added, %d removed, want %d, %d", len(got.Added), len(got.Removed), tt.added, tt.removed)
+			}
+			if got.Reordered {
+				t.Error("Reordered is set")
+			}
+		})
+	}
+
+	if d := Diff(withDACL(nil), withDACL(nil)); d != nil {
+		t.Errorf("Diff of NULL DACLs = %v, want nil", d)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	var (
+		everyone = mustSID(t, "S-1-1-0")
+		users    = mustSID(t, "S-1-5-32-545")
+		admins   = mustSID(t, "S-1-5-32-544")
+	)
+	read := Allow(everyone, FileGenericRead, 0)
+	write := Allow(users, FileGenericWrite, 0)
+	deny := Deny(users, Delete, 0)
+
+	build := func(owner *windows.SID, dacl ...ACE) *Descriptor {
+		d := new(Descriptor)
+		d.SetOwner(owner)
+		d.SetDACL(NewACL(dacl...))
+		return d
+	}
+
+	if d := Diff(build(admins, read, write), build(admins, read, write)); d != nil {
+		t.Errorf("Diff of equal descriptors = %v", d)
+	}
+
+	d := Diff(build(admins, read, write), build(users, deny, read))
+	if d == nil {
+		t.Fatal("Diff = nil")
+	}
+	if d.Owner == nil || !equalSID(d.Owner.Old, admins) || !equalSID(d.Owner.New, users) {
+		t.Errorf("Owner = %v", d.Owner)
+	}
+	if d.Group != nil || d.Control != nil || d.SACL != nil {
+		t.Errorf("Group, Control, SACL = %v, %v, %v, want nil", d.Group, d.Control, d.SACL)
+	}
+	if got := d.DACL; got == nil || !equalEntries(NewACL(got.Removed...), NewACL(write)) ||
+		!equalEntries(NewACL(got.Added...), NewACL(deny)) || got.Reordered {
+		t.Errorf("DACL = %+v", got)
+	}
+
+	d = Diff(build(admins, read, write), build(admins, write, read))
+	if d == nil || d.DACL == nil || !d.DACL.Reordered || d.DACL.Removed != nil || d.DACL.Added != nil {
+		t.Errorf("Diff of reordered DACLs = %+v", d)
+	}
+
+	protected := build(admins, read)
+	protected.SetControl(windows.SE_DACL_PROTECTED, windows.SE_DACL_PROTECTED)
+	d = Diff(build(admins, read), protected)
+	if d == nil || d.Control == nil || d.Control.New&windows.SE_DACL_PROTECTED == 0 || d.DACL != nil {
+		t.Errorf("Diff of a protected DACL = %+v", d)
+	}
+}
+
+func TestDiffACLDuplicates(t *testing.T) {
+	everyone := mustSID(t, "S-1-1-0")
+	read := Allow(everyone, FileGenericRead, 0)
+
+	d := diffACL(NewACL(read, read), NewACL(read))
+	if d == nil || len(d.Removed) != 1 || d.Added != nil || d.Reordered {
+		t.Errorf("diffACL = %+v, want one removed entry", d)
+	}
+}
@@ -0,0 +1,4 @@
+// Package security edits the security descriptors of files: owners,
+// groups and access control lists, in their SDDL, binary self-relative
+// and Windows API forms.
+package security
@@ -0,0 +1,153 @@
+package security
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/windows"
+
+	"github.com/go-sw/ntfs/internal/fsctl"
+)
+
+// ErrMissingSection is returned when writing a section which the
+// descriptor does not have.
+var ErrMissingSection = errors.New("security: descriptor lacks a section to write")
+
+// Get reads the sections of the security descriptor of the file or
+// directory at path selected by sections. Reading the SACL requires
+// SeSecurityPrivilege to be enabled.
+func Get(path string, sections Sections) (*Descriptor, error) {
+	sd, err := windows.GetNamedSecurityInfo(path, windows.SE_FILE_OBJECT, sections)
+	if err != nil {
+		return nil, &os.PathError{Op: "getsecurity", Path: path, Err: err}
+	}
+
+	return FromWindows(sd)
+}
+
+// GetHandle is like Get for an open file, whose handle must have been
+// opened with READ_CONTROL access, and ACCESS_SYSTEM_SECURITY to read the
+// SACL.
+func GetHandle(h windows.Handle, sections Sections) (*Descriptor, error) {
+	sd, err := windows.GetSecurityInfo(h, windows.SE_FILE_OBJECT, sections)
+	if err != nil {
+		return nil, err
+	}
+
+	return FromWindows(sd)
+}
+
+// getLink is like Get, but reads the descriptor of a reparse point itself
+// rather than that of its target.
+func getLink(path string, sections Sections) (*Descriptor, error) {
+	access := uint32(windows.READ_CONTROL)
+	if sections&windows.SACL_SECURITY_INFORMATION != 0 {
+		access |= windows.ACCESS_SYSTEM_SECURITY
+	}
+	h, err := fsctl.Open(path, access, windows.FILE_FLAG_OPEN_REPARSE_POINT)
+	if err != nil {
+		return nil, err
+	}
+	defer windows.CloseHandle(h)
+
+	d, err := GetHandle(h, sections)
+	if err != nil {
+		return nil, &os.PathError{Op: "getsecurity", Path: path, Err: err}
+	}
+
+	return d, nil
+}
+
+// Set writes the sections of d selected by sections to the file or
+// directory at path. The inheritable entries of a DACL or SACL written to a
+// directory are propagated to its existing children, and the protection of
+// the lists from inheritance follows the SE_DACL_PROTECTED and
+// SE_SACL_PROTECTED control flags of d.
+func Set(path string, sections Sections, d *Descriptor) error {
+	if !d.hasSections(sections) {
+		return &os.PathError{Op: "setsecurity", Path: path, Err: ErrMissingSection}
+	}
+
+	owner, group, dacl, sacl, err := d.absolute()
+	if err != nil {
+		return &os.PathError{Op: "setsecurity", Path: path, Err: err}
+	}
+
+	err = windows.SetNamedSecurityInfo(path, windows.SE_FILE_OBJECT, d.protection(sections), owner, group, dacl, sacl)
+	if err != nil {
+		return &os.PathError{Op: "setsecurity", Path: path, Err: err}
+	}
+
+	return nil
+}
+
+// SetHandle writes the sections of d selected by sections to an open file
+// as is, without computing inherited entries nor propagating them to
+// children. The handle must have been opened with the WRITE_DAC,
+// WRITE_OWNER or ACCESS_SYSTEM_SECURITY access matching sections.
+func SetHandle(h windows.Handle, sections Sections, d *Descriptor) error {
+	if !d.hasSections(sections) {
+		return ErrMissingSection
+	}
+
+	sd, err := d.Windows()
+	if err != nil {
+		return err
+	}
+
+	return windows.SetKernelObjectSecurity(h, sections, sd)
+}
+
+// absolute returns the owner, group and lists of d in the form expected by
+// SetNamedSecurityInfo. The pointers reference memory owned by the Go
+// runtime.
+func (d *Descriptor) absolute() (owner, group *windows.SID, dacl, sacl *windows.ACL, err error) {
+	sd, err := d.Windows()
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	if sd, err = sd.ToAbsolute(); err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	owner, _, _ = sd.Owner()
+	group, _, _ = sd.Group()
+	if d.HasDACL() {
+		dacl, _, _ = sd.DACL()
+	}
+	if d.HasSACL() {
+		sacl, _, _ = sd.SACL()
+	}
+
+	return owner, group, dacl, sacl, nil
+}
+
+// protection adds to sections the flags protecting or unprotecting the
+// lists of d it selects.
+func (d *Descriptor) protection(sections Sections) Sections {
+	if sections&windows.DACL_SECURITY_INFORMATION != 0 {
+		if d.control&windows.SE_DACL_PROTECTED != 0 {
+			sections |= windows.PROTECTED_DACL_SECURITY_INFORMATION
+		} else {
+			sections |= windows.UNPROTECTED_DACL_SECURITY_INFORMATION
+		}
+	}
+	if sections&windows.SACL_SECURITY_INFORMATION != 0 {
+		if d.control&windows.SE_SACL_PROTECTED != 0 {
+			sections |= windows.PROTECTED_SACL_SECURITY_INFORMATION
+		} else {
+			sections |= windows.UNPROTECTED_SACL_SECURITY_INFORMATION
+		}
+	}
+
+	return sections
+}
+
+// hasSections reports whether d has the owner, group and lists selected by
+// sections, so that writing them does not clear them by accident.
+func (d *Descriptor) hasSections(sections Sections) bool {
+	return (sections&windows.OWNER_SECURITY_INFORMATION == 0 || d.owner != nil) &&
+		(sections&windows.GROUP_SECURITY_INFORMATION == 0 || d.group != nil) &&
+		(sections&windows.DACL_SECURITY_INFORMATION == 0 || d.HasDACL()) &&
+		(sections&windows.SACL_SECURITY_INFORMATION == 0 || d.HasSACL())
+}
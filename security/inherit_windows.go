@@ -0,0 +1,282 @@
+package security
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"golang.org/x/sys/windows"
+
+	"github.com/go-sw/ntfs/internal/fsctl"
+)
+
+// PropagateOptions control PropagateInheritance.
+type PropagateOptions struct {
+	// DryRun computes and reports the changes without writing them.
+	DryRun bool
+	// Reset removes the explicit entries and the protection of the DACLs
+	// of the descendants, leaving them with the inherited entries alone,
+	// like icacls /reset.
+	Reset bool
+	// Progress, if not nil, is called for every descendant visited, after
+	// its DACL was written. If it returns filepath.SkipDir for a
+	// directory, its children are skipped, and if it returns any other
+	// error, PropagateInheritance stops and returns it, unless it is
+	// filepath.SkipAll. Errors met on a descendant are reported in
+	// Change.Err; if Progress is nil, they stop PropagateInheritance.
+	Progress func(Change) error
+}
+
+// Change describes the DACL update of a descendant by PropagateInheritance.
+type Change struct {
+	Path      string
+	Directory bool
+	// Before and After are the DACLs of the file before and after the
+	// update, which are equal if Changed is not set.
+	Before, After *ACL
+	Changed       bool
+	// Err is set if the DACL of the file could not be read or written.
+	Err error
+}
+
+// PropagateStats counts the descendants handled by PropagateInheritance.
+type PropagateStats struct {
+	Visited int
+	Changed int
+	Failed  int
+}
+
+// PropagateInheritance recomputes the inherited entries of the DACLs of
+// all the descendants of the directory root from its current DACL, as
+// SetNamedSecurityInfo does when a DACL is written to a directory. It is
+// meant to repair trees after the DACL of root was changed without
+// propagation, or after files were moved into root.
+//
+// Each descendant keeps its explicit entries, unless opts.Reset is set, and
+// those whose DACL is protected are left alone along with their subtree.
+// Reparse points are updated but not followed. A nil opts is the same as
+// the zero PropagateOptions.
+func PropagateInheritance(root string, opts *PropagateOptions) (PropagateStats, error) {
+	if opts == nil {
+		opts = new(PropagateOptions)
+	}
+
+	var stats PropagateStats
+	d, err := Get(root, windows.DACL_SECURITY_INFORMATION)
+	if err != nil {
+		return stats, err
+	}
+
+	err = propagate(root, d.DACL(), opts, &stats)
+	if errors.Is(err, filepath.SkipAll) {
+		err = nil
+	}
+
+	return stats, err
+}
+
+// propagate updates the children of the directory dir, whose DACL is
+// parent.
+func propagate(dir string, parent *ACL, opts *PropagateOptions, stats *PropagateStats) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return report(Change{Path: dir, Directory: true, Err: err}, opts, stats)
+	}
+
+	for _, e := range entries {
+		path := filepath.Join(dir, e.Name())
+		c, recurse := updateChild(path, e.IsDir(), parent, opts)
+
+		err := report(c, opts, stats)
+		if errors.Is(err, filepath.SkipDir) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		// Reparse points such as junctions are not followed, so that
+		// files outside of the tree are not changed.
+		if recurse && e.Type()&(fs.ModeSymlink|fs.ModeIrregular) == 0 {
+			if err := propagate(path, c.After, opts, stats); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// report counts c and hands it to opts.Progress.
+func report(c Change, opts *PropagateOptions, stats *PropagateStats) error {
+	stats.Visited++
+	switch {
+	case c.Err != nil:
+		stats.Failed++
+	case c.Changed:
+		stats.Changed++
+	}
+
+	if opts.Progress != nil {
+		return opts.Progress(c)
+	}
+
+	return c.Err
+}
+
+// updateChild recomputes the DACL of the child at path of a directory
+// whose DACL is parent. It reports whether the children of path must be
+// updated as well.
+func updateChild(path string, dir bool, parent *ACL, opts *PropagateOptions) (Change, bool) {
+	c := Change{Path: path, Directory: dir}
+
+	access := uint32(windows.READ_CONTROL)
+	if !opts.DryRun {
+		access |= windows.WRITE_DAC
+	}
+	h, err := fsctl.Open(path, access, windows.FILE_FLAG_OPEN_REPARSE_POINT)
+	if err != nil {
+		c.Err = err
+		return c, false
+	}
+	defer windows.CloseHandle(h)
+
+	d, err := GetHandle(h, windows.OWNER_SECURITY_INFORMATION|windows.GROUP_SECURITY_INFORMATION|
+		windows.DACL_SECURITY_INFORMATION)
+	if err != nil {
+		c.Err = &os.PathError{Op: "getsecurity", Path: path, Err: err}
+		return c, false
+	}
+
+	c.Before, c.After = d.DACL(), d.DACL()
+	protected := d.control&windows.SE_DACL_PROTECTED != 0
+	if protected && !opts.Reset {
+		return c, false
+	}
+
+	after := NewACL()
+	if !opts.Reset && c.Before != nil {
+		for _, e := range c.Before.Entries {
+			if !e.IsInherited() {
+				after.Entries = append(after.Entries, e)
+			}
+		}
+	}
+	after.Entries = append(after.Entries, Inherit(parent, dir, d.owner, d.group).Entries...)
+	c.After = after
+
+	c.Changed = protected || !d.HasDACL() || !equalEntries(c.Before, after)
+	if !c.Changed || opts.DryRun {
+		return c, dir
+	}
+
+	d.SetDACL(after)
+	d.control = d.control&^windows.SE_DACL_PROTECTED | windows.SE_DACL_AUTO_INHERITED
+	if err := SetHandle(h, windows.DACL_SECURITY_INFORMATION, d); err != nil {
+		c.Err = &os.PathError{Op: "setsecurity", Path: path, Err: err}
+		c.After = c.Before
+		return c, false
+	}
+
+	return c, dir
+}
+
+// equalEntries reports whether a and b hold the same entries in the same
+// order. A nil list only equals another nil list, as a NULL DACL grants
+// full access to everyone while an empty one grants none.
+func equalEntries(a, b *ACL) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	if len(a.Entries) != len(b.Entries) {
+		return false
+	}
+
+	return slices.EqualFunc(a.Entries, b.Entries, func(x, y ACE) bool { return x.Equal(&y) })
+}
+
+var (
+	creatorOwner = mustWellKnownSID(windows.WinCreatorOwnerSid)
+	creatorGroup = mustWellKnownSID(windows.WinCreatorGroupSid)
+)
+
+func mustWellKnownSID(t windows.WELL_KNOWN_SID_TYPE) *windows.SID {
+	sid, err := windows.CreateWellKnownSid(t)
+	if err != nil {
+		panic(err)
+	}
+
+	return sid
+}
+
+// Inherit returns the entries a file, or a directory if dir is set,
+// inherits from the DACL or SACL parent of its directory. Following the
+// rules of Windows, the CREATOR OWNER and CREATOR GROUP trustees are
+// replaced by owner and group, and the generic rights of the entries
+// which apply to the child are mapped to file rights, an inherit-only
+// copy of the original entry being kept for the children of dir.
+func Inherit(parent *ACL, dir bool, owner, group *windows.SID) *ACL {
+	acl := NewACL()
+	if parent == nil {
+		return acl
+	}
+
+	const inheritFlags = ObjectInherit | ContainerInherit | NoPropagateInherit | InheritOnly
+	for _, e := range parent.Entries {
+		var flags AceFlags
+		switch {
+		case !dir && e.Flags&ObjectInherit != 0:
+			flags = e.Flags &^ inheritFlags
+		case dir && e.Flags&ContainerInherit != 0:
+			flags = e.Flags &^ InheritOnly
+			if e.Flags&NoPropagateInherit != 0 {
+				flags &^= inheritFlags
+			}
+		case dir && e.Flags&ObjectInherit != 0 && e.Flags&NoPropagateInherit == 0:
+			// Only inherited for the files below dir.
+			flags = e.Flags | InheritOnly
+		default:
+			continue
+		}
+		flags |= Inherited
+
+		c := e.Clone()
+		c.Flags = flags
+		if flags&InheritOnly != 0 {
+			acl.Entries = append(acl.Entries, c)
+			continue
+		}
+
+		sid := c.SID
+		switch {
+		case equalSID(sid, creatorOwner) && owner != nil:
+			sid = owner
+		case equalSID(sid, creatorGroup) && group != nil:
+			sid = group
+		}
+		mask := c.Mask.Expand()
+		if sid == c.SID && mask == c.Mask {
+			acl.Entries = append(acl.Entries, c)
+			continue
+		}
+
+		// The entry is split into an effective entry for the child itself
+		// and an inherit-only one for its children.
+		var io *ACE
+		if flags&(ObjectInherit|ContainerInherit) != 0 {
+			e := c.Clone()
+			e.Flags |= InheritOnly
+			io = &e
+		}
+		c.SID, c.Mask = cloneSID(sid), mask
+		c.Flags &^= inheritFlags
+		acl.Entries = append(acl.Entries, c)
+		if io != nil {
+			acl.Entries = append(acl.Entries, *io)
+		}
+	}
+
+	return acl
+}
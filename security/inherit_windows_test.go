@@ -0,0 +1,107 @@
+package security
+
+import (
+	"testing"
+
+	"golang.org/x/sys/windows"
+)
+
+func mustSID(t *testing.T, s string) *windows.SID {
+	t.Helper()
+	sid, err := windows.StringToSid(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return sid
+}
+
+func TestEqualEntries(t *testing.T) {
+	everyone := mustSID(t, "S-1-1-0")
+	one := func() *ACL { return NewACL(Allow(everyone, GenericRead, 0)) }
+
+	tests := []struct {
+		name string
+		a, b *ACL
+		want bool
+	}{
+		{"null null", nil, nil, true},
+		{"null empty", nil, NewACL(), false},
+		{"empty null", NewACL(), nil, false},
+		{"null one", nil, one(), false},
+		{"empty empty", NewACL(), NewACL(), true},
+		{"empty one", NewACL(), one(), false},
+		{"one one", one(), one(), true},
+		{"different mask", one(), NewACL(Allow(everyone, GenericAll, 0)), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := equalEntries(tt.a, tt.b); got != tt.want {
+				t.Errorf("equalEntries = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInherit(t *testing.T) {
+	var (
+		everyone = mustSID(t, "S-1-1-0")
+		users    = mustSID(t, "S-1-5-32-545")
+		admins   = mustSID(t, "S-1-5-32-544")
+		owner    = mustSID(t, "S-1-5-21-1-2-3-1001")
+		group    = mustSID(t, "S-1-5-21-1-2-3-513")
+	)
+	const oici = ObjectInherit | ContainerInherit
+
+	parent := NewACL(
+		Allow(everyone, ReadAndExecute, oici),
+		Allow(creatorOwner, GenericAll, oici|InheritOnly),
+		Allow(creatorGroup, GenericRead, ObjectInherit|InheritOnly),
+		Deny(users, Delete, ContainerInherit),
+		Allow(admins, FileAllAccess, ObjectInherit|NoPropagateInherit),
+		Allow(users, FileTraverse, ContainerInherit|NoPropagateInherit),
+		Allow(admins, ReadAndExecute, 0),
+	)
+
+	tests := []struct {
+		name string
+		dir  bool
+		want *ACL
+	}{
+		{
+			name: "file",
+			want: NewACL(
+				Allow(everyone, ReadAndExecute, Inherited),
+				Allow(owner, FileAllAccess, Inherited),
+				Allow(group, FileGenericRead, Inherited),
+				Allow(admins, FileAllAccess, Inherited),
+			),
+		},
+		{
+			name: "directory",
+			dir:  true,
+			want: NewACL(
+				Allow(everyone, ReadAndExecute, oici|Inherited),
+				Allow(owner, FileAllAccess, Inherited),
+				Allow(creatorOwner, GenericAll, oici|InheritOnly|Inherited),
+				Allow(creatorGroup, GenericRead, ObjectInherit|InheritOnly|Inherited),
+				Deny(users, Delete, ContainerInherit|Inherited),
+				Allow(users, FileTraverse, Inherited),
+			),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Inherit(parent, tt.dir, owner, group)
+			if !equalEntries(got, tt.want) {
+				t.Errorf("Inherit =\n%v\nwant\n%v", got.Entries, tt.want.Entries)
+			}
+		})
+	}
+
+	if got := Inherit(nil, true, owner, group); got == nil || got.Len() != 0 {
+		t.Errorf("Inherit(nil) = %v, want an empty list", got)
+	}
+}
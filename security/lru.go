@@ -0,0 +1,45 @@
+package security
+
+import "container/list"
+
+// lru is a least recently used cache. It is not safe for concurrent use.
+type lru[K comparable, V any] struct {
+	size  int
+	order *list.List
+	items map[K]*list.Element
+}
+
+type lruEntry[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+func newLRU[K comparable, V any](size int) *lru[K, V] {
+	return &lru[K, V]{size: size, order: list.New(), items: make(map[K]*list.Element)}
+}
+
+func (c *lru[K, V]) get(key K) (V, bool) {
+	e, ok := c.items[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	c.order.MoveToFront(e)
+
+	return e.Value.(*lruEntry[K, V]).value, true
+}
+
+func (c *lru[K, V]) add(key K, value V) {
+	if e, ok := c.items[key]; ok {
+		e.Value.(*lruEntry[K, V]).value = value
+		c.order.MoveToFront(e)
+		return
+	}
+
+	c.items[key] = c.order.PushFront(&lruEntry[K, V]{key, value})
+	if c.order.Len() > c.size {
+		e := c.order.Back()
+		c.order.Remove(e)
+		delete(c.items, e.Value.(*lruEntry[K, V]).key)
+	}
+}
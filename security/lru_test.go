@@ -0,0 +1,33 @@
+package security
+
+import "testing"
+
+func TestLRU(t *testing.T) {
+	c := newLRU[string, int](2)
+	c.add("a", 1)
+	c.add("b", 2)
+
+	// Getting a makes b the least recently used entry.
+	if v, ok := c.get("a"); !ok || v != 1 {
+		t.Errorf("get(a) = %d, %v", v, ok)
+	}
+	c.add("c", 3)
+	if _, ok := c.get("b"); ok {
+		t.Error("b was not evicted")
+	}
+
+	// Updating a makes c the least recently used entry.
+	c.add("a", 10)
+	c.add("d", 4)
+	if _, ok := c.get("c"); ok {
+		t.Error("c was not evicted")
+	}
+	for k, want := range map[string]int{"a": 10, "d": 4} {
+		if v, ok := c.get(k); !ok || v != want {
+			t.Errorf("get(%s) = %d, %v, want %d", k, v, ok, want)
+		}
+	}
+	if c.order.Len() != 2 || len(c.items) != 2 {
+		t.Errorf("%d entries, %d items, want 2", c.order.Len(), len(c.items))
+	}
+}
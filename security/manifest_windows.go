@@ -0,0 +1,179 @@
+package security
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"golang.org/x/sys/windows"
+
+	"github.com/go-sw/ntfs/internal/fsctl"
+)
+
+// manifestSections are the sections captured by CaptureTree.
+const manifestSections = windows.OWNER_SECURITY_INFORMATION | windows.GROUP_SECURITY_INFORMATION |
+	windows.DACL_SECURITY_INFORMATION
+
+// Manifest maps the paths of the files of a tree, relative to its root,
+// to their security descriptors in SDDL form. The root itself is ".".
+type Manifest map[string]string
+
+// SIDMap maps the string form of SIDs to the string form of the SIDs
+// replacing them, such as the accounts of a domain to those of another.
+type SIDMap map[string]string
+
+// CaptureTree returns the owners, groups and DACLs of all the files of the
+// tree rooted at root, the root included. Reparse points are captured but
+// not followed.
+func CaptureTree(root string) (Manifest, error) {
+	m := make(Manifest)
+	err := filepath.WalkDir(root, func(path string, _ fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		d, err := getLink(path, manifestSections)
+		if err != nil {
+			return err
+		}
+		s, err := d.SDDL(manifestSections)
+		if err != nil {
+			return &os.PathError{Op: "getsecurity", Path: path, Err: err}
+		}
+		m[rel] = s
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// ApplyManifest writes the descriptors of m to the files of the tree
+// rooted at root, after replacing their SIDs found in sidMap, which may be
+// nil. The descriptors are written as captured, inherited entries
+// included, without propagating them. SeRestorePrivilege is enabled if
+// held, so that the original owners can be set.
+//
+// The files which cannot be updated, such as those missing from the tree,
+// do not stop ApplyManifest, which returns their errors joined.
+func ApplyManifest(m Manifest, root string, sidMap SIDMap) error {
+	paths := make([]string, 0, len(m))
+	for rel := range m {
+		paths = append(paths, rel)
+	}
+	slices.Sort(paths)
+
+	apply := func() error {
+		var errs []error
+		for _, rel := range paths {
+			if err := applyEntry(filepath.Join(root, rel), m[rel], sidMap); err != nil {
+				errs = append(errs, err)
+			}
+		}
+
+		return errors.Join(errs...)
+	}
+
+	err := WithPrivileges([]string{PrivilegeRestore, PrivilegeTakeOwnership}, apply)
+	var privErr *PrivilegeError
+	if errors.As(err, &privErr) {
+		return apply()
+	}
+
+	return err
+}
+
+func applyEntry(path, sddl string, sidMap SIDMap) error {
+	d, err := Parse(sddl)
+	if err != nil {
+		return &os.PathError{Op: "setsecurity", Path: path, Err: err}
+	}
+	if err := d.MapSIDs(sidMap); err != nil {
+		return &os.PathError{Op: "setsecurity", Path: path, Err: err}
+	}
+
+	var (
+		sections Sections
+		access   uint32
+	)
+	if d.owner != nil {
+		sections |= windows.OWNER_SECURITY_INFORMATION
+		access |= windows.WRITE_OWNER
+	}
+	if d.group != nil {
+		sections |= windows.GROUP_SECURITY_INFORMATION
+		access |= windows.WRITE_OWNER
+	}
+	if d.HasDACL() {
+		sections |= windows.DACL_SECURITY_INFORMATION
+		access |= windows.WRITE_DAC
+	}
+	if sections == 0 {
+		return nil
+	}
+
+	h, err := fsctl.Open(path, access, windows.FILE_FLAG_OPEN_REPARSE_POINT)
+	if err != nil {
+		return err
+	}
+	defer windows.CloseHandle(h)
+
+	if err := SetHandle(h, sections, d); err != nil {
+		return &os.PathError{Op: "setsecurity", Path: path, Err: err}
+	}
+
+	return nil
+}
+
+// MapSIDs replaces the SIDs of the owner, group and access control entries
+// of d found in m. The SIDs referenced by the conditional expressions of
+// entries are left alone.
+func (d *Descriptor) MapSIDs(m SIDMap) error {
+	if len(m) == 0 {
+		return nil
+	}
+
+	sids := make(map[string]*windows.SID, len(m))
+	for from, to := range m {
+		src, err := windows.StringToSid(from)
+		if err != nil {
+			return err
+		}
+		sid, err := windows.StringToSid(to)
+		if err != nil {
+			return err
+		}
+		sids[src.String()] = sid
+	}
+	mapSID := func(sid *windows.SID) *windows.SID {
+		if sid == nil {
+			return nil
+		}
+		if to, ok := sids[sid.String()]; ok {
+			return cloneSID(to)
+		}
+		return sid
+	}
+
+	d.owner = mapSID(d.owner)
+	d.group = mapSID(d.group)
+	for _, acl := range []*ACL{d.dacl, d.sacl} {
+		if acl == nil {
+			continue
+		}
+		for i := range acl.Entries {
+			acl.Entries[i].SID = mapSID(acl.Entries[i].SID)
+		}
+	}
+
+	return nil
+}
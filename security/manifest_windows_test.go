@@ -0,0 +1,53 @@
+package security
+
+import "testing"
+
+func TestMapSIDs(t *testing.T) {
+	var (
+		oldUser = mustSID(t, "S-1-5-21-1-2-3-1001")
+		newUser = mustSID(t, "S-1-5-21-4-5-6-1001")
+		group   = mustSID(t, "S-1-5-32-545")
+		admins  = mustSID(t, "S-1-5-32-544")
+	)
+
+	d := new(Descriptor)
+	d.SetOwner(oldUser)
+	d.SetGroup(group)
+	d.SetDACL(NewACL(Allow(oldUser, FullControl, 0), Allow(admins, FullControl, Inherited)))
+	d.SetSACL(NewACL(Audit(oldUser, Delete, FailedAccess)))
+
+	if err := d.MapSIDs(SIDMap{oldUser.String(): newUser.String()}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !equalSID(d.Owner(), newUser) || !equalSID(d.Group(), group) {
+		t.Errorf("owner, group = %v, %v, want %v, %v", d.Owner(), d.Group(), newUser, group)
+	}
+	wantDACL := NewACL(Allow(newUser, FullControl, 0), Allow(admins, FullControl, Inherited))
+	if !equalEntries(d.DACL(), wantDACL) {
+		t.Errorf("DACL = %v, want %v", d.DACL().Entries, wantDACL.Entries)
+	}
+	wantSACL := NewACL(Audit(newUser, Delete, FailedAccess))
+	if !equalEntries(d.SACL(), wantSACL) {
+		t.Errorf("SACL = %v, want %v", d.SACL().Entries, wantSACL.Entries)
+	}
+}
+
+func TestMapSIDsNullDACL(t *testing.T) {
+	d := new(Descriptor)
+	d.SetDACL(nil)
+	if err := d.MapSIDs(SIDMap{"S-1-1-0": "S-1-5-32-545"}); err != nil {
+		t.Fatal(err)
+	}
+	if !d.HasDACL() || d.DACL() != nil {
+		t.Error("the NULL DACL was not kept")
+	}
+}
+
+func TestMapSIDsInvalid(t *testing.T) {
+	for _, m := range []SIDMap{{"bogus": "S-1-1-0"}, {"S-1-1-0": "bogus"}} {
+		if err := new(Descriptor).MapSIDs(m); err == nil {
+			t.Errorf("MapSIDs(%v) succeeded", m)
+		}
+	}
+}
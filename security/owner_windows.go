@@ -0,0 +1,74 @@
+package security
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/windows"
+
+	"github.com/go-sw/ntfs/internal/fsctl"
+)
+
+// ChangeOwner sets the owner of the file or directory at path to sid,
+// which may be any SID. If the caller is denied WRITE_OWNER on the file,
+// or sid is neither the caller nor one of its groups, the change is
+// retried with SeTakeOwnershipPrivilege and SeRestorePrivilege enabled,
+// as restoring the original owners of files requires.
+func ChangeOwner(path string, sid *windows.SID) error {
+	return changeSID(path, windows.OWNER_SECURITY_INFORMATION, sid)
+}
+
+// ChangeGroup sets the primary group of the file or directory at path to
+// sid, enabling privileges as needed like ChangeOwner.
+func ChangeGroup(path string, sid *windows.SID) error {
+	return changeSID(path, windows.GROUP_SECURITY_INFORMATION, sid)
+}
+
+func changeSID(path string, section Sections, sid *windows.SID) error {
+	if sid == nil || !sid.IsValid() {
+		return &os.PathError{Op: "setsecurity", Path: path, Err: errInvalidSID}
+	}
+
+	err := setSID(path, section, sid)
+	if !errors.Is(err, windows.ERROR_ACCESS_DENIED) && !errors.Is(err, windows.ERROR_INVALID_OWNER) {
+		return err
+	}
+
+	privileges := []string{PrivilegeTakeOwnership, PrivilegeRestore}
+	perr := WithPrivileges(privileges, func() error {
+		return setSID(path, section, sid)
+	})
+
+	var privErr *PrivilegeError
+	if errors.As(perr, &privErr) {
+		// Report the original error, as the privileges may not
+		// have been needed at all.
+		return err
+	}
+
+	return perr
+}
+
+// setSID writes the owner or group of the file at path through a handle
+// opened with backup semantics, so that the restore privilege applies.
+func setSID(path string, section Sections, sid *windows.SID) error {
+	h, err := fsctl.Open(path, windows.WRITE_OWNER, windows.FILE_FLAG_OPEN_REPARSE_POINT)
+	if err != nil {
+		return err
+	}
+	defer windows.CloseHandle(h)
+
+	var owner, group *windows.SID
+	if section == windows.OWNER_SECURITY_INFORMATION {
+		owner = sid
+	} else {
+		group = sid
+	}
+
+	err = windows.SetSecurityInfo(h, windows.SE_FILE_OBJECT, section, owner, group, nil, nil)
+	if err != nil {
+		return &os.PathError{Op: "setsecurity", Path: path, Err: err}
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package security
+
+import (
+	"encoding/binary"
+	"fmt"
+	"runtime"
+	"strings"
+
+	"golang.org/x/sys/windows"
+)
+
+// Privileges used to read and write security descriptors regardless of
+// their access control lists.
+const (
+	// PrivilegeTakeOwnership grants WRITE_OWNER on any file, allowing to
+	// set its owner to the caller or one of its groups.
+	PrivilegeTakeOwnership = "SeTakeOwnershipPrivilege"
+	// PrivilegeRestore grants write access to any file opened with backup
+	// semantics, and allows to set any SID as its owner.
+	PrivilegeRestore = "SeRestorePrivilege"
+	// PrivilegeBackup grants read access to any file opened with backup
+	// semantics.
+	PrivilegeBackup = "SeBackupPrivilege"
+	// PrivilegeSecurity grants ACCESS_SYSTEM_SECURITY, which reading and
+	// writing SACLs requires.
+	PrivilegeSecurity = "SeSecurityPrivilege"
+)
+
+// PrivilegeError is returned when privileges are not held by the caller.
+type PrivilegeError struct {
+	Privileges []string
+}
+
+func (e *PrivilegeError) Error() string {
+	return fmt.Sprintf("security: privileges not held: %s", strings.Join(e.Privileges, ", "))
+}
+
+// WithPrivileges runs fn with privileges enabled. They are only enabled on
+// the OS thread running fn, which impersonates the process for the time
+// of the call, so that other goroutines are not granted them.
+func WithPrivileges(privileges []string, fn func() error) error {
+	luids := make([]windows.LUID, len(privileges))
+	for i, name := range privileges {
+		p, err := windows.UTF16PtrFromString(name)
+		if err != nil {
+			return err
+		}
+		if err := windows.LookupPrivilegeValue(nil, p, &luids[i]); err != nil {
+			return fmt.Errorf("security: lookup privilege %s: %w", name, err)
+		}
+	}
+
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	if err := windows.ImpersonateSelf(windows.SecurityImpersonation); err != nil {
+		return err
+	}
+	defer windows.RevertToSelf()
+
+	thread, _ := windows.GetCurrentThread()
+	var token windows.Token
+	err := windows.OpenThreadToken(thread, windows.TOKEN_ADJUST_PRIVILEGES|windows.TOKEN_QUERY, false, &token)
+	if err != nil {
+		return err
+	}
+	defer token.Close()
+
+	if err := enablePrivileges(token, luids); err != nil {
+		if err == windows.ERROR_NOT_ALL_ASSIGNED {
+			return &PrivilegeError{Privileges: privileges}
+		}
+		return err
+	}
+
+	return fn()
+}
+
+// enablePrivileges enables the privileges luids in token.
+func enablePrivileges(token windows.Token, luids []windows.LUID) error {
+	// TOKEN_PRIVILEGES holds a count followed by LUID_AND_ATTRIBUTES.
+	b := make([]byte, 4, 4+12*len(luids))
+	binary.LittleEndian.PutUint32(b, uint32(len(luids)))
+	for _, luid := range luids {
+		b = binary.LittleEndian.AppendUint32(b, luid.LowPart)
+		b = binary.LittleEndian.AppendUint32(b, uint32(luid.HighPart))
+		b = binary.LittleEndian.AppendUint32(b, windows.SE_PRIVILEGE_ENABLED)
+	}
+
+	// AdjustTokenPrivileges succeeds when some privileges are not held,
+	// which the last error tells.
+	ok, err := adjustTokenPrivileges(token, false, &b[0], 0, nil, nil)
+	if !ok {
+		return err
+	}
+	if err == windows.ERROR_NOT_ALL_ASSIGNED {
+		return err
+	}
+
+	return nil
+}
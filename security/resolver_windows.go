@@ -0,0 +1,205 @@
+package security
+
+import (
+	"errors"
+	"strings"
+	"sync"
+
+	"golang.org/x/sys/windows"
+)
+
+// DefaultCacheSize is the number of SIDs and names cached by a Resolver
+// unless set otherwise.
+const DefaultCacheSize = 4096
+
+// Account is a user, group or other security principal known by name.
+type Account struct {
+	Domain string
+	Name   string
+	// Type is the SID_NAME_USE of the account, such as
+	// windows.SidTypeUser, or zero for accounts of the offline table.
+	Type uint32
+}
+
+// String returns the name of a in the DOMAIN\name form, or name alone if
+// a has no domain.
+func (a Account) String() string {
+	if a.Domain == "" {
+		return a.Name
+	}
+
+	return a.Domain + `\` + a.Name
+}
+
+// ParseAccount parses an account name in the DOMAIN\name form, or a bare
+// name.
+func ParseAccount(s string) Account {
+	if domain, name, ok := strings.Cut(s, `\`); ok {
+		return Account{Domain: domain, Name: name}
+	}
+
+	return Account{Name: s}
+}
+
+// ResolverOptions control a Resolver.
+type ResolverOptions struct {
+	// System is the name of the computer on which accounts are looked up;
+	// the local computer if empty.
+	System string
+	// CacheSize is the number of entries kept in each direction, or
+	// DefaultCacheSize if zero.
+	CacheSize int
+	// Mappings is the offline mapping table from the string form of SIDs
+	// to account names, in the DOMAIN\name form. It takes precedence
+	// over the lookups, and resolves the SIDs of accounts unknown to
+	// System, such as those of another domain or computer.
+	Mappings map[string]string
+	// Offline disables the lookups, resolving from Mappings alone.
+	Offline bool
+}
+
+// Resolver translates between SIDs and account names, caching the results
+// of LookupAccountSid and LookupAccountName, failures included, as audits
+// of whole trees look up the same few accounts over and over. It is safe
+// for concurrent use.
+type Resolver struct {
+	system  string
+	offline bool
+
+	// The offline table and the caches are indexed by the string form of
+	// SIDs and the upper case names of accounts.
+	mappedSIDs  map[string]Account
+	mappedNames map[string]string
+
+	mu     sync.Mutex
+	bySID  *lru[string, resolvedAccount]
+	byName *lru[string, resolvedSID]
+}
+
+type resolvedAccount struct {
+	account Account
+	err     error
+}
+
+type resolvedSID struct {
+	sid *windows.SID
+	err error
+}
+
+// NewResolver returns a resolver configured by opts. A nil opts is the
+// same as the zero ResolverOptions.
+func NewResolver(opts *ResolverOptions) (*Resolver, error) {
+	if opts == nil {
+		opts = new(ResolverOptions)
+	}
+
+	size := opts.CacheSize
+	if size <= 0 {
+		size = DefaultCacheSize
+	}
+
+	r := &Resolver{
+		system:      opts.System,
+		offline:     opts.Offline,
+		mappedSIDs:  make(map[string]Account, len(opts.Mappings)),
+		mappedNames: make(map[string]string, len(opts.Mappings)),
+		bySID:       newLRU[string, resolvedAccount](size),
+		byName:      newLRU[string, resolvedSID](size),
+	}
+	for s, name := range opts.Mappings {
+		sid, err := windows.StringToSid(s)
+		if err != nil {
+			return nil, err
+		}
+		s = sid.String()
+		r.mappedSIDs[s] = ParseAccount(name)
+		r.mappedNames[nameKey(name)] = s
+	}
+
+	return r, nil
+}
+
+func nameKey(name string) string {
+	return strings.ToUpper(name)
+}
+
+// Account returns the account of sid.
+func (r *Resolver) Account(sid *windows.SID) (Account, error) {
+	key := sid.String()
+	if a, ok := r.mappedSIDs[key]; ok {
+		return a, nil
+	}
+	if r.offline {
+		return Account{}, windows.ERROR_NONE_MAPPED
+	}
+
+	r.mu.Lock()
+	res, ok := r.bySID.get(key)
+	r.mu.Unlock()
+	if ok {
+		return res.account, res.err
+	}
+
+	name, domain, typ, err := sid.LookupAccount(r.system)
+	res = resolvedAccount{Account{Domain: domain, Name: name, Type: typ}, err}
+	if err != nil && !errors.Is(err, windows.ERROR_NONE_MAPPED) {
+		// Transient failures, such as an unreachable domain
+		// controller, are not cached.
+		return Account{}, err
+	}
+
+	r.mu.Lock()
+	r.bySID.add(key, res)
+	r.mu.Unlock()
+
+	return res.account, res.err
+}
+
+// Name returns the name of the account of sid in the DOMAIN\name form, or
+// the string form of sid if it cannot be resolved, as for the SIDs of
+// deleted accounts.
+func (r *Resolver) Name(sid *windows.SID) string {
+	a, err := r.Account(sid)
+	if err != nil {
+		return sid.String()
+	}
+
+	return a.String()
+}
+
+// SID returns the SID of the account name, in the DOMAIN\name form or a
+// bare name, or the SID whose string form is name.
+func (r *Resolver) SID(name string) (*windows.SID, error) {
+	if strings.HasPrefix(name, "S-") {
+		if sid, err := windows.StringToSid(name); err == nil {
+			return sid, nil
+		}
+	}
+
+	key := nameKey(name)
+	if s, ok := r.mappedNames[key]; ok {
+		return windows.StringToSid(s)
+	}
+	if r.offline {
+		return nil, windows.ERROR_NONE_MAPPED
+	}
+
+	r.mu.Lock()
+	res, ok := r.byName.get(key)
+	r.mu.Unlock()
+	if ok {
+		return res.sid, res.err
+	}
+
+	sid, _, _, err := windows.LookupSID(r.system, name)
+	if err != nil && !errors.Is(err, windows.ERROR_NONE_MAPPED) {
+		return nil, err
+	}
+	res = resolvedSID{sid, err}
+
+	r.mu.Lock()
+	r.byName.add(key, res)
+	r.mu.Unlock()
+
+	return res.sid, res.err
+}
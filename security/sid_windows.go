@@ -0,0 +1,50 @@
+package security
+
+import (
+	"errors"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var errInvalidSID = errors.New("security: invalid SID")
+
+// sidBytes returns the binary form of sid, sharing its memory.
+func sidBytes(sid *windows.SID) []byte {
+	return unsafe.Slice((*byte)(unsafe.Pointer(sid)), sid.Len())
+}
+
+// sidFromBytes decodes the SID at the start of b into memory owned by the
+// Go runtime, and returns it with its length.
+func sidFromBytes(b []byte) (*windows.SID, int, error) {
+	if len(b) < 8 || b[0] != 1 {
+		return nil, 0, errInvalidSID
+	}
+
+	n := 8 + 4*int(b[1])
+	if n > len(b) {
+		return nil, 0, errInvalidSID
+	}
+
+	buf := make([]byte, n)
+	copy(buf, b)
+
+	return (*windows.SID)(unsafe.Pointer(&buf[0])), n, nil
+}
+
+// cloneSID copies sid into memory owned by the Go runtime, so that it
+// outlives buffers returned by the Windows API.
+func cloneSID(sid *windows.SID) *windows.SID {
+	s, _, _ := sidFromBytes(sidBytes(sid))
+	return s
+}
+
+// equalSID reports whether a and b are the same SID, nil being equal to
+// nil only.
+func equalSID(a, b *windows.SID) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return string(sidBytes(a)) == string(sidBytes(b))
+}
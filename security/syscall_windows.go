@@ -0,0 +1,9 @@
+package security
+
+//go:generate go run golang.org/x/sys/windows/mkwinsyscall -output zsyscall_windows.go syscall_windows.go
+
+//sys	convertSecurityDescriptorToStringSecurityDescriptor(sd *windows.SECURITY_DESCRIPTOR, revision uint32, securityInformation windows.SECURITY_INFORMATION, str **uint16, strLen *uint32) (err error) = advapi32.ConvertSecurityDescriptorToStringSecurityDescriptorW
+//sys	adjustTokenPrivileges(token windows.Token, disableAll bool, newState *byte, bufLen uint32, prevState *byte, returnLen *uint32) (success bool, err error) [true] = advapi32.AdjustTokenPrivileges
+//sys	authzInitializeResourceManager(flags uint32, accessCheck uintptr, computeDynamicGroups uintptr, freeDynamicGroups uintptr, name *uint16, rm *authzHandle) (err error) = authz.AuthzInitializeResourceManager
+//sys	authzAccessCheck(flags uint32, ctx authzHandle, request *authzAccessRequest, auditEvent uintptr, sd *windows.SECURITY_DESCRIPTOR, optionalSDs **windows.SECURITY_DESCRIPTOR, optionalSDCount uint32, reply *authzAccessReply, results *authzHandle) (err error) = authz.AuthzAccessCheck
+//sys	authzFreeContext(ctx authzHandle) (err error) = authz.AuthzFreeContext
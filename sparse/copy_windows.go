@@ -0,0 +1,101 @@
+package sparse
+
+import (
+	"io"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// copyBufferSize is the size of the buffer used to copy allocated ranges.
+const copyBufferSize = 1 << 20
+
+// Copy copies the file src to dst, which is created or truncated, reading
+// and writing only the allocated ranges of src. If src has holes, dst is
+// marked sparse and its holes are left unallocated, so that copying a
+// large and mostly empty file neither reads its zeros nor fills the
+// destination volume.
+func Copy(src, dst string) error {
+	return copyPath(src, dst, nil)
+}
+
+// CopySparsify is like Copy, but also leaves as holes in dst the runs of
+// zeros of the allocated ranges of src selected by opts, turning a dense
+// file into a sparse copy. A nil opts is the same as the zero ZeroOptions.
+func CopySparsify(src, dst string, opts *ZeroOptions) error {
+	if opts == nil {
+		opts = new(ZeroOptions)
+	}
+
+	return copyPath(src, dst, opts)
+}
+
+func copyPath(src, dst string, zeros *ZeroOptions) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+
+	if err := copyFile(out, in, zeros); err != nil {
+		out.Close()
+		return err
+	}
+
+	return out.Close()
+}
+
+// copyFile copies the content of in to the empty file out. If zeros is
+// not nil, the runs of zeros it selects are left as holes as well.
+func copyFile(out, in *os.File, zeros *ZeroOptions) error {
+	fi, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	size := fi.Size()
+
+	ranges, err := AllocatedRangesHandle(windows.Handle(in.Fd()), 0, size)
+	if err != nil {
+		return &os.PathError{Op: "FSCTL_QUERY_ALLOCATED_RANGES", Path: in.Name(), Err: err}
+	}
+	if zeros != nil {
+		runs, err := ZeroRuns(in, ranges, zeros)
+		if err != nil {
+			return err
+		}
+		ranges = subtract(ranges, runs)
+	}
+
+	if len(Holes(ranges, size)) > 0 {
+		if err := SetSparseHandle(windows.Handle(out.Fd()), true); err != nil {
+			return &os.PathError{Op: "FSCTL_SET_SPARSE", Path: out.Name(), Err: err}
+		}
+	}
+	// Extending a sparse file leaves the new bytes unallocated.
+	if err := out.Truncate(size); err != nil {
+		return err
+	}
+
+	buf := make([]byte, copyBufferSize)
+	for _, r := range ranges {
+		// The ranges are rounded to clusters, and may end past the end
+		// of a file shrunk since.
+		length := min(r.End(), size) - r.Offset
+		if length <= 0 {
+			continue
+		}
+
+		src := io.NewSectionReader(in, r.Offset, length)
+		dst := io.NewOffsetWriter(out, r.Offset)
+		if _, err := io.CopyBuffer(dst, src, buf); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,4 @@
+// Package sparse manages sparse files: marking them sparse, punching holes
+// in them, finding their allocated ranges, and copying them without
+// filling their holes.
+package sparse
@@ -0,0 +1,33 @@
+package sparse
+
+// Range is a range of bytes of a file.
+type Range struct {
+	Offset int64
+	Length int64
+}
+
+// End returns the offset following r.
+func (r Range) End() int64 {
+	return r.Offset + r.Length
+}
+
+// Holes returns the ranges of [0, size) which are not covered by
+// allocated, which must be sorted and not overlap.
+func Holes(allocated []Range, size int64) []Range {
+	var holes []Range
+	off := int64(0)
+	for _, r := range allocated {
+		if r.Offset > off {
+			holes = append(holes, Range{Offset: off, Length: min(r.Offset, size) - off})
+		}
+		off = max(off, r.End())
+		if off >= size {
+			return holes
+		}
+	}
+	if off < size {
+		holes = append(holes, Range{Offset: off, Length: size - off})
+	}
+
+	return holes
+}
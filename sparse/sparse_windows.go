@@ -0,0 +1,167 @@
+package sparse
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+
+	"golang.org/x/sys/windows"
+
+	"github.com/go-sw/ntfs/internal/fsctl"
+)
+
+// rangesChunk is the number of ranges requested per
+// FSCTL_QUERY_ALLOCATED_RANGES call.
+const rangesChunk = 512
+
+// IsSparse reports whether the file at path is sparse.
+func IsSparse(path string) (bool, error) {
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return false, &os.PathError{Op: "getfileattributes", Path: path, Err: err}
+	}
+
+	attrs, err := windows.GetFileAttributes(p)
+	if err != nil {
+		return false, &os.PathError{Op: "getfileattributes", Path: path, Err: err}
+	}
+
+	return attrs&windows.FILE_ATTRIBUTE_SPARSE_FILE != 0, nil
+}
+
+// MakeSparse marks the file at path as sparse, so that the ranges of zeros
+// written with PunchHole are deallocated.
+func MakeSparse(path string) error {
+	h, err := fsctl.Open(path, windows.FILE_WRITE_DATA|windows.FILE_WRITE_ATTRIBUTES, 0)
+	if err != nil {
+		return err
+	}
+	defer windows.CloseHandle(h)
+
+	if err := SetSparseHandle(h, true); err != nil {
+		return &os.PathError{Op: "FSCTL_SET_SPARSE", Path: path, Err: err}
+	}
+
+	return nil
+}
+
+// SetSparseHandle marks the file opened as h as sparse, or clears its
+// sparse flag, which fails if the file has holes.
+func SetSparseHandle(h windows.Handle, sparse bool) error {
+	// FILE_SET_SPARSE_BUFFER holds a BOOLEAN.
+	in := []byte{0}
+	if sparse {
+		in[0] = 1
+	}
+
+	_, err := fsctl.Call(h, windows.FSCTL_SET_SPARSE, in, nil)
+	return err
+}
+
+// PunchHole deallocates the length bytes of the file at path starting at
+// off, which then read as zeros. The file is marked sparse first if it is
+// not; the clusters of non-sparse files are zeroed rather than freed. Only
+// the whole clusters of the range are deallocated, the others being
+// zeroed.
+func PunchHole(path string, off, length int64) error {
+	h, err := fsctl.Open(path, windows.FILE_READ_ATTRIBUTES|windows.FILE_WRITE_DATA|windows.FILE_WRITE_ATTRIBUTES, 0)
+	if err != nil {
+		return err
+	}
+	defer windows.CloseHandle(h)
+
+	var fi windows.ByHandleFileInformation
+	if err := windows.GetFileInformationByHandle(h, &fi); err != nil {
+		return &os.PathError{Op: "getfileinformation", Path: path, Err: err}
+	}
+	if fi.FileAttributes&windows.FILE_ATTRIBUTE_SPARSE_FILE == 0 {
+		if err := SetSparseHandle(h, true); err != nil {
+			return &os.PathError{Op: "FSCTL_SET_SPARSE", Path: path, Err: err}
+		}
+	}
+
+	if err := PunchHoleHandle(h, off, length); err != nil {
+		return &os.PathError{Op: "FSCTL_SET_ZERO_DATA", Path: path, Err: err}
+	}
+
+	return nil
+}
+
+// PunchHoleHandle deallocates the length bytes of the sparse file opened
+// as h starting at off. The handle must have been opened with
+// FILE_WRITE_DATA access.
+func PunchHoleHandle(h windows.Handle, off, length int64) error {
+	if off < 0 || length < 0 {
+		return windows.ERROR_INVALID_PARAMETER
+	}
+	if length == 0 {
+		return nil
+	}
+
+	// FILE_ZERO_DATA_INFORMATION
+	in := make([]byte, 16)
+	binary.LittleEndian.PutUint64(in, uint64(off))
+	binary.LittleEndian.PutUint64(in[8:], uint64(off+length))
+
+	_, err := fsctl.Call(h, windows.FSCTL_SET_ZERO_DATA, in, nil)
+	return err
+}
+
+// AllocatedRanges returns the ranges of the file at path which are backed
+// by clusters, in ascending order. The whole file is a single range if it
+// is not sparse.
+func AllocatedRanges(path string) ([]Range, error) {
+	h, err := fsctl.Open(path, windows.FILE_READ_DATA|windows.FILE_READ_ATTRIBUTES, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer windows.CloseHandle(h)
+
+	var fi windows.ByHandleFileInformation
+	if err := windows.GetFileInformationByHandle(h, &fi); err != nil {
+		return nil, &os.PathError{Op: "getfileinformation", Path: path, Err: err}
+	}
+	size := int64(fi.FileSizeHigh)<<32 | int64(fi.FileSizeLow)
+
+	ranges, err := AllocatedRangesHandle(h, 0, size)
+	if err != nil {
+		return nil, &os.PathError{Op: "FSCTL_QUERY_ALLOCATED_RANGES", Path: path, Err: err}
+	}
+
+	return ranges, nil
+}
+
+// AllocatedRangesHandle returns the allocated ranges of the file opened as
+// h within the length bytes starting at off. The handle must have been
+// opened with FILE_READ_DATA access.
+func AllocatedRangesHandle(h windows.Handle, off, length int64) ([]Range, error) {
+	var ranges []Range
+
+	in := make([]byte, 16)
+	out := make([]byte, 16*rangesChunk)
+	end := off + length
+	for off < end {
+		// FILE_ALLOCATED_RANGE_BUFFER
+		binary.LittleEndian.PutUint64(in, uint64(off))
+		binary.LittleEndian.PutUint64(in[8:], uint64(end-off))
+
+		n, err := fsctl.Call(h, windows.FSCTL_QUERY_ALLOCATED_RANGES, in, out)
+		more := errors.Is(err, windows.ERROR_MORE_DATA)
+		if err != nil && !more {
+			return nil, err
+		}
+
+		for b := out[:n]; len(b) >= 16; b = b[16:] {
+			ranges = append(ranges, Range{
+				Offset: int64(binary.LittleEndian.Uint64(b)),
+				Length: int64(binary.LittleEndian.Uint64(b[8:])),
+			})
+		}
+		if !more || n < 16 {
+			break
+		}
+		off = ranges[len(ranges)-1].End()
+	}
+
+	return ranges, nil
+}
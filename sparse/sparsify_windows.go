@@ -0,0 +1,62 @@
+package sparse
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// Sparsify punches holes in place in the runs of zeros of the file at path
+// selected by opts, marking it sparse if needed, and returns the number of
+// bytes deallocated. Only the allocated ranges of the file are scanned, so
+// that sparsifying a file again after it shrank only reads its data. A nil
+// opts is the same as the zero ZeroOptions.
+//
+// Writers are denied access to the file while it is sparsified, so that
+// no data written after a run was scanned is punched out with it. Files
+// open for writing elsewhere fail with a sharing violation.
+func Sparsify(path string, opts *ZeroOptions) (int64, error) {
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, &os.PathError{Op: "open", Path: path, Err: err}
+	}
+	h, err := windows.CreateFile(p, windows.FILE_READ_DATA|windows.FILE_WRITE_DATA|
+		windows.FILE_READ_ATTRIBUTES|windows.FILE_WRITE_ATTRIBUTES, windows.FILE_SHARE_READ, nil,
+		windows.OPEN_EXISTING, windows.FILE_FLAG_BACKUP_SEMANTICS, 0)
+	if err != nil {
+		return 0, &os.PathError{Op: "open", Path: path, Err: err}
+	}
+	f := os.NewFile(uintptr(h), path)
+	defer f.Close()
+
+	var fi windows.ByHandleFileInformation
+	if err := windows.GetFileInformationByHandle(h, &fi); err != nil {
+		return 0, &os.PathError{Op: "getfileinformation", Path: path, Err: err}
+	}
+	size := int64(fi.FileSizeHigh)<<32 | int64(fi.FileSizeLow)
+
+	ranges, err := AllocatedRangesHandle(h, 0, size)
+	if err != nil {
+		return 0, &os.PathError{Op: "FSCTL_QUERY_ALLOCATED_RANGES", Path: path, Err: err}
+	}
+	runs, err := ZeroRuns(f, ranges, opts)
+	if err != nil || len(runs) == 0 {
+		return 0, err
+	}
+
+	if fi.FileAttributes&windows.FILE_ATTRIBUTE_SPARSE_FILE == 0 {
+		if err := SetSparseHandle(h, true); err != nil {
+			return 0, &os.PathError{Op: "FSCTL_SET_SPARSE", Path: path, Err: err}
+		}
+	}
+
+	var freed int64
+	for _, r := range runs {
+		if err := PunchHoleHandle(h, r.Offset, r.Length); err != nil {
+			return freed, &os.PathError{Op: "FSCTL_SET_ZERO_DATA", Path: path, Err: err}
+		}
+		freed += r.Length
+	}
+
+	return freed, nil
+}
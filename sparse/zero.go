@@ -0,0 +1,134 @@
+package sparse
+
+import (
+	"bytes"
+	"io"
+)
+
+const (
+	// DefaultThreshold is the shortest run of zeros turned into a hole
+	// unless set otherwise, the unit in which NTFS allocates sparse files
+	// with 4KiB clusters.
+	DefaultThreshold = 64 << 10
+	// DefaultBlockSize is the granularity at which zeros are detected
+	// unless set otherwise.
+	DefaultBlockSize = 4 << 10
+
+	// scanChunk is the size of the reads of ZeroRuns.
+	scanChunk = 1 << 20
+)
+
+// ZeroOptions control the detection of runs of zeros.
+type ZeroOptions struct {
+	// Threshold is the length of the shortest run of zeros reported, or
+	// DefaultThreshold if zero.
+	Threshold int64
+	// BlockSize is the size of the blocks found to be all zeros, which
+	// the runs are aligned to, or DefaultBlockSize if zero. It should be
+	// a multiple of the cluster size.
+	BlockSize int64
+}
+
+func (o *ZeroOptions) values() (threshold, block int64) {
+	threshold, block = DefaultThreshold, DefaultBlockSize
+	if o != nil && o.Threshold > 0 {
+		threshold = o.Threshold
+	}
+	if o != nil && o.BlockSize > 0 {
+		block = o.BlockSize
+	}
+
+	return max(threshold, block), block
+}
+
+// zeroBlock is compared against the data scanned, as bytes.Equal runs
+// vectorized on most platforms.
+var zeroBlock [scanChunk]byte
+
+// isZero reports whether b only holds zeros.
+func isZero(b []byte) bool {
+	for len(b) > 0 {
+		n := min(len(b), len(zeroBlock))
+		if !bytes.Equal(b[:n], zeroBlock[:n]) {
+			return false
+		}
+		b = b[n:]
+	}
+
+	return true
+}
+
+// ZeroRuns returns the runs of zeros of r within ranges, which must be
+// sorted and not overlap, that are at least as long as the threshold of
+// opts. A nil opts is the same as the zero ZeroOptions.
+func ZeroRuns(r io.ReaderAt, ranges []Range, opts *ZeroOptions) ([]Range, error) {
+	threshold, block := opts.values()
+	chunk := max(scanChunk/block, 1) * block
+	buf := make([]byte, chunk)
+
+	var (
+		runs []Range
+		run  Range
+	)
+	flush := func() {
+		if run.Length >= threshold {
+			runs = append(runs, run)
+		}
+		run = Range{}
+	}
+
+	for _, rg := range ranges {
+		for off := rg.Offset; off < rg.End(); off += chunk {
+			n, err := r.ReadAt(buf[:min(chunk, rg.End()-off)], off)
+			if err != nil && err != io.EOF {
+				return nil, err
+			}
+
+			for i := 0; i < n; i += int(block) {
+				b := buf[i:min(i+int(block), n)]
+				if !isZero(b) {
+					flush()
+					continue
+				}
+				if run.Length == 0 || run.End() != off+int64(i) {
+					flush()
+					run.Offset = off + int64(i)
+				}
+				run.Length += int64(len(b))
+			}
+			if err == io.EOF {
+				break
+			}
+		}
+	}
+	flush()
+
+	return runs, nil
+}
+
+// subtract returns the parts of ranges not covered by holes, both being
+// sorted and without overlaps.
+func subtract(ranges, holes []Range) []Range {
+	var out []Range
+	for _, r := range ranges {
+		for len(holes) > 0 && holes[0].End() <= r.Offset {
+			holes = holes[1:]
+		}
+
+		off := r.Offset
+		for _, h := range holes {
+			if h.Offset >= r.End() {
+				break
+			}
+			if h.Offset > off {
+				out = append(out, Range{Offset: off, Length: h.Offset - off})
+			}
+			off = max(off, h.End())
+		}
+		if off < r.End() {
+			out = append(out, Range{Offset: off, Length: r.End() - off})
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,429 @@
+package txf
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+
+	"github.com/go-sw/ntfs/internal/fsctl"
+)
+
+var (
+	// ErrStaged is returned when staging a second change to a file.
+	ErrStaged = errors.New("txf: file already has a staged change")
+	// ErrCycle is returned by Commit when the dependencies of the changes
+	// form a cycle.
+	ErrCycle = errors.New("txf: dependency cycle between staged changes")
+)
+
+// state is the state of a change while a batch is committed.
+type state int
+
+const (
+	pending state = iota
+	// replaced is a file replaced with ReplaceFile, whose previous
+	// content is in the backup.
+	replaced
+	// created is a file which did not exist.
+	created
+	// removed is a file renamed to its backup.
+	removed
+)
+
+// change is a staged change to a file.
+type change struct {
+	name string
+	// staged holds the new content of the file, and is empty for
+	// removals.
+	staged string
+	backup string
+	after  []string
+	state  state
+}
+
+// Batch stages changes to files, which are committed together without
+// TxF: either all of them are applied, or those applied are undone. The
+// new contents are written to temporary files next to their targets, and
+// then replace them with ReplaceFile, which keeps their attributes and
+// security, or take their names with atomic renames. A crash while
+// committing may leave some changes applied, with the previous contents
+// of the files left in backup files next to them.
+//
+// A Batch is not safe for concurrent use.
+type Batch struct {
+	changes []*change
+	byName  map[string]*change
+	done    bool
+}
+
+// NewBatch returns an empty batch.
+func NewBatch() *Batch {
+	return &Batch{byName: make(map[string]*change)}
+}
+
+// WriteFile stages writing data to the named file, which is created if
+// needed. The change is committed after the changes to the files after,
+// such as those the file refers to.
+func (b *Batch) WriteFile(name string, data []byte, after ...string) error {
+	f, err := b.Create(name, after...)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err1 := f.Close(); err == nil {
+		err = err1
+	}
+
+	return err
+}
+
+// Create stages writing the named file, returning the temporary file
+// holding its new content. The file must be written and closed before the
+// batch is committed. The change is committed after the changes to the
+// files after.
+func (b *Batch) Create(name string, after ...string) (*os.File, error) {
+	c, err := b.add(name, after)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(c.name), "."+filepath.Base(c.name)+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	c.staged = f.Name()
+	b.stage(c)
+
+	return f, nil
+}
+
+// Remove stages removing the named file. The change is committed after
+// the changes to the files after.
+func (b *Batch) Remove(name string, after ...string) error {
+	c, err := b.add(name, after)
+	if err != nil {
+		return err
+	}
+	b.stage(c)
+
+	return nil
+}
+
+// add returns the change to the named file, which must not have one yet.
+func (b *Batch) add(name string, after []string) (*change, error) {
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		return nil, err
+	}
+	if b.done {
+		return nil, &os.PathError{Op: "stage", Path: name, Err: ErrDone}
+	}
+	if _, ok := b.byName[key(abs)]; ok {
+		return nil, &os.PathError{Op: "stage", Path: name, Err: ErrStaged}
+	}
+
+	c := &change{name: abs}
+	for _, a := range after {
+		if a, err = filepath.Abs(a); err != nil {
+			return nil, err
+		}
+		c.after = append(c.after, a)
+	}
+
+	return c, nil
+}
+
+func (b *Batch) stage(c *change) {
+	b.changes = append(b.changes, c)
+	b.byName[key(c.name)] = c
+}
+
+// key returns the key of the change to the file at the absolute path
+// name, which is the same for every case of it.
+func key(name string) string {
+	return strings.ToUpper(name)
+}
+
+// Commit applies the staged changes, each after the changes it depends
+// on and otherwise in the order they were staged. If a change fails, the
+// changes already applied are undone, and the error is returned along
+// with those of the undoing.
+func (b *Batch) Commit() error {
+	if b.done {
+		return ErrDone
+	}
+	b.done = true
+
+	order, err := b.order()
+	if err != nil {
+		b.discard()
+		return err
+	}
+
+	for i, c := range order {
+		if err := c.apply(); err != nil {
+			// The failed change is undone as well, as it may have been
+			// partly applied.
+			errs := []error{err}
+			for j := i; j >= 0; j-- {
+				errs = append(errs, order[j].undo())
+			}
+			b.discard()
+			return errors.Join(errs...)
+		}
+	}
+
+	// The backups are only needed until every change is applied.
+	for _, c := range order {
+		if c.backup != "" {
+			os.Remove(c.backup)
+		}
+	}
+
+	return nil
+}
+
+// Abort discards the staged changes.
+func (b *Batch) Abort() error {
+	if b.done {
+		return ErrDone
+	}
+	b.done = true
+	b.discard()
+
+	return nil
+}
+
+// discard removes the temporary files whose content did not take the name
+// of its target.
+func (b *Batch) discard() {
+	for _, c := range b.changes {
+		if c.state != replaced && c.state != created && c.staged != "" {
+			os.Remove(c.staged)
+		}
+	}
+}
+
+// order returns the changes sorted so that every change comes after the
+// changes it depends on. Dependencies on files without a staged change
+// are ignored.
+func (b *Batch) order() ([]*change, error) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	marks := make(map[*change]int, len(b.changes))
+	order := make([]*change, 0, len(b.changes))
+
+	var visit func(c *change) error
+	visit = func(c *change) error {
+		switch marks[c] {
+		case visiting:
+			return &os.PathError{Op: "commit", Path: c.name, Err: ErrCycle}
+		case visited:
+			return nil
+		}
+		marks[c] = visiting
+		for _, a := range c.after {
+			if dep, ok := b.byName[key(a)]; ok {
+				if err := visit(dep); err != nil {
+					return err
+				}
+			}
+		}
+		marks[c] = visited
+		order = append(order, c)
+		return nil
+	}
+
+	for _, c := range b.changes {
+		if err := visit(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return order, nil
+}
+
+// apply applies the change.
+func (c *change) apply() error {
+	_, err := os.Lstat(c.name)
+	exists := err == nil
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	switch {
+	case c.staged == "":
+		if !exists {
+			// Removing a missing file is a no-op.
+			return nil
+		}
+		if c.backup, err = backupName(c.name); err != nil {
+			return err
+		}
+		if err := rename(c.name, c.backup, false); err != nil {
+			return err
+		}
+		c.state = removed
+	case exists:
+		if c.backup, err = backupName(c.name); err != nil {
+			return err
+		}
+		if err := replace(c.name, c.staged, c.backup); err != nil {
+			if errors.Is(err, windows.ERROR_UNABLE_TO_MOVE_REPLACEMENT_2) {
+				// The file was moved to its backup, but the new content
+				// did not take its name: it is undone as a removal.
+				c.state = removed
+			}
+			return err
+		}
+		c.state = replaced
+	default:
+		if err := rename(c.staged, c.name, false); err != nil {
+			return err
+		}
+		c.state = created
+	}
+
+	return nil
+}
+
+// undo undoes the change applied.
+func (c *change) undo() error {
+	switch c.state {
+	case replaced:
+		return rename(c.backup, c.name, true)
+	case created:
+		return os.Remove(c.name)
+	case removed:
+		return rename(c.backup, c.name, false)
+	default:
+		return nil
+	}
+}
+
+// backupName returns an unused name for the backup of the file at path.
+func backupName(path string) (string, error) {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+
+	return filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+"."+hex.EncodeToString(b[:])+".bak"), nil
+}
+
+// replace replaces the file at path with the file at staged, moving the
+// previous file to backup.
+func replace(path, staged, backup string) error {
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return &os.PathError{Op: "ReplaceFile", Path: path, Err: err}
+	}
+	s, err := windows.UTF16PtrFromString(staged)
+	if err != nil {
+		return &os.PathError{Op: "ReplaceFile", Path: path, Err: err}
+	}
+	b, err := windows.UTF16PtrFromString(backup)
+	if err != nil {
+		return &os.PathError{Op: "ReplaceFile", Path: path, Err: err}
+	}
+
+	if err := replaceFile(p, s, b, 0, 0, 0); err != nil {
+		return &os.PathError{Op: "ReplaceFile", Path: path, Err: err}
+	}
+
+	return nil
+}
+
+// fileRenameInfo is FILE_RENAME_INFO with the Flags of FileRenameInfoEx.
+type fileRenameInfo struct {
+	Flags          uint32
+	RootDirectory  windows.Handle
+	FileNameLength uint32
+	FileName       [1]uint16
+}
+
+// rename renames the file at from to to with FileRenameInfoEx and POSIX
+// semantics, replacing to only if overwrite is set. It falls back to
+// MoveFileEx on systems older than Windows 10 1607.
+func rename(from, to string, overwrite bool) error {
+	err := renameEx(from, to, overwrite)
+	if errors.Is(err, windows.ERROR_INVALID_PARAMETER) || errors.Is(err, windows.ERROR_INVALID_FUNCTION) ||
+		errors.Is(err, windows.ERROR_NOT_SUPPORTED) {
+		err = moveFile(from, to, overwrite)
+	}
+	if err != nil {
+		return &os.LinkError{Op: "rename", Old: from, New: to, Err: err}
+	}
+
+	return nil
+}
+
+func renameEx(from, to string, overwrite bool) error {
+	p, err := windows.UTF16PtrFromString(from)
+	if err != nil {
+		return err
+	}
+	h, err := windows.CreateFile(p, windows.DELETE|windows.SYNCHRONIZE, fsctl.ShareAll, nil, windows.OPEN_EXISTING,
+		windows.FILE_FLAG_BACKUP_SEMANTICS|windows.FILE_FLAG_OPEN_REPARSE_POINT, 0)
+	if err != nil {
+		return err
+	}
+	defer windows.CloseHandle(h)
+
+	name, err := windows.UTF16FromString(longPath(to))
+	if err != nil {
+		return err
+	}
+	var info fileRenameInfo
+	size := int(unsafe.Offsetof(info.FileName)) + 2*len(name)
+	buf := make([]uint64, (size+7)/8)
+	fi := (*fileRenameInfo)(unsafe.Pointer(&buf[0]))
+	fi.Flags = windows.FILE_RENAME_POSIX_SEMANTICS
+	if overwrite {
+		fi.Flags |= windows.FILE_RENAME_REPLACE_IF_EXISTS
+	}
+	fi.FileNameLength = uint32(2 * (len(name) - 1))
+	copy(unsafe.Slice(&fi.FileName[0], len(name)), name)
+
+	return windows.SetFileInformationByHandle(h, windows.FileRenameInfoEx, (*byte)(unsafe.Pointer(fi)), uint32(size))
+}
+
+func moveFile(from, to string, overwrite bool) error {
+	f, err := windows.UTF16PtrFromString(from)
+	if err != nil {
+		return err
+	}
+	t, err := windows.UTF16PtrFromString(to)
+	if err != nil {
+		return err
+	}
+
+	var flags uint32
+	if overwrite {
+		flags = windows.MOVEFILE_REPLACE_EXISTING
+	}
+
+	return windows.MoveFileEx(f, t, flags)
+}
+
+// longPath returns the absolute path with the \\?\ prefix, as the names
+// passed to SetFileInformationByHandle are not normalized.
+func longPath(path string) string {
+	switch {
+	case strings.HasPrefix(path, `\\?\`), strings.HasPrefix(path, `\\.\`):
+		return path
+	case strings.HasPrefix(path, `\\`):
+		return `\\?\UNC\` + path[2:]
+	default:
+		return `\\?\` + path
+	}
+}
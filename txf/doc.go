@@ -0,0 +1,10 @@
+// Package txf performs file operations within Kernel Transaction Manager
+// transactions, using Transactional NTFS (TxF).
+//
+// TxF is deprecated: Microsoft discourages its use and may remove it from
+// future versions of Windows, and it does not work on network shares,
+// ReFS or removable media. This package is meant for programs which must
+// take part in existing transacted workflows; others should stage their
+// changes with a Batch, which commits them with ReplaceFile and atomic
+// renames instead.
+package txf
@@ -0,0 +1,13 @@
+package txf
+
+//go:generate go run golang.org/x/sys/windows/mkwinsyscall -output zsyscall_windows.go syscall_windows.go
+
+//sys	createTransaction(sa *windows.SecurityAttributes, uow *windows.GUID, options uint32, isolationLevel uint32, isolationFlags uint32, timeout uint32, description *uint16) (h windows.Handle, err error) [failretval==windows.InvalidHandle] = ktmw32.CreateTransaction
+//sys	commitTransaction(h windows.Handle) (err error) = ktmw32.CommitTransaction
+//sys	rollbackTransaction(h windows.Handle) (err error) = ktmw32.RollbackTransaction
+//sys	createFileTransacted(name *uint16, access uint32, share uint32, sa *windows.SecurityAttributes, disposition uint32, flags uint32, template windows.Handle, tx windows.Handle, miniVersion *uint16, extended uintptr) (h windows.Handle, err error) [failretval==windows.InvalidHandle] = kernel32.CreateFileTransactedW
+//sys	deleteFileTransacted(name *uint16, tx windows.Handle) (err error) = kernel32.DeleteFileTransactedW
+//sys	moveFileTransacted(from *uint16, to *uint16, progress uintptr, data uintptr, flags uint32, tx windows.Handle) (err error) = kernel32.MoveFileTransactedW
+//sys	createDirectoryTransacted(template *uint16, name *uint16, sa *windows.SecurityAttributes, tx windows.Handle) (err error) = kernel32.CreateDirectoryTransactedW
+//sys	removeDirectoryTransacted(name *uint16, tx windows.Handle) (err error) = kernel32.RemoveDirectoryTransactedW
+//sys	replaceFile(replaced *uint16, replacement *uint16, backup *uint16, flags uint32, exclude uintptr, reserved uintptr) (err error) = kernel32.ReplaceFileW
@@ -0,0 +1,233 @@
+package txf
+
+import (
+	"errors"
+	"io"
+	"os"
+	"time"
+
+	"golang.org/x/sys/windows"
+
+	"github.com/go-sw/ntfs/internal/fsctl"
+)
+
+// ErrDone is returned when using a transaction which was committed or
+// rolled back.
+var ErrDone = errors.New("txf: transaction already committed or rolled back")
+
+// Transaction is a KTM transaction. The changes made through it are only
+// visible to it until it is committed, and are undone if it is rolled
+// back, closed or times out.
+type Transaction struct {
+	h    windows.Handle
+	done bool
+}
+
+// Begin starts a transaction described by description, as shown by
+// administrative tools. If timeout is not zero, the transaction is rolled
+// back if it is not committed within it.
+func Begin(description string, timeout time.Duration) (*Transaction, error) {
+	desc, err := windows.UTF16PtrFromString(description)
+	if err != nil {
+		return nil, err
+	}
+
+	var ms uint32
+	if timeout > 0 {
+		ms = uint32(min(timeout.Milliseconds(), windows.INFINITE-1))
+	}
+	h, err := createTransaction(nil, nil, 0, 0, 0, ms, desc)
+	if err != nil {
+		return nil, os.NewSyscallError("CreateTransaction", err)
+	}
+
+	return &Transaction{h: h}, nil
+}
+
+// Handle returns the handle of the transaction, for use with other
+// transacted APIs.
+func (t *Transaction) Handle() windows.Handle {
+	return t.h
+}
+
+// Commit commits the changes made within the transaction.
+func (t *Transaction) Commit() error {
+	if t.done {
+		return ErrDone
+	}
+	if err := commitTransaction(t.h); err != nil {
+		return os.NewSyscallError("CommitTransaction", err)
+	}
+	t.done = true
+
+	return nil
+}
+
+// Rollback undoes the changes made within the transaction.
+func (t *Transaction) Rollback() error {
+	if t.done {
+		return ErrDone
+	}
+	if err := rollbackTransaction(t.h); err != nil {
+		return os.NewSyscallError("RollbackTransaction", err)
+	}
+	t.done = true
+
+	return nil
+}
+
+// Close rolls the transaction back unless it was committed, and releases
+// it. The files opened within the transaction should be closed first.
+func (t *Transaction) Close() error {
+	if t.h == windows.InvalidHandle {
+		return nil
+	}
+
+	var err error
+	if !t.done {
+		err = t.Rollback()
+	}
+	windows.CloseHandle(t.h)
+	t.h = windows.InvalidHandle
+
+	return err
+}
+
+// OpenFile opens the named file within the transaction, like os.OpenFile.
+// perm is ignored, the file getting the permissions inherited from its
+// directory.
+func (t *Transaction) OpenFile(name string, flag int, perm os.FileMode) (*os.File, error) {
+	if t.done {
+		return nil, &os.PathError{Op: "open", Path: name, Err: ErrDone}
+	}
+	p, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: name, Err: err}
+	}
+
+	var access uint32
+	switch flag & (os.O_RDONLY | os.O_WRONLY | os.O_RDWR) {
+	case os.O_RDONLY:
+		access = windows.GENERIC_READ
+	case os.O_WRONLY:
+		access = windows.GENERIC_WRITE
+	case os.O_RDWR:
+		access = windows.GENERIC_READ | windows.GENERIC_WRITE
+	}
+
+	var disposition uint32
+	switch {
+	case flag&(os.O_CREATE|os.O_EXCL) == os.O_CREATE|os.O_EXCL:
+		disposition = windows.CREATE_NEW
+	case flag&(os.O_CREATE|os.O_TRUNC) == os.O_CREATE|os.O_TRUNC:
+		disposition = windows.CREATE_ALWAYS
+	case flag&os.O_CREATE != 0:
+		disposition = windows.OPEN_ALWAYS
+	case flag&os.O_TRUNC != 0:
+		disposition = windows.TRUNCATE_EXISTING
+	default:
+		disposition = windows.OPEN_EXISTING
+	}
+
+	h, err := createFileTransacted(p, access, fsctl.ShareAll, nil, disposition,
+		windows.FILE_ATTRIBUTE_NORMAL|windows.FILE_FLAG_BACKUP_SEMANTICS, 0, t.h, nil, 0)
+	if err != nil {
+		return nil, &os.PathError{Op: "CreateFileTransacted", Path: name, Err: err}
+	}
+
+	f := os.NewFile(uintptr(h), name)
+	if flag&os.O_APPEND != 0 {
+		if _, err := f.Seek(0, io.SeekEnd); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+
+	return f, nil
+}
+
+// Open opens the named file for reading within the transaction.
+func (t *Transaction) Open(name string) (*os.File, error) {
+	return t.OpenFile(name, os.O_RDONLY, 0)
+}
+
+// Create creates or truncates the named file within the transaction.
+func (t *Transaction) Create(name string) (*os.File, error) {
+	return t.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0)
+}
+
+// WriteFile writes data to the named file within the transaction,
+// creating or truncating it, like os.WriteFile.
+func (t *Transaction) WriteFile(name string, data []byte, perm os.FileMode) error {
+	f, err := t.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err1 := f.Close(); err == nil {
+		err = err1
+	}
+
+	return err
+}
+
+// Remove removes the named file or empty directory within the
+// transaction.
+func (t *Transaction) Remove(name string) error {
+	if t.done {
+		return &os.PathError{Op: "remove", Path: name, Err: ErrDone}
+	}
+	p, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return &os.PathError{Op: "remove", Path: name, Err: err}
+	}
+
+	err = deleteFileTransacted(p, t.h)
+	if err == nil {
+		return nil
+	}
+	if errDir := removeDirectoryTransacted(p, t.h); errDir == nil {
+		return nil
+	}
+
+	return &os.PathError{Op: "DeleteFileTransacted", Path: name, Err: err}
+}
+
+// Rename renames oldpath to newpath within the transaction, replacing
+// newpath if it is a file.
+func (t *Transaction) Rename(oldpath, newpath string) error {
+	if t.done {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: ErrDone}
+	}
+	from, err := windows.UTF16PtrFromString(oldpath)
+	if err != nil {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: err}
+	}
+	to, err := windows.UTF16PtrFromString(newpath)
+	if err != nil {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: err}
+	}
+
+	if err := moveFileTransacted(from, to, 0, 0, windows.MOVEFILE_REPLACE_EXISTING, t.h); err != nil {
+		return &os.LinkError{Op: "MoveFileTransacted", Old: oldpath, New: newpath, Err: err}
+	}
+
+	return nil
+}
+
+// Mkdir creates the named directory within the transaction.
+func (t *Transaction) Mkdir(name string) error {
+	if t.done {
+		return &os.PathError{Op: "mkdir", Path: name, Err: ErrDone}
+	}
+	p, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return &os.PathError{Op: "mkdir", Path: name, Err: err}
+	}
+
+	if err := createDirectoryTransacted(nil, p, nil, t.h); err != nil {
+		return &os.PathError{Op: "CreateDirectoryTransacted", Path: name, Err: err}
+	}
+
+	return nil
+}
@@ -0,0 +1,116 @@
+package volume
+
+import (
+	"iter"
+	"math/bits"
+)
+
+// ClusterBitmap is a portion of the cluster allocation bitmap of a
+// volume. Each bit tells whether the corresponding cluster is in use.
+type ClusterBitmap struct {
+	// StartLCN is the first cluster covered by the bitmap.
+	StartLCN int64
+	// Clusters is the number of clusters covered by the bitmap.
+	Clusters int64
+
+	base  int64 // cluster of bit 0 of data, a multiple of 8
+	data  []byte
+	total int64 // clusters on the volume
+}
+
+// Run is a sequence of contiguous clusters in the same allocation state.
+type Run struct {
+	LCN       int64
+	Length    int64
+	Allocated bool
+}
+
+// Contains reports whether lcn is covered by b.
+func (b *ClusterBitmap) Contains(lcn int64) bool {
+	return lcn >= b.StartLCN && lcn < b.StartLCN+b.Clusters
+}
+
+// Allocated reports whether the cluster lcn is in use. Clusters outside of
+// the bitmap are reported as free.
+func (b *ClusterBitmap) Allocated(lcn int64) bool {
+	if !b.Contains(lcn) {
+		return false
+	}
+
+	i := lcn - b.base
+	return b.data[i/8]&(1<<(i%8)) != 0
+}
+
+// AllocatedCount returns the number of clusters in use in b.
+func (b *ClusterBitmap) AllocatedCount() int64 {
+	var n int64
+	for lcn, end := b.StartLCN, b.StartLCN+b.Clusters; lcn < end; {
+		i := lcn - b.base
+		if i%8 == 0 && end-lcn >= 8 {
+			n += int64(bits.OnesCount8(b.data[i/8]))
+			lcn += 8
+			continue
+		}
+		if b.Allocated(lcn) {
+			n++
+		}
+		lcn++
+	}
+
+	return n
+}
+
+// FreeCount returns the number of free clusters in b.
+func (b *ClusterBitmap) FreeCount() int64 {
+	return b.Clusters - b.AllocatedCount()
+}
+
+// Runs returns an iterator over the allocated and free runs of b, in
+// ascending cluster order.
+func (b *ClusterBitmap) Runs() iter.Seq[Run] {
+	return func(yield func(Run) bool) {
+		end := b.StartLCN + b.Clusters
+		for lcn := b.StartLCN; lcn < end; {
+			r := Run{LCN: lcn, Allocated: b.Allocated(lcn)}
+			for lcn < end && b.Allocated(lcn) == r.Allocated {
+				// Skip whole bytes sharing the state of the run.
+				if i := lcn - b.base; i%8 == 0 && end-lcn >= 8 {
+					if v := b.data[i/8]; (r.Allocated && v == 0xFF) || (!r.Allocated && v == 0) {
+						lcn += 8
+						continue
+					}
+				}
+				lcn++
+			}
+			r.Length = lcn - r.LCN
+
+			if !yield(r) {
+				return
+			}
+		}
+	}
+}
+
+// FreeRuns returns an iterator over the free runs of b.
+func (b *ClusterBitmap) FreeRuns() iter.Seq[Run] {
+	return func(yield func(Run) bool) {
+		for r := range b.Runs() {
+			if !r.Allocated && !yield(r) {
+				return
+			}
+		}
+	}
+}
+
+// LargestFreeRun returns the longest free run in b. The returned run has
+// a zero length if b has no free cluster.
+func (b *ClusterBitmap) LargestFreeRun() Run {
+	var best Run
+	for r := range b.FreeRuns() {
+		if r.Length > best.Length {
+			best = r
+		}
+	}
+
+	return best
+}
@@ -0,0 +1,88 @@
+package volume
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+
+	"golang.org/x/sys/windows"
+
+	"github.com/go-sw/ntfs/internal/fsctl"
+)
+
+// FSCTL_GET_VOLUME_BITMAP is missing from golang.org/x/sys/windows.
+const fsctlGetVolumeBitmap = 0x0009006F
+
+// bitmapChunk bounds the bitmap bytes requested per FSCTL_GET_VOLUME_BITMAP
+// call.
+const bitmapChunk = 1 << 20
+
+// Bitmap returns the allocation state of clusters clusters of the volume
+// containing root, starting at startLCN. If clusters is zero or negative,
+// the bitmap extends to the end of the volume. Reading the bitmap
+// requires administrative rights.
+func Bitmap(root string, startLCN, clusters int64) (*ClusterBitmap, error) {
+	h, err := openVolume(root, windows.GENERIC_READ)
+	if err != nil {
+		return nil, err
+	}
+	defer windows.CloseHandle(h)
+
+	b, err := BitmapHandle(h, startLCN, clusters)
+	if err != nil {
+		return nil, &os.PathError{Op: "FSCTL_GET_VOLUME_BITMAP", Path: root, Err: err}
+	}
+
+	return b, nil
+}
+
+// BitmapHandle is like Bitmap but reads the bitmap of the volume opened as
+// h.
+func BitmapHandle(h windows.Handle, startLCN, clusters int64) (*ClusterBitmap, error) {
+	if startLCN < 0 {
+		return nil, windows.ERROR_INVALID_PARAMETER
+	}
+
+	// The file system rounds the starting LCN down to a multiple of 8.
+	b := &ClusterBitmap{StartLCN: startLCN, base: startLCN &^ 7}
+
+	end := int64(-1)
+	if clusters > 0 {
+		end = startLCN + clusters
+	}
+
+	in := make([]byte, 8)
+	for lcn := b.base; end < 0 || lcn < end; {
+		want := int64(bitmapChunk)
+		if end >= 0 {
+			want = min(want, (end-lcn+7)/8)
+		}
+
+		out := make([]byte, 16+want)
+		binary.LittleEndian.PutUint64(in, uint64(lcn))
+		n, err := fsctl.Call(h, fsctlGetVolumeBitmap, in, out)
+		if err != nil && !errors.Is(err, windows.ERROR_MORE_DATA) {
+			return nil, err
+		}
+
+		got := int64(binary.LittleEndian.Uint64(out[0:]))
+		size := int64(binary.LittleEndian.Uint64(out[8:])) // clusters left on the volume
+		if got != lcn {
+			return nil, errors.New("volume: unexpected starting LCN in volume bitmap")
+		}
+		b.total = got + size
+		if end < 0 || end > b.total {
+			end = b.total
+		}
+
+		b.data = append(b.data, out[16:n]...)
+		lcn = got + int64(n-16)*8
+		if err == nil {
+			break
+		}
+	}
+
+	b.Clusters = max(0, min(end, b.base+int64(len(b.data))*8)-startLCN)
+
+	return b, nil
+}
@@ -0,0 +1,3 @@
+// Package volume queries and controls NTFS volumes: their capabilities,
+// layout, mount points and cluster allocation.
+package volume
@@ -0,0 +1,105 @@
+package volume
+
+// ExtentKind tells how the clusters of an extent are backed.
+type ExtentKind uint8
+
+const (
+	// ExtentData is a run of clusters allocated on the volume.
+	ExtentData ExtentKind = iota
+	// ExtentSparse is a hole of a sparse file, which reads as zeros and
+	// occupies no cluster.
+	ExtentSparse
+	// ExtentCompressed is the unallocated tail of a compression unit
+	// whose data is stored compressed in the clusters preceding it.
+	ExtentCompressed
+)
+
+func (k ExtentKind) String() string {
+	switch k {
+	case ExtentData:
+		return "data"
+	case ExtentSparse:
+		return "sparse"
+	case ExtentCompressed:
+		return "compressed"
+	default:
+		return "unknown"
+	}
+}
+
+// Extent maps a run of virtual clusters of a file to logical clusters of
+// its volume.
+type Extent struct {
+	// VCN is the first virtual cluster of the extent, relative to the
+	// start of the file.
+	VCN int64
+	// LCN is the first logical cluster of the extent on the volume, or
+	// -1 if the extent is not allocated.
+	LCN int64
+	// Length is the number of clusters in the extent.
+	Length int64
+	// Kind tells how the clusters of the extent are backed.
+	Kind ExtentKind
+}
+
+// Allocated reports whether e occupies clusters on the volume.
+func (e Extent) Allocated() bool {
+	return e.LCN >= 0
+}
+
+// MarkCompressionUnits reclassifies as ExtentCompressed the holes of a
+// compressed file which complete a partially allocated compression unit
+// of unit clusters.
+func MarkCompressionUnits(extents []Extent, unit int64) {
+	if unit <= 1 {
+		return
+	}
+
+	for i := 1; i < len(extents); i++ {
+		e := &extents[i]
+		prev := extents[i-1]
+		if e.Allocated() || !prev.Allocated() {
+			continue
+		}
+		// A hole which starts inside a unit holding data and ends on a
+		// unit boundary is the space saved by compressing that unit.
+		if e.VCN%unit != 0 && (e.VCN+e.Length)%unit == 0 && e.Length < unit {
+			e.Kind = ExtentCompressed
+		}
+	}
+}
+
+// Fragments returns the number of physically contiguous pieces the
+// allocated extents of a file are stored in.
+func Fragments(extents []Extent) int {
+	n := 0
+	next := int64(-1)
+	for _, e := range extents {
+		if !e.Allocated() {
+			continue
+		}
+		if e.LCN != next {
+			n++
+		}
+		next = e.LCN + e.Length
+	}
+
+	return n
+}
+
+// FragmentationScore returns a measure of the fragmentation of a file
+// between 0, for a file stored in a single piece, and 1, for a file whose
+// every cluster is stored apart from the previous one.
+func FragmentationScore(extents []Extent) float64 {
+	var clusters int64
+	for _, e := range extents {
+		if e.Allocated() {
+			clusters += e.Length
+		}
+	}
+	if clusters <= 1 {
+		return 0
+	}
+
+	return float64(Fragments(extents)-1) / float64(clusters-1)
+}
@@ -0,0 +1,88 @@
+package volume
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+
+	"golang.org/x/sys/windows"
+
+	"github.com/go-sw/ntfs/internal/fsctl"
+)
+
+// extentsChunk is the number of extents requested per
+// FSCTL_GET_RETRIEVAL_POINTERS call.
+const extentsChunk = 512
+
+// FileExtents returns the extents of the unnamed data stream of the file
+// at path, in ascending VCN order. Sparse holes and the space saved by
+// NTFS compression are reported as extents without clusters. Files whose
+// data is resident in their MFT record have no extent.
+func FileExtents(path string) ([]Extent, error) {
+	h, err := fsctl.Open(path, windows.FILE_READ_ATTRIBUTES, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer windows.CloseHandle(h)
+
+	extents, err := FileExtentsHandle(h)
+	if err != nil {
+		return nil, &os.PathError{Op: "FSCTL_GET_RETRIEVAL_POINTERS", Path: path, Err: err}
+	}
+
+	return extents, nil
+}
+
+// FileExtentsHandle is like FileExtents but maps the file opened as h.
+func FileExtentsHandle(h windows.Handle) ([]Extent, error) {
+	extents, err := retrievalPointers(h)
+	if err != nil {
+		return nil, err
+	}
+
+	ci, err := fsctl.CompressionInfo(h)
+	if err == nil && ci.CompressionFormat != 0 && ci.CompressionUnitShift > ci.ClusterShift {
+		MarkCompressionUnits(extents, 1<<(ci.CompressionUnitShift-ci.ClusterShift))
+	}
+
+	return extents, nil
+}
+
+// retrievalPointers collects the RETRIEVAL_POINTERS_BUFFER extents of the
+// file opened as h.
+func retrievalPointers(h windows.Handle) ([]Extent, error) {
+	var extents []Extent
+
+	in := make([]byte, 8)
+	out := make([]byte, 16+16*extentsChunk)
+	vcn := int64(0)
+	for {
+		binary.LittleEndian.PutUint64(in, uint64(vcn))
+		_, err := fsctl.Call(h, windows.FSCTL_GET_RETRIEVAL_POINTERS, in, out)
+		if errors.Is(err, windows.ERROR_HANDLE_EOF) {
+			return extents, nil
+		}
+		if err != nil && !errors.Is(err, windows.ERROR_MORE_DATA) {
+			return nil, err
+		}
+
+		count := int(binary.LittleEndian.Uint32(out[0:]))
+		vcn = int64(binary.LittleEndian.Uint64(out[8:]))
+		for i := range count {
+			rec := out[16+16*i:]
+			next := int64(binary.LittleEndian.Uint64(rec[0:]))
+			lcn := int64(binary.LittleEndian.Uint64(rec[8:]))
+
+			e := Extent{VCN: vcn, LCN: lcn, Length: next - vcn}
+			if lcn < 0 {
+				e.LCN, e.Kind = -1, ExtentSparse
+			}
+			extents = append(extents, e)
+			vcn = next
+		}
+
+		if err == nil || count == 0 {
+			return extents, nil
+		}
+	}
+}
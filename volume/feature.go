@@ -0,0 +1,69 @@
+package volume
+
+// Feature is a file system capability which can be probed with Supports.
+type Feature int
+
+const (
+	FeatureNamedStreams Feature = iota
+	FeatureExtendedAttributes
+	FeatureEncryption
+	FeatureCompression
+	FeatureObjectIDs
+	FeatureQuotas
+	FeatureUsnJournal
+	FeatureSparseFiles
+	FeatureReparsePoints
+	FeatureHardLinks
+	FeatureDedup
+)
+
+var featureNames = [...]string{
+	FeatureNamedStreams:       "named streams",
+	FeatureExtendedAttributes: "extended attributes",
+	FeatureEncryption:         "encryption",
+	FeatureCompression:        "compression",
+	FeatureObjectIDs:          "object IDs",
+	FeatureQuotas:             "quotas",
+	FeatureUsnJournal:         "USN journal",
+	FeatureSparseFiles:        "sparse files",
+	FeatureReparsePoints:      "reparse points",
+	FeatureHardLinks:          "hard links",
+	FeatureDedup:              "data deduplication",
+}
+
+func (f Feature) String() string {
+	if f < 0 || int(f) >= len(featureNames) {
+		return "unknown feature"
+	}
+
+	return featureNames[f]
+}
+
+// flag returns the file system flag advertising f, or 0 if f is not
+// advertised through the file system flags.
+func (f Feature) flag() Flags {
+	switch f {
+	case FeatureNamedStreams:
+		return NamedStreams
+	case FeatureExtendedAttributes:
+		return SupportsExtendedAttributes
+	case FeatureEncryption:
+		return SupportsEncryption
+	case FeatureCompression:
+		return FileCompression
+	case FeatureObjectIDs:
+		return SupportsObjectIDs
+	case FeatureQuotas:
+		return VolumeQuotas
+	case FeatureUsnJournal:
+		return SupportsUsnJournal
+	case FeatureSparseFiles:
+		return SupportsSparseFiles
+	case FeatureReparsePoints:
+		return SupportsReparsePoints
+	case FeatureHardLinks:
+		return SupportsHardLinks
+	default:
+		return 0
+	}
+}
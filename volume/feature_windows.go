@@ -0,0 +1,58 @@
+package volume
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/sys/windows"
+)
+
+// dedupDir is created by the Data Deduplication service on every volume
+// it is enabled for.
+const dedupDir = `System Volume Information\Dedup`
+
+// Supports reports whether the volume containing root supports feature.
+// Packages relying on a feature use it to degrade gracefully on volumes
+// formatted with other file systems than NTFS.
+func Supports(root string, feature Feature) (bool, error) {
+	info, err := Info(root)
+	if err != nil {
+		return false, err
+	}
+
+	return info.Supports(feature)
+}
+
+// Supports reports whether the volume supports feature.
+func (i *Information) Supports(feature Feature) (bool, error) {
+	if feature == FeatureDedup {
+		return dedupEnabled(i.Root)
+	}
+
+	flag := feature.flag()
+	if flag == 0 {
+		return false, fmt.Errorf("volume: unknown feature %d", int(feature))
+	}
+
+	return i.Flags.Has(flag), nil
+}
+
+// dedupEnabled reports whether Data Deduplication is enabled on the volume
+// with the given root. The deduplication store is only readable by
+// SYSTEM, so being denied access to it still tells that it exists.
+func dedupEnabled(root string) (bool, error) {
+	p, err := windows.UTF16PtrFromString(root + dedupDir)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = windows.GetFileAttributes(p)
+	switch {
+	case err == nil, errors.Is(err, windows.ERROR_ACCESS_DENIED):
+		return true, nil
+	case errors.Is(err, windows.ERROR_FILE_NOT_FOUND), errors.Is(err, windows.ERROR_PATH_NOT_FOUND):
+		return false, nil
+	default:
+		return false, err
+	}
+}
@@ -0,0 +1,97 @@
+package volume
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Flags are the file system flags reported by GetVolumeInformation.
+//
+// https://learn.microsoft.com/en-us/windows/win32/api/fileapi/nf-fileapi-getvolumeinformationw
+type Flags uint32
+
+const (
+	CaseSensitiveSearch        Flags = 0x00000001 // FILE_CASE_SENSITIVE_SEARCH
+	CasePreservedNames         Flags = 0x00000002 // FILE_CASE_PRESERVED_NAMES
+	UnicodeOnDisk              Flags = 0x00000004 // FILE_UNICODE_ON_DISK
+	PersistentACLs             Flags = 0x00000008 // FILE_PERSISTENT_ACLS
+	FileCompression            Flags = 0x00000010 // FILE_FILE_COMPRESSION
+	VolumeQuotas               Flags = 0x00000020 // FILE_VOLUME_QUOTAS
+	SupportsSparseFiles        Flags = 0x00000040 // FILE_SUPPORTS_SPARSE_FILES
+	SupportsReparsePoints      Flags = 0x00000080 // FILE_SUPPORTS_REPARSE_POINTS
+	SupportsRemoteStorage      Flags = 0x00000100 // FILE_SUPPORTS_REMOTE_STORAGE
+	ReturnsCleanupResultInfo   Flags = 0x00000200 // FILE_RETURNS_CLEANUP_RESULT_INFO
+	SupportsPosixUnlinkRename  Flags = 0x00000400 // FILE_SUPPORTS_POSIX_UNLINK_RENAME
+	SupportsBypassIO           Flags = 0x00000800 // FILE_SUPPORTS_BYPASS_IO
+	VolumeIsCompressed         Flags = 0x00008000 // FILE_VOLUME_IS_COMPRESSED
+	SupportsObjectIDs          Flags = 0x00010000 // FILE_SUPPORTS_OBJECT_IDS
+	SupportsEncryption         Flags = 0x00020000 // FILE_SUPPORTS_ENCRYPTION
+	NamedStreams               Flags = 0x00040000 // FILE_NAMED_STREAMS
+	ReadOnlyVolume             Flags = 0x00080000 // FILE_READ_ONLY_VOLUME
+	SequentialWriteOnce        Flags = 0x00100000 // FILE_SEQUENTIAL_WRITE_ONCE
+	SupportsTransactions       Flags = 0x00200000 // FILE_SUPPORTS_TRANSACTIONS
+	SupportsHardLinks          Flags = 0x00400000 // FILE_SUPPORTS_HARD_LINKS
+	SupportsExtendedAttributes Flags = 0x00800000 // FILE_SUPPORTS_EXTENDED_ATTRIBUTES
+	SupportsOpenByFileID       Flags = 0x01000000 // FILE_SUPPORTS_OPEN_BY_FILE_ID
+	SupportsUsnJournal         Flags = 0x02000000 // FILE_SUPPORTS_USN_JOURNAL
+	SupportsIntegrityStreams   Flags = 0x04000000 // FILE_SUPPORTS_INTEGRITY_STREAMS
+	SupportsBlockRefcounting   Flags = 0x08000000 // FILE_SUPPORTS_BLOCK_REFCOUNTING
+	SupportsSparseVDL          Flags = 0x10000000 // FILE_SUPPORTS_SPARSE_VDL
+	DaxVolume                  Flags = 0x20000000 // FILE_DAX_VOLUME
+	SupportsGhosting           Flags = 0x40000000 // FILE_SUPPORTS_GHOSTING
+)
+
+var flagNames = []struct {
+	flag Flags
+	name string
+}{
+	{CaseSensitiveSearch, "FILE_CASE_SENSITIVE_SEARCH"},
+	{CasePreservedNames, "FILE_CASE_PRESERVED_NAMES"},
+	{UnicodeOnDisk, "FILE_UNICODE_ON_DISK"},
+	{PersistentACLs, "FILE_PERSISTENT_ACLS"},
+	{FileCompression, "FILE_FILE_COMPRESSION"},
+	{VolumeQuotas, "FILE_VOLUME_QUOTAS"},
+	{SupportsSparseFiles, "FILE_SUPPORTS_SPARSE_FILES"},
+	{SupportsReparsePoints, "FILE_SUPPORTS_REPARSE_POINTS"},
+	{SupportsRemoteStorage, "FILE_SUPPORTS_REMOTE_STORAGE"},
+	{ReturnsCleanupResultInfo, "FILE_RETURNS_CLEANUP_RESULT_INFO"},
+	{SupportsPosixUnlinkRename, "FILE_SUPPORTS_POSIX_UNLINK_RENAME"},
+	{SupportsBypassIO, "FILE_SUPPORTS_BYPASS_IO"},
+	{VolumeIsCompressed, "FILE_VOLUME_IS_COMPRESSED"},
+	{SupportsObjectIDs, "FILE_SUPPORTS_OBJECT_IDS"},
+	{SupportsEncryption, "FILE_SUPPORTS_ENCRYPTION"},
+	{NamedStreams, "FILE_NAMED_STREAMS"},
+	{ReadOnlyVolume, "FILE_READ_ONLY_VOLUME"},
+	{SequentialWriteOnce, "FILE_SEQUENTIAL_WRITE_ONCE"},
+	{SupportsTransactions, "FILE_SUPPORTS_TRANSACTIONS"},
+	{SupportsHardLinks, "FILE_SUPPORTS_HARD_LINKS"},
+	{SupportsExtendedAttributes, "FILE_SUPPORTS_EXTENDED_ATTRIBUTES"},
+	{SupportsOpenByFileID, "FILE_SUPPORTS_OPEN_BY_FILE_ID"},
+	{SupportsUsnJournal, "FILE_SUPPORTS_USN_JOURNAL"},
+	{SupportsIntegrityStreams, "FILE_SUPPORTS_INTEGRITY_STREAMS"},
+	{SupportsBlockRefcounting, "FILE_SUPPORTS_BLOCK_REFCOUNTING"},
+	{SupportsSparseVDL, "FILE_SUPPORTS_SPARSE_VDL"},
+	{DaxVolume, "FILE_DAX_VOLUME"},
+	{SupportsGhosting, "FILE_SUPPORTS_GHOSTING"},
+}
+
+// Has reports whether all bits of flag are set in f.
+func (f Flags) Has(flag Flags) bool {
+	return f&flag == flag
+}
+
+// String returns the names of the flags set in f joined with "|".
+func (f Flags) String() string {
+	var names []string
+	for _, n := range flagNames {
+		if f&n.flag != 0 {
+			names = append(names, n.name)
+			f &^= n.flag
+		}
+	}
+	if f != 0 || len(names) == 0 {
+		names = append(names, fmt.Sprintf("0x%X", uint32(f)))
+	}
+
+	return strings.Join(names, "|")
+}
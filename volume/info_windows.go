@@ -0,0 +1,98 @@
+package volume
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// Information describes a mounted volume.
+type Information struct {
+	// Root is the root of the volume, with a trailing backslash.
+	Root string
+	// Label is the volume label.
+	Label string
+	// FileSystem is the name of the file system, such as "NTFS".
+	FileSystem string
+	// SerialNumber is the volume serial number.
+	SerialNumber uint32
+	// MaxComponentLength is the maximum length, in characters, of a
+	// file name component.
+	MaxComponentLength uint32
+	// Flags are the features supported by the file system.
+	Flags Flags
+}
+
+// IsNTFS reports whether the volume is formatted with NTFS.
+func (i *Information) IsNTFS() bool {
+	return i.FileSystem == "NTFS"
+}
+
+// NamedStreams reports whether the volume supports alternate data streams.
+func (i *Information) NamedStreams() bool {
+	return i.Flags.Has(NamedStreams)
+}
+
+// ExtendedAttributes reports whether the volume supports extended attributes.
+func (i *Information) ExtendedAttributes() bool {
+	return i.Flags.Has(SupportsExtendedAttributes)
+}
+
+// Compression reports whether the volume supports per-file compression.
+func (i *Information) Compression() bool {
+	return i.Flags.Has(FileCompression)
+}
+
+// Encryption reports whether the volume supports EFS.
+func (i *Information) Encryption() bool {
+	return i.Flags.Has(SupportsEncryption)
+}
+
+// UsnJournal reports whether the volume supports update sequence number
+// journals.
+func (i *Information) UsnJournal() bool {
+	return i.Flags.Has(SupportsUsnJournal)
+}
+
+// CaseSensitive reports whether the volume supports case-sensitive file
+// names.
+func (i *Information) CaseSensitive() bool {
+	return i.Flags.Has(CaseSensitiveSearch)
+}
+
+// Info returns information about the volume containing root, which may be
+// a drive letter ("C:"), a mounted folder, a volume GUID path or any
+// path on the volume.
+func Info(root string) (*Information, error) {
+	r, err := Root(root)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := windows.UTF16PtrFromString(r)
+	if err != nil {
+		return nil, &os.PathError{Op: "GetVolumeInformation", Path: root, Err: err}
+	}
+
+	var (
+		label   [windows.MAX_PATH + 1]uint16
+		fsName  [windows.MAX_PATH + 1]uint16
+		serial  uint32
+		maxComp uint32
+		flags   uint32
+	)
+	err = windows.GetVolumeInformation(p, &label[0], uint32(len(label)), &serial, &maxComp, &flags,
+		&fsName[0], uint32(len(fsName)))
+	if err != nil {
+		return nil, &os.PathError{Op: "GetVolumeInformation", Path: root, Err: err}
+	}
+
+	return &Information{
+		Root:               r,
+		Label:              windows.UTF16ToString(label[:]),
+		FileSystem:         windows.UTF16ToString(fsName[:]),
+		SerialNumber:       serial,
+		MaxComponentLength: maxComp,
+		Flags:              Flags(flags),
+	}, nil
+}
@@ -0,0 +1,122 @@
+package volume
+
+import (
+	"os"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+
+	"github.com/go-sw/ntfs/internal/fsctl"
+)
+
+// fileFsObjectIDInformation is the FileFsObjectIdInformation class of
+// FS_INFORMATION_CLASS.
+const fileFsObjectIDInformation = 8
+
+// ObjectID is the object identifier of a volume, as stored in
+// FILE_FS_OBJECTID_INFORMATION. Distributed link tracking uses it as the
+// birth volume ID of the files created on the volume.
+type ObjectID struct {
+	ID           windows.GUID
+	ExtendedInfo [48]byte
+}
+
+// NewObjectID returns a new, randomly generated volume object ID.
+func NewObjectID() (*ObjectID, error) {
+	g, err := windows.GenerateGUID()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ObjectID{ID: g}, nil
+}
+
+// Label returns the label of the volume containing root.
+func Label(root string) (string, error) {
+	info, err := Info(root)
+	if err != nil {
+		return "", err
+	}
+
+	return info.Label, nil
+}
+
+// SetLabel sets the label of the volume containing root. An empty label
+// removes it.
+func SetLabel(root, label string) error {
+	r, err := Root(root)
+	if err != nil {
+		return err
+	}
+
+	p, err := windows.UTF16PtrFromString(r)
+	if err != nil {
+		return &os.PathError{Op: "SetVolumeLabel", Path: root, Err: err}
+	}
+
+	var l *uint16
+	if label != "" {
+		if l, err = windows.UTF16PtrFromString(label); err != nil {
+			return &os.PathError{Op: "SetVolumeLabel", Path: root, Err: err}
+		}
+	}
+
+	if err := windows.SetVolumeLabel(p, l); err != nil {
+		return &os.PathError{Op: "SetVolumeLabel", Path: root, Err: err}
+	}
+
+	return nil
+}
+
+// GetObjectID returns the object ID of the volume containing root. It
+// fails with STATUS_OBJECT_NAME_NOT_FOUND if the volume has none.
+func GetObjectID(root string) (*ObjectID, error) {
+	r, err := Root(root)
+	if err != nil {
+		return nil, err
+	}
+
+	// Volume information can be queried through any open file on the
+	// volume, which avoids requiring administrative rights.
+	h, err := fsctl.Open(r, windows.FILE_READ_ATTRIBUTES, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer windows.CloseHandle(h)
+
+	var (
+		id   ObjectID
+		iosb windows.IO_STATUS_BLOCK
+	)
+	err = ntQueryVolumeInformationFile(h, &iosb, (*byte)(unsafe.Pointer(&id)), uint32(unsafe.Sizeof(id)),
+		fileFsObjectIDInformation)
+	if err != nil {
+		return nil, &os.PathError{Op: "NtQueryVolumeInformationFile", Path: root, Err: err}
+	}
+
+	return &id, nil
+}
+
+// SetObjectID stamps the volume containing root with id. This requires
+// administrative rights.
+func SetObjectID(root string, id *ObjectID) error {
+	v, err := Open(root)
+	if err != nil {
+		return err
+	}
+	defer v.Close()
+
+	return v.SetObjectID(id)
+}
+
+// SetObjectID stamps the volume with id.
+func (v *Handle) SetObjectID(id *ObjectID) error {
+	var iosb windows.IO_STATUS_BLOCK
+	err := ntSetVolumeInformationFile(v.h, &iosb, (*byte)(unsafe.Pointer(id)), uint32(unsafe.Sizeof(*id)),
+		fileFsObjectIDInformation)
+	if err != nil {
+		return v.pathError("NtSetVolumeInformationFile", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,177 @@
+package volume
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// Entry describes a volume known to the volume mount manager.
+type Entry struct {
+	// GUIDPath is the volume GUID path, such as
+	// \\?\Volume{26a21bda-a627-11d7-9931-806e6f6e6963}\.
+	GUIDPath string
+	// DriveLetters are the drive letters assigned to the volume, such
+	// as C:\.
+	DriveLetters []string
+	// MountedFolders are the folders the volume is mounted on, other
+	// than drive letters.
+	MountedFolders []string
+	// FileSystem is the name of the file system on the volume, or empty
+	// if the volume is not mounted or contains no media.
+	FileSystem string
+	// Label is the volume label.
+	Label string
+}
+
+// List returns every volume on the system, including volumes without a
+// drive letter or mounted folder.
+func List() ([]Entry, error) {
+	var entries []Entry
+	err := eachVolume(func(guid string) error {
+		e, err := newEntry(guid)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, *e)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// eachVolume calls fn with the GUID path of every volume on the system,
+// until fn returns an error.
+func eachVolume(fn func(guid string) error) error {
+	var buf [50]uint16
+
+	h, err := windows.FindFirstVolume(&buf[0], uint32(len(buf)))
+	if err != nil {
+		return os.NewSyscallError("FindFirstVolume", err)
+	}
+	defer windows.FindVolumeClose(h)
+
+	for {
+		if err := fn(windows.UTF16ToString(buf[:])); err != nil {
+			return err
+		}
+
+		if err := windows.FindNextVolume(h, &buf[0], uint32(len(buf))); err != nil {
+			if errors.Is(err, windows.ERROR_NO_MORE_FILES) {
+				return nil
+			}
+			return os.NewSyscallError("FindNextVolume", err)
+		}
+	}
+}
+
+func newEntry(guid string) (*Entry, error) {
+	paths, err := pathNames(guid)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Entry{GUIDPath: guid}
+	for _, p := range paths {
+		if isDriveRoot(p) {
+			e.DriveLetters = append(e.DriveLetters, p)
+		} else {
+			e.MountedFolders = append(e.MountedFolders, p)
+		}
+	}
+
+	// Volumes without media or with an unrecognized file system cannot
+	// be queried, which is not an error while listing.
+	if info, err := Info(guid); err == nil {
+		e.FileSystem = info.FileSystem
+		e.Label = info.Label
+	}
+
+	return e, nil
+}
+
+// pathNames returns the drive letters and mounted folders of the volume
+// with the given GUID path.
+func pathNames(guid string) ([]string, error) {
+	p, err := windows.UTF16PtrFromString(guid)
+	if err != nil {
+		return nil, &os.PathError{Op: "GetVolumePathNamesForVolumeName", Path: guid, Err: err}
+	}
+
+	n := uint32(windows.MAX_PATH)
+	for {
+		buf := make([]uint16, n)
+		err := windows.GetVolumePathNamesForVolumeName(p, &buf[0], n, &n)
+		if err == nil {
+			return splitMultiSz(buf), nil
+		}
+		if !errors.Is(err, windows.ERROR_MORE_DATA) {
+			return nil, &os.PathError{Op: "GetVolumePathNamesForVolumeName", Path: guid, Err: err}
+		}
+	}
+}
+
+// MountPoints returns the mounted folders located on the volume
+// containing vol, that is, the folders of vol on which other volumes are
+// mounted. Enumerating mounted folders requires administrative rights.
+func MountPoints(vol string) ([]string, error) {
+	root, err := Root(vol)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := windows.UTF16PtrFromString(root)
+	if err != nil {
+		return nil, &os.PathError{Op: "FindFirstVolumeMountPoint", Path: vol, Err: err}
+	}
+
+	buf := make([]uint16, windows.MAX_LONG_PATH)
+	h, err := windows.FindFirstVolumeMountPoint(p, &buf[0], uint32(len(buf)))
+	if err != nil {
+		if errors.Is(err, windows.ERROR_NO_MORE_FILES) {
+			return nil, nil
+		}
+		return nil, &os.PathError{Op: "FindFirstVolumeMountPoint", Path: vol, Err: err}
+	}
+	defer windows.FindVolumeMountPointClose(h)
+
+	var mounts []string
+	for {
+		mounts = append(mounts, root+windows.UTF16ToString(buf))
+
+		if err := windows.FindNextVolumeMountPoint(h, &buf[0], uint32(len(buf))); err != nil {
+			if errors.Is(err, windows.ERROR_NO_MORE_FILES) {
+				return mounts, nil
+			}
+			return nil, &os.PathError{Op: "FindNextVolumeMountPoint", Path: vol, Err: err}
+		}
+	}
+}
+
+// isDriveRoot reports whether p has the form X:\.
+func isDriveRoot(p string) bool {
+	return len(p) == 3 && p[1] == ':' && p[2] == '\\' &&
+		('A' <= p[0] && p[0] <= 'Z' || 'a' <= p[0] && p[0] <= 'z')
+}
+
+// splitMultiSz splits a sequence of NUL terminated strings ending with an
+// empty string.
+func splitMultiSz(buf []uint16) []string {
+	var s []string
+	for start, i := 0, 0; i < len(buf); i++ {
+		if buf[i] != 0 {
+			continue
+		}
+		if i == start {
+			break
+		}
+		s = append(s, windows.UTF16ToString(buf[start:i]))
+		start = i + 1
+	}
+
+	return s
+}
@@ -0,0 +1,222 @@
+package volume
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	"golang.org/x/sys/windows"
+
+	"github.com/go-sw/ntfs/internal/fsctl"
+)
+
+// Volume control codes missing from golang.org/x/sys/windows.
+const (
+	fsctlLockVolume      = 0x00090018 // FSCTL_LOCK_VOLUME
+	fsctlUnlockVolume    = 0x0009001C // FSCTL_UNLOCK_VOLUME
+	fsctlDismountVolume  = 0x00090020 // FSCTL_DISMOUNT_VOLUME
+	ioctlVolumeOnline    = 0x0056C008 // IOCTL_VOLUME_ONLINE
+	ioctlVolumeOffline   = 0x0056C00C // IOCTL_VOLUME_OFFLINE
+	ioctlVolumeIsOffline = 0x00560010 // IOCTL_VOLUME_IS_OFFLINE
+)
+
+// Default LockOptions values.
+const (
+	DefaultLockAttempts = 10
+	DefaultLockDelay    = 500 * time.Millisecond
+)
+
+// LockOptions control how Handle.Lock retries while other handles to the
+// volume are open.
+type LockOptions struct {
+	// Attempts is the number of times locking is tried. It defaults to
+	// DefaultLockAttempts.
+	Attempts int
+	// Delay is the time waited between attempts. It defaults to
+	// DefaultLockDelay.
+	Delay time.Duration
+	// ForceDismount dismounts the volume when it still cannot be locked
+	// after the last attempt. Dismounting invalidates every other open
+	// handle to the volume, which then can be locked.
+	ForceDismount bool
+}
+
+// Handle is an open volume, used to obtain exclusive access to it.
+type Handle struct {
+	h      windows.Handle
+	path   string
+	locked bool
+}
+
+// Open opens the volume containing root for reading and writing. This
+// requires administrative rights.
+func Open(root string) (*Handle, error) {
+	h, err := openVolume(root, windows.GENERIC_READ|windows.GENERIC_WRITE)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Handle{h: h, path: root}, nil
+}
+
+// Fd returns the Windows handle of the volume.
+func (v *Handle) Fd() windows.Handle {
+	return v.h
+}
+
+// Locked reports whether the volume is locked through v.
+func (v *Handle) Locked() bool {
+	return v.locked
+}
+
+// Lock locks the volume, retrying as set by opts while other processes
+// hold it open. A nil opts uses the defaults. Lock returns early with the
+// context error if ctx is done while waiting between attempts.
+func (v *Handle) Lock(ctx context.Context, opts *LockOptions) error {
+	attempts, delay, force := DefaultLockAttempts, DefaultLockDelay, false
+	if opts != nil {
+		if opts.Attempts > 0 {
+			attempts = opts.Attempts
+		}
+		if opts.Delay > 0 {
+			delay = opts.Delay
+		}
+		force = opts.ForceDismount
+	}
+
+	var err error
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			t := time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				t.Stop()
+				return ctx.Err()
+			case <-t.C:
+			}
+		}
+
+		if err = v.control(fsctlLockVolume); err == nil {
+			v.locked = true
+			return nil
+		}
+		if !errors.Is(err, windows.ERROR_ACCESS_DENIED) && !errors.Is(err, windows.ERROR_SHARING_VIOLATION) {
+			return v.pathError("FSCTL_LOCK_VOLUME", err)
+		}
+	}
+
+	if force {
+		if err := v.Dismount(); err != nil {
+			return err
+		}
+		if err = v.control(fsctlLockVolume); err == nil {
+			v.locked = true
+			return nil
+		}
+	}
+
+	return v.pathError("FSCTL_LOCK_VOLUME", err)
+}
+
+// Unlock unlocks a volume locked with Lock.
+func (v *Handle) Unlock() error {
+	if err := v.control(fsctlUnlockVolume); err != nil {
+		return v.pathError("FSCTL_UNLOCK_VOLUME", err)
+	}
+	v.locked = false
+
+	return nil
+}
+
+// Dismount dismounts the volume. Open handles to files on the volume
+// become invalid, and the file system is mounted again on the next
+// access. Dismounting a volume which is not locked forcibly closes every
+// other handle to it.
+func (v *Handle) Dismount() error {
+	if err := v.control(fsctlDismountVolume); err != nil {
+		return v.pathError("FSCTL_DISMOUNT_VOLUME", err)
+	}
+
+	return nil
+}
+
+// Offline takes the volume offline, making it inaccessible until Online
+// is called.
+func (v *Handle) Offline() error {
+	if err := v.control(ioctlVolumeOffline); err != nil {
+		return v.pathError("IOCTL_VOLUME_OFFLINE", err)
+	}
+
+	return nil
+}
+
+// Online brings an offline volume back online.
+func (v *Handle) Online() error {
+	if err := v.control(ioctlVolumeOnline); err != nil {
+		return v.pathError("IOCTL_VOLUME_ONLINE", err)
+	}
+
+	return nil
+}
+
+// IsOffline reports whether the volume is offline.
+func (v *Handle) IsOffline() (bool, error) {
+	err := v.control(ioctlVolumeIsOffline)
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, windows.ERROR_GEN_FAILURE):
+		// IOCTL_VOLUME_IS_OFFLINE fails with STATUS_UNSUCCESSFUL for
+		// volumes which are online.
+		return false, nil
+	default:
+		return false, v.pathError("IOCTL_VOLUME_IS_OFFLINE", err)
+	}
+}
+
+// Close unlocks the volume if needed and closes the handle.
+func (v *Handle) Close() error {
+	if v.h == windows.InvalidHandle {
+		return os.ErrClosed
+	}
+
+	// Closing the handle releases the lock as well.
+	err := windows.CloseHandle(v.h)
+	v.h, v.locked = windows.InvalidHandle, false
+
+	return err
+}
+
+// Exclusive locks the volume containing root, calls fn and unlocks the
+// volume, as needed by tools which must be the sole user of a volume.
+func Exclusive(ctx context.Context, root string, opts *LockOptions, fn func(v *Handle) error) error {
+	v, err := Open(root)
+	if err != nil {
+		return err
+	}
+	defer v.Close()
+
+	if err := v.Lock(ctx, opts); err != nil {
+		return err
+	}
+
+	if err := fn(v); err != nil {
+		return err
+	}
+
+	if v.locked {
+		return v.Unlock()
+	}
+
+	return nil
+}
+
+func (v *Handle) control(code uint32) error {
+	_, err := fsctl.Call(v.h, code, nil, nil)
+	return err
+}
+
+func (v *Handle) pathError(op string, err error) error {
+	return &os.PathError{Op: op, Path: v.path, Err: err}
+}
@@ -0,0 +1,164 @@
+package volume
+
+import (
+	"errors"
+	"os"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+
+	"github.com/go-sw/ntfs/internal/fsctl"
+)
+
+// FSCTL_MOVE_FILE is missing from golang.org/x/sys/windows.
+const fsctlMoveFile = 0x00090074
+
+// volumeNameGUID is VOLUME_NAME_GUID for GetFinalPathNameByHandle.
+const volumeNameGUID = 0x1
+
+// freeSpaceChunk is the number of clusters of the volume bitmap examined
+// at once while looking for free space.
+const freeSpaceChunk = 64 << 20
+
+var ErrNoFreeSpace = errors.New("volume: no free run large enough for the file")
+
+// moveFileData is MOVE_FILE_DATA, padded to its C layout on 32-bit
+// platforms.
+type moveFileData struct {
+	FileHandle   windows.Handle
+	_            [8 - unsafe.Sizeof(windows.Handle(0))]byte
+	StartingVcn  int64
+	StartingLcn  int64
+	ClusterCount uint32
+	_            uint32
+}
+
+// MoveFileClusters moves count clusters of the file opened as file,
+// starting at the virtual cluster startVCN, to the free clusters of its
+// volume starting at targetLCN. The file handle needs FILE_READ_ATTRIBUTES
+// access, and moving clusters requires administrative rights.
+func MoveFileClusters(file windows.Handle, startVCN, targetLCN int64, count uint32) error {
+	v, err := openFileVolume(file)
+	if err != nil {
+		return err
+	}
+	defer v.Close()
+
+	return v.MoveFileClusters(file, startVCN, targetLCN, count)
+}
+
+// MoveFileClusters is like the MoveFileClusters function but uses v,
+// which must be the volume of the file.
+func (v *Handle) MoveFileClusters(file windows.Handle, startVCN, targetLCN int64, count uint32) error {
+	in := moveFileData{
+		FileHandle:   file,
+		StartingVcn:  startVCN,
+		StartingLcn:  targetLCN,
+		ClusterCount: count,
+	}
+	if err := fsctl.CallStruct[moveFileData, byte](v.h, fsctlMoveFile, &in, nil); err != nil {
+		return v.pathError("FSCTL_MOVE_FILE", err)
+	}
+
+	return nil
+}
+
+// Consolidate moves the allocated clusters of the file at path into the
+// largest free run of its volume, leaving the file in a single fragment.
+// It does nothing if the file already is contiguous, and returns
+// ErrNoFreeSpace if the largest free run is too small to hold the file.
+func Consolidate(path string) error {
+	f, err := fsctl.Open(path, windows.FILE_READ_ATTRIBUTES|windows.SYNCHRONIZE, 0)
+	if err != nil {
+		return err
+	}
+	defer windows.CloseHandle(f)
+
+	extents, err := FileExtentsHandle(f)
+	if err != nil {
+		return &os.PathError{Op: "FSCTL_GET_RETRIEVAL_POINTERS", Path: path, Err: err}
+	}
+	if Fragments(extents) <= 1 {
+		return nil
+	}
+
+	var clusters int64
+	for _, e := range extents {
+		if e.Allocated() {
+			clusters += e.Length
+		}
+	}
+
+	v, err := openFileVolume(f)
+	if err != nil {
+		return err
+	}
+	defer v.Close()
+
+	run, err := v.LargestFreeRun()
+	if err != nil {
+		return err
+	}
+	if run.Length < clusters {
+		return &os.PathError{Op: "Consolidate", Path: path, Err: ErrNoFreeSpace}
+	}
+
+	lcn := run.LCN
+	for _, e := range extents {
+		if !e.Allocated() {
+			continue
+		}
+		// FSCTL_MOVE_FILE takes a 32-bit cluster count.
+		for vcn, left := e.VCN, e.Length; left > 0; {
+			n := min(left, 1<<31)
+			if err := v.MoveFileClusters(f, vcn, lcn, uint32(n)); err != nil {
+				return err
+			}
+			vcn, lcn, left = vcn+n, lcn+n, left-n
+		}
+	}
+
+	return nil
+}
+
+// LargestFreeRun returns the longest run of free clusters on the volume.
+func (v *Handle) LargestFreeRun() (Run, error) {
+	var best, cur Run
+	for lcn := int64(0); ; {
+		b, err := BitmapHandle(v.h, lcn, freeSpaceChunk)
+		if err != nil {
+			return Run{}, v.pathError("FSCTL_GET_VOLUME_BITMAP", err)
+		}
+
+		for r := range b.FreeRuns() {
+			// Join free runs spanning the boundary between two chunks.
+			if cur.Length > 0 && cur.LCN+cur.Length == r.LCN {
+				cur.Length += r.Length
+			} else {
+				cur = r
+			}
+			if cur.Length > best.Length {
+				best = cur
+			}
+		}
+
+		lcn += b.Clusters
+		if lcn >= b.total || b.Clusters == 0 {
+			return best, nil
+		}
+	}
+}
+
+// openFileVolume opens the volume holding the file opened as file.
+func openFileVolume(file windows.Handle) (*Handle, error) {
+	buf := make([]uint16, windows.MAX_LONG_PATH)
+	n, err := windows.GetFinalPathNameByHandle(file, &buf[0], uint32(len(buf)), volumeNameGUID)
+	if err != nil {
+		return nil, os.NewSyscallError("GetFinalPathNameByHandle", err)
+	}
+	if int(n) < guidPathLen {
+		return nil, os.NewSyscallError("GetFinalPathNameByHandle", windows.ERROR_BAD_PATHNAME)
+	}
+
+	return Open(windows.UTF16ToString(buf[:guidPathLen]))
+}
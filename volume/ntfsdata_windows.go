@@ -0,0 +1,146 @@
+package volume
+
+import (
+	"os"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+
+	"github.com/go-sw/ntfs/internal/fsctl"
+)
+
+// NtfsVolumeData holds the NTFS_VOLUME_DATA_BUFFER of a volume.
+//
+// https://learn.microsoft.com/en-us/windows/win32/api/winioctl/ns-winioctl-ntfs_volume_data_buffer
+type NtfsVolumeData struct {
+	VolumeSerialNumber           int64
+	NumberSectors                int64
+	TotalClusters                int64
+	FreeClusters                 int64
+	TotalReserved                int64
+	BytesPerSector               uint32
+	BytesPerCluster              uint32
+	BytesPerFileRecordSegment    uint32
+	ClustersPerFileRecordSegment uint32
+	MftValidDataLength           int64
+	MftStartLcn                  int64
+	Mft2StartLcn                 int64
+	MftZoneStart                 int64
+	MftZoneEnd                   int64
+
+	// Extended is the NTFS_EXTENDED_VOLUME_DATA following the buffer, or
+	// nil if the file system did not return it.
+	Extended *NtfsExtendedVolumeData
+}
+
+// NtfsExtendedVolumeData holds the NTFS_EXTENDED_VOLUME_DATA of a volume.
+//
+// https://learn.microsoft.com/en-us/windows/win32/api/winioctl/ns-winioctl-ntfs_extended_volume_data
+type NtfsExtendedVolumeData struct {
+	ByteCount                uint32
+	MajorVersion             uint16
+	MinorVersion             uint16
+	BytesPerPhysicalSector   uint32
+	LfsMajorVersion          uint16
+	LfsMinorVersion          uint16
+	MaxDeviceTrimExtentCount uint32
+	MaxDeviceTrimByteCount   uint32
+	MaxVolumeTrimExtentCount uint32
+	MaxVolumeTrimByteCount   uint32
+}
+
+// ntfsVolumeDataBuffer is the layout of NTFS_VOLUME_DATA_BUFFER followed by
+// NTFS_EXTENDED_VOLUME_DATA, as returned by FSCTL_GET_NTFS_VOLUME_DATA.
+type ntfsVolumeDataBuffer struct {
+	VolumeSerialNumber           int64
+	NumberSectors                int64
+	TotalClusters                int64
+	FreeClusters                 int64
+	TotalReserved                int64
+	BytesPerSector               uint32
+	BytesPerCluster              uint32
+	BytesPerFileRecordSegment    uint32
+	ClustersPerFileRecordSegment uint32
+	MftValidDataLength           int64
+	MftStartLcn                  int64
+	Mft2StartLcn                 int64
+	MftZoneStart                 int64
+	MftZoneEnd                   int64
+	Extended                     NtfsExtendedVolumeData
+}
+
+// TotalBytes returns the size of the volume in bytes.
+func (d *NtfsVolumeData) TotalBytes() int64 {
+	return d.TotalClusters * int64(d.BytesPerCluster)
+}
+
+// FreeBytes returns the number of unallocated bytes on the volume.
+func (d *NtfsVolumeData) FreeBytes() int64 {
+	return d.FreeClusters * int64(d.BytesPerCluster)
+}
+
+// MftRecords returns the number of records in the valid part of the MFT.
+func (d *NtfsVolumeData) MftRecords() int64 {
+	if d.BytesPerFileRecordSegment == 0 {
+		return 0
+	}
+
+	return d.MftValidDataLength / int64(d.BytesPerFileRecordSegment)
+}
+
+// MftZoneClusters returns the number of clusters reserved for the MFT zone.
+func (d *NtfsVolumeData) MftZoneClusters() int64 {
+	return d.MftZoneEnd - d.MftZoneStart
+}
+
+// NtfsData returns the NTFS layout of the volume containing root. The
+// volume must be formatted with NTFS, and querying it usually requires
+// administrative rights.
+func NtfsData(root string) (*NtfsVolumeData, error) {
+	h, err := openVolume(root, windows.FILE_READ_ATTRIBUTES)
+	if err != nil {
+		return nil, err
+	}
+	defer windows.CloseHandle(h)
+
+	d, err := NtfsDataHandle(h)
+	if err != nil {
+		return nil, &os.PathError{Op: "FSCTL_GET_NTFS_VOLUME_DATA", Path: root, Err: err}
+	}
+
+	return d, nil
+}
+
+// NtfsDataHandle returns the NTFS layout of the volume opened as h.
+func NtfsDataHandle(h windows.Handle) (*NtfsVolumeData, error) {
+	var buf ntfsVolumeDataBuffer
+	out := unsafe.Slice((*byte)(unsafe.Pointer(&buf)), unsafe.Sizeof(buf))
+
+	n, err := fsctl.Call(h, windows.FSCTL_GET_NTFS_VOLUME_DATA, nil, out)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &NtfsVolumeData{
+		VolumeSerialNumber:           buf.VolumeSerialNumber,
+		NumberSectors:                buf.NumberSectors,
+		TotalClusters:                buf.TotalClusters,
+		FreeClusters:                 buf.FreeClusters,
+		TotalReserved:                buf.TotalReserved,
+		BytesPerSector:               buf.BytesPerSector,
+		BytesPerCluster:              buf.BytesPerCluster,
+		BytesPerFileRecordSegment:    buf.BytesPerFileRecordSegment,
+		ClustersPerFileRecordSegment: buf.ClustersPerFileRecordSegment,
+		MftValidDataLength:           buf.MftValidDataLength,
+		MftStartLcn:                  buf.MftStartLcn,
+		Mft2StartLcn:                 buf.Mft2StartLcn,
+		MftZoneStart:                 buf.MftZoneStart,
+		MftZoneEnd:                   buf.MftZoneEnd,
+	}
+	if n > int(unsafe.Offsetof(buf.Extended)) && buf.Extended.ByteCount > 0 {
+		ext := buf.Extended
+		d.Extended = &ext
+	}
+
+	return d, nil
+}
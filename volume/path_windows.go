@@ -0,0 +1,242 @@
+package volume
+
+import (
+	"errors"
+	"os"
+	"strings"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	ErrNoDriveLetter = errors.New("volume: no drive letter assigned to the volume")
+	ErrNotMounted    = errors.New("volume: volume has no drive letter or mounted folder")
+	ErrUnknownDevice = errors.New("volume: no volume matches the device name")
+)
+
+// guidPathLen is the length of a volume GUID path with its trailing
+// backslash, \\?\Volume{xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx}\.
+const guidPathLen = 49
+
+// Root returns the root of the volume containing path, with a trailing
+// backslash, as accepted by the volume management functions.
+func Root(path string) (string, error) {
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return "", &os.PathError{Op: "GetVolumePathName", Path: path, Err: err}
+	}
+
+	buf := make([]uint16, windows.MAX_LONG_PATH)
+	if err := windows.GetVolumePathName(p, &buf[0], uint32(len(buf))); err != nil {
+		return "", &os.PathError{Op: "GetVolumePathName", Path: path, Err: err}
+	}
+
+	return windows.UTF16ToString(buf), nil
+}
+
+// GUIDPath returns the volume GUID path, with a trailing backslash, of the
+// volume containing path. path may be a drive letter ("C:"), a mounted
+// folder or any path on the volume.
+func GUIDPath(path string) (string, error) {
+	r, err := Root(path)
+	if err != nil {
+		return "", err
+	}
+
+	p, err := windows.UTF16PtrFromString(r)
+	if err != nil {
+		return "", &os.PathError{Op: "GetVolumeNameForVolumeMountPoint", Path: path, Err: err}
+	}
+
+	var buf [guidPathLen + 1]uint16
+	if err := windows.GetVolumeNameForVolumeMountPoint(p, &buf[0], uint32(len(buf))); err != nil {
+		return "", &os.PathError{Op: "GetVolumeNameForVolumeMountPoint", Path: path, Err: err}
+	}
+
+	return windows.UTF16ToString(buf[:]), nil
+}
+
+// DriveLetter returns the drive letter, such as "C:", assigned to the
+// volume containing path. It returns ErrNoDriveLetter if the volume is
+// only reachable through a mounted folder or its GUID path.
+func DriveLetter(path string) (string, error) {
+	g, err := GUIDPath(path)
+	if err != nil {
+		return "", err
+	}
+
+	paths, err := pathNames(g)
+	if err != nil {
+		return "", err
+	}
+	for _, p := range paths {
+		if isDriveRoot(p) {
+			return p[:2], nil
+		}
+	}
+
+	return "", &os.PathError{Op: "DriveLetter", Path: path, Err: ErrNoDriveLetter}
+}
+
+// DeviceName returns the NT device name, such as
+// \Device\HarddiskVolume3, of the volume containing path.
+func DeviceName(path string) (string, error) {
+	g, err := GUIDPath(path)
+	if err != nil {
+		return "", err
+	}
+
+	return queryDosDevice(strings.TrimSuffix(strings.TrimPrefix(g, `\\?\`), `\`))
+}
+
+// DeviceVolume returns the volume GUID path, with a trailing backslash,
+// of the volume with the NT device name device.
+func DeviceVolume(device string) (string, error) {
+	var found string
+	errFound := errors.New("found")
+
+	err := eachVolume(func(guid string) error {
+		name, err := queryDosDevice(strings.TrimSuffix(strings.TrimPrefix(guid, `\\?\`), `\`))
+		if err == nil && strings.EqualFold(name, device) {
+			found = guid
+			return errFound
+		}
+		return nil
+	})
+	switch {
+	case found != "":
+		return found, nil
+	case err != nil:
+		return "", err
+	default:
+		return "", &os.PathError{Op: "DeviceVolume", Path: device, Err: ErrUnknownDevice}
+	}
+}
+
+// ToGUIDPath rewrites path so that it goes through the volume GUID path
+// of its volume. path may start with a drive letter, a mounted folder, a
+// volume GUID path or an NT device name, with or without the
+// \\?\GLOBALROOT prefix.
+func ToGUIDPath(path string) (string, error) {
+	if device, rest, ok := splitDevicePath(path); ok {
+		g, err := DeviceVolume(device)
+		if err != nil {
+			return "", err
+		}
+		return g + strings.TrimPrefix(rest, `\`), nil
+	}
+
+	if hasPrefixFold(path, `\??\`) {
+		path = `\\?\` + path[4:]
+	}
+
+	full, err := windows.FullPath(path)
+	if err != nil {
+		return "", &os.PathError{Op: "GetFullPathName", Path: path, Err: err}
+	}
+
+	r, err := Root(full)
+	if err != nil {
+		return "", err
+	}
+	g, err := GUIDPath(r)
+	if err != nil {
+		return "", err
+	}
+
+	rest, ok := trimRoot(full, r)
+	if !ok {
+		return "", &os.PathError{Op: "ToGUIDPath", Path: path, Err: windows.ERROR_BAD_PATHNAME}
+	}
+
+	return g + rest, nil
+}
+
+// ToMountPath is the inverse of ToGUIDPath: it rewrites path so that it
+// goes through the drive letter of its volume or, lacking one, through
+// the first folder the volume is mounted on. It returns ErrNotMounted if
+// the volume is not mounted anywhere.
+func ToMountPath(path string) (string, error) {
+	g, err := ToGUIDPath(path)
+	if err != nil {
+		return "", err
+	}
+
+	paths, err := pathNames(g[:guidPathLen])
+	if err != nil {
+		return "", err
+	}
+	if len(paths) == 0 {
+		return "", &os.PathError{Op: "ToMountPath", Path: path, Err: ErrNotMounted}
+	}
+
+	mount := paths[0]
+	for _, p := range paths {
+		if isDriveRoot(p) {
+			mount = p
+			break
+		}
+	}
+
+	return mount + g[guidPathLen:], nil
+}
+
+// queryDosDevice returns the target of the MS-DOS device name.
+func queryDosDevice(name string) (string, error) {
+	p, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return "", &os.PathError{Op: "QueryDosDevice", Path: name, Err: err}
+	}
+
+	buf := make([]uint16, windows.MAX_PATH)
+	for {
+		_, err := windows.QueryDosDevice(p, &buf[0], uint32(len(buf)))
+		if err == nil {
+			return windows.UTF16ToString(buf), nil
+		}
+		if !errors.Is(err, windows.ERROR_INSUFFICIENT_BUFFER) || len(buf) >= windows.MAX_LONG_PATH {
+			return "", &os.PathError{Op: "QueryDosDevice", Path: name, Err: err}
+		}
+		buf = make([]uint16, 2*len(buf))
+	}
+}
+
+// splitDevicePath splits a path starting with an NT device name, such as
+// \Device\HarddiskVolume3\dir\file, into the device name and the rest of
+// the path.
+func splitDevicePath(path string) (device, rest string, ok bool) {
+	for _, prefix := range []string{`\\?\GLOBALROOT`, `\\.\GLOBALROOT`, `\??\GLOBALROOT`} {
+		if hasPrefixFold(path, prefix) {
+			path = path[len(prefix):]
+			break
+		}
+	}
+	if !hasPrefixFold(path, `\Device\`) {
+		return "", "", false
+	}
+
+	i := strings.IndexByte(path[len(`\Device\`):], '\\')
+	if i < 0 {
+		return path, "", true
+	}
+	i += len(`\Device\`)
+
+	return path[:i], path[i:], true
+}
+
+// trimRoot returns path relative to the volume root r, which may lack the
+// \\?\ prefix carried by path.
+func trimRoot(path, r string) (string, bool) {
+	if hasPrefixFold(path, r) {
+		return path[len(r):], true
+	}
+	if hasPrefixFold(path, `\\?\`) && hasPrefixFold(path[4:], r) {
+		return path[4+len(r):], true
+	}
+
+	return "", false
+}
+
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
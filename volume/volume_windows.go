@@ -0,0 +1,21 @@
+package volume
+
+import (
+	"strings"
+
+	"golang.org/x/sys/windows"
+
+	"github.com/go-sw/ntfs/internal/fsctl"
+)
+
+// openVolume opens the volume containing path with the given access.
+func openVolume(path string, access uint32) (windows.Handle, error) {
+	g, err := GUIDPath(path)
+	if err != nil {
+		return windows.InvalidHandle, err
+	}
+
+	// The volume itself is opened without the trailing backslash, which
+	// would otherwise refer to its root directory.
+	return fsctl.Open(strings.TrimSuffix(g, `\`), access, 0)
+}
@@ -0,0 +1,213 @@
+package vss
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"runtime"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// Error is a failed VSS operation, with the HRESULT it returned.
+type Error struct {
+	Op      string
+	HRESULT uint32
+}
+
+// errorNames are the names of the VSS_E_* HRESULTs, which the system
+// message tables do not describe.
+var errorNames = map[uint32]string{
+	0x80042301: "VSS_E_BAD_STATE",
+	0x80042302: "VSS_E_UNEXPECTED",
+	0x80042304: "VSS_E_PROVIDER_NOT_REGISTERED",
+	0x80042306: "VSS_E_PROVIDER_VETO",
+	0x80042307: "VSS_E_PROVIDER_IN_USE",
+	0x80042308: "VSS_E_OBJECT_NOT_FOUND",
+	0x8004230C: "VSS_E_VOLUME_NOT_SUPPORTED",
+	0x8004230E: "VSS_E_VOLUME_NOT_SUPPORTED_BY_PROVIDER",
+	0x8004230F: "VSS_E_UNEXPECTED_PROVIDER_ERROR",
+	0x80042312: "VSS_E_MAXIMUM_NUMBER_OF_VOLUMES_REACHED",
+	0x80042313: "VSS_E_FLUSH_WRITES_TIMEOUT",
+	0x80042314: "VSS_E_HOLD_WRITES_TIMEOUT",
+	0x80042315: "VSS_E_UNEXPECTED_WRITER_ERROR",
+	0x80042316: "VSS_E_SNAPSHOT_SET_IN_PROGRESS",
+	0x80042317: "VSS_E_MAXIMUM_NUMBER_OF_SNAPSHOTS_REACHED",
+	0x80042318: "VSS_E_WRITER_INFRASTRUCTURE",
+	0x80042319: "VSS_E_WRITER_NOT_RESPONDING",
+	0x8004231F: "VSS_E_INSUFFICIENT_STORAGE",
+}
+
+func (e *Error) Error() string {
+	if name, ok := errorNames[e.HRESULT]; ok {
+		return "vss: " + e.Op + ": " + name
+	}
+	if err := e.Unwrap(); err != nil {
+		return "vss: " + e.Op + ": " + err.Error()
+	}
+
+	return fmt.Sprintf("vss: %s: HRESULT 0x%08X", e.Op, e.HRESULT)
+}
+
+// Unwrap returns the Windows error of HRESULTs of FACILITY_WIN32, so that
+// errors.Is(err, windows.ERROR_ACCESS_DENIED) holds for E_ACCESSDENIED.
+func (e *Error) Unwrap() error {
+	if e.HRESULT&0xFFFF0000 == 0x80070000 {
+		return windows.Errno(e.HRESULT & 0xFFFF)
+	}
+
+	return nil
+}
+
+// check returns the error of op if hr is a failure.
+func check(op string, hr uint32) error {
+	if int32(hr) < 0 {
+		return &Error{Op: op, HRESULT: hr}
+	}
+
+	return nil
+}
+
+// object is a COM interface, which starts with a pointer to its table of
+// methods.
+type object struct {
+	vtbl *[64]uintptr
+}
+
+// IUnknown methods.
+const (
+	methodRelease = 2
+)
+
+// call calls the method at index method of the table of o, and returns
+// its HRESULT.
+func (o *object) call(method int, args ...uintptr) uint32 {
+	r, _, _ := syscall.SyscallN(o.vtbl[method], append([]uintptr{uintptr(unsafe.Pointer(o))}, args...)...)
+
+	return uint32(r)
+}
+
+func (o *object) release() {
+	o.call(methodRelease)
+}
+
+// guidArgs returns the arguments passing *g by value, which is passed by
+// reference on amd64, in two registers on arm64 and as four words on
+// 32-bit platforms. The caller keeps g alive until the call returns.
+func guidArgs(g *windows.GUID) []uintptr {
+	b := (*[16]byte)(unsafe.Pointer(g))
+	switch runtime.GOARCH {
+	case "amd64":
+		return []uintptr{uintptr(unsafe.Pointer(g))}
+	case "arm64":
+		return []uintptr{uintptr(binary.LittleEndian.Uint64(b[0:])), uintptr(binary.LittleEndian.Uint64(b[8:]))}
+	default:
+		return []uintptr{
+			uintptr(binary.LittleEndian.Uint32(b[0:])), uintptr(binary.LittleEndian.Uint32(b[4:])),
+			uintptr(binary.LittleEndian.Uint32(b[8:])), uintptr(binary.LittleEndian.Uint32(b[12:])),
+		}
+	}
+}
+
+func boolArg(b bool) uintptr {
+	if b {
+		return 1
+	}
+
+	return 0
+}
+
+// IVssAsync methods.
+const (
+	asyncCancel      = 3
+	asyncWait        = 4
+	asyncQueryStatus = 5
+)
+
+const (
+	vssAsyncPending  = 0x00042309 // VSS_S_ASYNC_PENDING
+	vssAsyncFinished = 0x0004230A // VSS_S_ASYNC_FINISHED
+	vssAsyncCanceled = 0x0004230B // VSS_S_ASYNC_CANCELLED
+)
+
+// asyncPoll is how long wait blocks in IVssAsync::Wait between checks of
+// its context.
+const asyncPoll = 100
+
+// wait waits for the operation op tracked by the IVssAsync a to complete,
+// cancelling it if ctx is done first, and releases a.
+func wait(ctx context.Context, op string, a *object) error {
+	defer a.release()
+
+	for {
+		a.call(asyncWait, asyncPoll)
+
+		var status uint32
+		if err := check(op, a.call(asyncQueryStatus, uintptr(unsafe.Pointer(&status)), 0)); err != nil {
+			return err
+		}
+		switch status {
+		case vssAsyncFinished:
+			return nil
+		case vssAsyncCanceled:
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			return &Error{Op: op, HRESULT: status}
+		case vssAsyncPending:
+			if err := ctx.Err(); err != nil {
+				a.call(asyncCancel)
+				return err
+			}
+		default:
+			return check(op, status)
+		}
+	}
+}
+
+// apartment runs COM calls on a thread of the multithreaded apartment,
+// which is kept initialized for as long as the objects created on it are
+// used.
+type apartment struct {
+	calls chan func()
+}
+
+func newApartment() (*apartment, error) {
+	a := &apartment{calls: make(chan func())}
+	errc := make(chan error, 1)
+	go func() {
+		// The thread is left locked, so that it exits with the goroutine.
+		runtime.LockOSThread()
+		if err := windows.CoInitializeEx(0, windows.COINIT_MULTITHREADED); err != nil {
+			errc <- &Error{Op: "CoInitializeEx", HRESULT: uint32(err.(syscall.Errno))}
+			return
+		}
+		errc <- nil
+
+		for fn := range a.calls {
+			fn()
+		}
+		windows.CoUninitialize()
+	}()
+	if err := <-errc; err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// do runs fn on the thread of the apartment.
+func (a *apartment) do(fn func() error) error {
+	errc := make(chan error, 1)
+	a.calls <- func() {
+		errc <- fn()
+	}
+
+	return <-errc
+}
+
+func (a *apartment) close() {
+	close(a.calls)
+}
@@ -0,0 +1,5 @@
+// Package vss creates and lists Volume Shadow Copy snapshots, which give
+// consistent read-only views of volumes in use, through the
+// IVssBackupComponents interface of the Volume Shadow Copy Service.
+// Creating snapshots requires administrative rights.
+package vss
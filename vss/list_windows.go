@@ -0,0 +1,100 @@
+package vss
+
+import (
+	"runtime"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+
+	"github.com/go-sw/ntfs/volume"
+)
+
+// IVssEnumObject methods.
+const enumNext = 3
+
+// objectProp is VSS_OBJECT_PROP holding a VSS_SNAPSHOT_PROP, the largest
+// member of its union.
+type objectProp struct {
+	Type int32
+	_    int32
+	Snap snapshotProp
+}
+
+// List returns the existing snapshots of the volume containing vol, or of
+// every volume if vol is empty, whatever their context, so that backups
+// can reuse scheduled snapshots rather than create new ones. The files of
+// a snapshot are found below its Device.
+func List(vol string) ([]Info, error) {
+	var guid string
+	if vol != "" {
+		var err error
+		if guid, err = volume.GUIDPath(vol); err != nil {
+			return nil, err
+		}
+	}
+
+	apt, err := newApartment()
+	if err != nil {
+		return nil, err
+	}
+	defer apt.close()
+
+	var infos []Info
+	err = apt.do(func() error {
+		var comp *object
+		if err := check("CreateVssBackupComponents", createVssBackupComponents(&comp)); err != nil {
+			return err
+		}
+		defer comp.release()
+
+		if err := check("InitializeForBackup", comp.call(methodInitializeForBackup, 0)); err != nil {
+			return err
+		}
+		if err := check("SetContext", comp.call(methodSetContext, vssCtxAll)); err != nil {
+			return err
+		}
+
+		var (
+			none windows.GUID
+			enum *object
+		)
+		args := append(guidArgs(&none), vssObjectNone, vssObjectSnapshot, uintptr(unsafe.Pointer(&enum)))
+		hr := comp.call(methodQuery, args...)
+		runtime.KeepAlive(&none)
+		if err := check("Query", hr); err != nil {
+			return err
+		}
+		// Query succeeds without an enumerator when there are no
+		// snapshots.
+		if enum == nil {
+			return nil
+		}
+		defer enum.release()
+
+		for {
+			var (
+				prop    objectProp
+				fetched uint32
+			)
+			hr := enum.call(enumNext, 1, uintptr(unsafe.Pointer(&prop)), uintptr(unsafe.Pointer(&fetched)))
+			if err := check("IVssEnumObject::Next", hr); err != nil {
+				return err
+			}
+			if fetched == 0 {
+				return nil
+			}
+
+			info := prop.Snap.info()
+			vssFreeSnapshotProperties(&prop.Snap)
+			if guid == "" || strings.EqualFold(info.Volume, guid) {
+				infos = append(infos, info)
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return infos, nil
+}
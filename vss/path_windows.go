@@ -0,0 +1,44 @@
+package vss
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-sw/ntfs/volume"
+)
+
+// ErrOtherVolume is returned by PathInSnapshot for paths which are not on
+// the volume of the snapshot.
+var ErrOtherVolume = errors.New("vss: path is not on the volume of the snapshot")
+
+// PathInSnapshot returns the path of the file at path, on the volume of
+// snap, in the snapshot, so that files locked on the live volume can be
+// read consistently. path may go through any mount point of the volume.
+func PathInSnapshot(snap Info, path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	guid, err := volume.GUIDPath(abs)
+	if err != nil {
+		return "", err
+	}
+	if !strings.EqualFold(guid, snap.Volume) {
+		return "", &os.PathError{Op: "vss", Path: path, Err: ErrOtherVolume}
+	}
+
+	root, err := volume.Root(abs)
+	if err != nil {
+		return "", err
+	}
+	// The root has a trailing backslash, which the path may lack.
+	abs = strings.TrimSuffix(abs, `\`) + `\`
+	if len(abs) < len(root) || !strings.EqualFold(abs[:len(root)], root) {
+		return "", &os.PathError{Op: "vss", Path: path, Err: ErrOtherVolume}
+	}
+
+	return snap.Device + `\` + strings.TrimSuffix(abs[len(root):], `\`), nil
+}
@@ -0,0 +1,362 @@
+package vss
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+
+	"github.com/go-sw/ntfs/volume"
+)
+
+// IVssBackupComponents methods.
+const (
+	methodInitializeForBackup   = 5
+	methodSetBackupState        = 6
+	methodGatherWriterMetadata  = 9
+	methodFreeWriterMetadata    = 12
+	methodPrepareForBackup      = 14
+	methodAbortBackup           = 15
+	methodBackupComplete        = 27
+	methodSetContext            = 35
+	methodStartSnapshotSet      = 36
+	methodAddToSnapshotSet      = 37
+	methodDoSnapshotSet         = 38
+	methodDeleteSnapshots       = 39
+	methodGetSnapshotProperties = 42
+	methodQuery                 = 43
+	methodIsVolumeSupported     = 44
+)
+
+const (
+	vssCtxBackup      = 0          // VSS_CTX_BACKUP
+	vssCtxAll         = 0xFFFFFFFF // VSS_CTX_ALL
+	vssObjectNone     = 1          // VSS_OBJECT_NONE
+	vssObjectSnapshot = 3          // VSS_OBJECT_SNAPSHOT
+
+	vssEVolumeNotSupported = 0x8004230C
+
+	vssVolsnapAttrPersistent = 0x1 // VSS_VOLSNAP_ATTR_PERSISTENT
+)
+
+// snapshotProp is VSS_SNAPSHOT_PROP.
+type snapshotProp struct {
+	SnapshotID           windows.GUID
+	SnapshotSetID        windows.GUID
+	SnapshotsCount       int32
+	SnapshotDeviceObject *uint16
+	OriginalVolumeName   *uint16
+	OriginatingMachine   *uint16
+	ServiceMachine       *uint16
+	ExposedName          *uint16
+	ExposedPath          *uint16
+	ProviderID           windows.GUID
+	SnapshotAttributes   int32
+	CreationTimestamp    int64
+	Status               int32
+	// The structure is 8-byte aligned on every platform.
+	_ int32
+}
+
+// creationTime returns the creation time of the snapshot.
+func (p *snapshotProp) creationTime() time.Time {
+	// FILETIME, counting 100ns intervals since 1601.
+	return time.Unix(0, (p.CreationTimestamp-116444736000000000)*100)
+}
+
+// Info describes a snapshot.
+type Info struct {
+	ID    windows.GUID
+	SetID windows.GUID
+	// Volume is the GUID path of the volume the snapshot is of.
+	Volume string
+	// Device is the path of the device of the snapshot, such as
+	// \\?\GLOBALROOT\Device\HarddiskVolumeShadowCopy1, below which the
+	// files of the volume are found.
+	Device  string
+	Created time.Time
+	// Persistent is set for the snapshots which outlive the requester
+	// which created them, such as those of system restore points and of
+	// scheduled shadow copies of shared folders.
+	Persistent bool
+}
+
+// info returns the description of the snapshot of p.
+func (p *snapshotProp) info() Info {
+	return Info{
+		ID:         p.SnapshotID,
+		SetID:      p.SnapshotSetID,
+		Volume:     windows.UTF16PtrToString(p.OriginalVolumeName),
+		Device:     windows.UTF16PtrToString(p.SnapshotDeviceObject),
+		Created:    p.creationTime(),
+		Persistent: p.SnapshotAttributes&vssVolsnapAttrPersistent != 0,
+	}
+}
+
+// BackupType is the type of the backup a snapshot is created for, which
+// the writers record in their backup history. Writers such as SQL Server
+// truncate their logs after successful full and log backups.
+type BackupType int
+
+const (
+	// BackupCopy backs up files without changing the backup history.
+	BackupCopy BackupType = iota
+	BackupFull
+	BackupIncremental
+	BackupDifferential
+	BackupLog
+)
+
+// vss returns the VSS_BACKUP_TYPE of t.
+func (t BackupType) vss() uintptr {
+	switch t {
+	case BackupFull:
+		return 1 // VSS_BT_FULL
+	case BackupIncremental:
+		return 2 // VSS_BT_INCREMENTAL
+	case BackupDifferential:
+		return 3 // VSS_BT_DIFFERENTIAL
+	case BackupLog:
+		return 4 // VSS_BT_LOG
+	default:
+		return 5 // VSS_BT_COPY
+	}
+}
+
+// Options control the creation of a snapshot.
+type Options struct {
+	Type BackupType
+	// ExcludeWriters are the class IDs of the writers left out of the
+	// snapshot, which neither freeze nor are checked for failures.
+	ExcludeWriters []windows.GUID
+	// Select, if not nil, selects the components backed up among those of
+	// the writers, whose writers are told by Complete or Close whether
+	// their backup succeeded. The writers of the other components still
+	// freeze while the snapshot is created, but only the writers of
+	// selected components make their failures fail the creation. Select
+	// should only select components whose files are on the volume of the
+	// snapshot.
+	Select func(*Component) bool
+}
+
+// Snapshot is a shadow copy of a volume created with Create or CreateWith.
+type Snapshot struct {
+	Info
+	// Writers are the writers found while creating the snapshot.
+	Writers []*Writer
+
+	// root is the root of the volume passed to VSS.
+	root     string
+	selected []*Component
+	apt      *apartment
+	comp     *object
+}
+
+// Create creates a snapshot of the volume containing vol, in the backup
+// context, which is deleted by Close or when the process exits. The
+// writers of the system freeze their data while the snapshot is created,
+// leaving it consistent, but the snapshot is a copy backup, which does not
+// change their backup history. If ctx is done while the providers are
+// preparing or creating the snapshot, the operation is cancelled and
+// Create returns the context error.
+func Create(ctx context.Context, vol string) (*Snapshot, error) {
+	return CreateWith(ctx, vol, nil)
+}
+
+// CreateWith is like Create, with the snapshot controlled by opts. A nil
+// opts is the same as the zero Options. If a writer taking part in the
+// snapshot fails, CreateWith deletes the snapshot and returns a
+// *WriterError for each failure.
+func CreateWith(ctx context.Context, vol string, opts *Options) (*Snapshot, error) {
+	if opts == nil {
+		opts = new(Options)
+	}
+
+	root, err := volume.Root(vol)
+	if err != nil {
+		return nil, err
+	}
+
+	apt, err := newApartment()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Snapshot{root: root, apt: apt}
+	if err := apt.do(func() error { return s.create(ctx, opts) }); err != nil {
+		apt.close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *Snapshot) create(ctx context.Context, opts *Options) (err error) {
+	if err := check("CreateVssBackupComponents", createVssBackupComponents(&s.comp)); err != nil {
+		return err
+	}
+	started := false
+	defer func() {
+		if err != nil {
+			if started {
+				s.comp.call(methodAbortBackup)
+			}
+			s.comp.release()
+			s.comp = nil
+		}
+	}()
+
+	if err := check("InitializeForBackup", s.comp.call(methodInitializeForBackup, 0)); err != nil {
+		return err
+	}
+	if err := check("SetContext", s.comp.call(methodSetContext, vssCtxBackup)); err != nil {
+		return err
+	}
+	hr := s.comp.call(methodSetBackupState, boolArg(opts.Select != nil), 0, opts.Type.vss(), 0)
+	if err := check("SetBackupState", hr); err != nil {
+		return err
+	}
+	if err := s.disableWriters(opts.ExcludeWriters); err != nil {
+		return err
+	}
+	// The metadata of the writers must be gathered before preparing
+	// for the backup, even if no component is selected.
+	if err := s.async(ctx, "GatherWriterMetadata", methodGatherWriterMetadata); err != nil {
+		return err
+	}
+	s.Writers, err = s.writerMetadata()
+	s.comp.call(methodFreeWriterMetadata)
+	if err != nil {
+		return err
+	}
+
+	root, err := windows.UTF16PtrFromString(s.root)
+	if err != nil {
+		return err
+	}
+	var (
+		provider  windows.GUID
+		supported int32
+	)
+	args := append(guidArgs(&provider), uintptr(unsafe.Pointer(root)), uintptr(unsafe.Pointer(&supported)))
+	hr = s.comp.call(methodIsVolumeSupported, args...)
+	runtime.KeepAlive(&provider)
+	if err := check("IsVolumeSupported", hr); err != nil {
+		return err
+	}
+	if supported == 0 {
+		return &Error{Op: "IsVolumeSupported", HRESULT: vssEVolumeNotSupported}
+	}
+
+	if err := check("StartSnapshotSet", s.comp.call(methodStartSnapshotSet, uintptr(unsafe.Pointer(&s.SetID)))); err != nil {
+		return err
+	}
+	started = true
+	args = append([]uintptr{uintptr(unsafe.Pointer(root))}, guidArgs(&provider)...)
+	hr = s.comp.call(methodAddToSnapshotSet, append(args, uintptr(unsafe.Pointer(&s.ID)))...)
+	runtime.KeepAlive(&provider)
+	if err := check("AddToSnapshotSet", hr); err != nil {
+		return err
+	}
+
+	if opts.Select != nil {
+		for _, w := range s.Writers {
+			for i := range w.Components {
+				c := &w.Components[i]
+				if !opts.Select(c) {
+					continue
+				}
+				if err := s.addComponent(c); err != nil {
+					return err
+				}
+				s.selected = append(s.selected, c)
+			}
+		}
+	}
+
+	// The writers freeze and thaw within DoSnapshotSet, and report
+	// their failures in their status after each step.
+	if err := s.async(ctx, "PrepareForBackup", methodPrepareForBackup); err != nil {
+		return err
+	}
+	if err := s.checkWriters(ctx, "PrepareForBackup", opts.Select != nil); err != nil {
+		return err
+	}
+	if err := s.async(ctx, "DoSnapshotSet", methodDoSnapshotSet); err != nil {
+		return err
+	}
+	if err := s.checkWriters(ctx, "DoSnapshotSet", opts.Select != nil); err != nil {
+		return err
+	}
+
+	var prop snapshotProp
+	id := s.ID
+	hr = s.comp.call(methodGetSnapshotProperties, append(guidArgs(&id), uintptr(unsafe.Pointer(&prop)))...)
+	runtime.KeepAlive(&id)
+	if err := check("GetSnapshotProperties", hr); err != nil {
+		return err
+	}
+	defer vssFreeSnapshotProperties(&prop)
+	s.Info = prop.info()
+
+	return nil
+}
+
+// async calls the method of the backup components starting the operation
+// op, and waits for it to complete.
+func (s *Snapshot) async(ctx context.Context, op string, method int) error {
+	var a *object
+	if err := check(op, s.comp.call(method, uintptr(unsafe.Pointer(&a)))); err != nil {
+		return err
+	}
+
+	return wait(ctx, op, a)
+}
+
+// Complete tells the writers of the selected components that their
+// backup succeeded, completes the backup and deletes the snapshot.
+func (s *Snapshot) Complete() error {
+	return s.finish(true)
+}
+
+// Close completes the backup and deletes the snapshot, if Complete was not
+// called. The writers of the selected components are told that their
+// backup failed.
+func (s *Snapshot) Close() error {
+	return s.finish(false)
+}
+
+func (s *Snapshot) finish(succeeded bool) error {
+	if s.apt == nil {
+		return nil
+	}
+
+	err := s.apt.do(func() error {
+		defer s.comp.release()
+
+		var errs []error
+		for _, c := range s.selected {
+			errs = append(errs, s.setBackupSucceeded(c, succeeded))
+		}
+		errs = append(errs, s.async(context.Background(), "BackupComplete", methodBackupComplete))
+
+		var (
+			deleted    int32
+			nondeleted windows.GUID
+		)
+		id := s.ID
+		args := append(guidArgs(&id), vssObjectSnapshot, boolArg(true),
+			uintptr(unsafe.Pointer(&deleted)), uintptr(unsafe.Pointer(&nondeleted)))
+		hr := s.comp.call(methodDeleteSnapshots, args...)
+		runtime.KeepAlive(&id)
+
+		return errors.Join(append(errs, check("DeleteSnapshots", hr))...)
+	})
+	s.apt.close()
+	s.apt, s.comp = nil, nil
+
+	return err
+}
@@ -0,0 +1,7 @@
+package vss
+
+//go:generate go run golang.org/x/sys/windows/mkwinsyscall -output zsyscall_windows.go syscall_windows.go
+
+//sys	createVssBackupComponents(comp **object) (hr uint32) = vssapi.CreateVssBackupComponentsInternal
+//sys	vssFreeSnapshotProperties(prop *snapshotProp) = vssapi.VssFreeSnapshotPropertiesInternal
+//sys	sysFreeString(bstr *uint16) = oleaut32.SysFreeString
@@ -0,0 +1,329 @@
+package vss
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// IVssBackupComponents methods dealing with writers.
+const (
+	methodGetWriterMetadataCount = 10
+	methodGetWriterMetadata      = 11
+	methodAddComponent           = 13
+	methodGatherWriterStatus     = 16
+	methodGetWriterStatusCount   = 17
+	methodFreeWriterStatus       = 18
+	methodGetWriterStatus        = 19
+	methodSetBackupSucceeded     = 20
+	methodDisableWriterClasses   = 45
+)
+
+// IVssExamineWriterMetadata methods.
+const (
+	metadataGetIdentity   = 3
+	metadataGetFileCounts = 4
+	metadataGetComponent  = 7
+)
+
+// IVssWMComponent methods.
+const (
+	componentGetInfo  = 3
+	componentFreeInfo = 4
+)
+
+// ComponentType is the type of a component of a writer.
+type ComponentType int32
+
+const (
+	ComponentDatabase  ComponentType = 1 // VSS_CT_DATABASE
+	ComponentFileGroup ComponentType = 2 // VSS_CT_FILEGROUP
+)
+
+func (t ComponentType) String() string {
+	switch t {
+	case ComponentDatabase:
+		return "database"
+	case ComponentFileGroup:
+		return "file group"
+	default:
+		return fmt.Sprintf("ComponentType(%d)", int32(t))
+	}
+}
+
+// Writer describes a writer, an application taking part in snapshots to
+// leave its data consistent in them, such as SQL Server or Exchange.
+type Writer struct {
+	// ID identifies the class of the writer, and Instance the running
+	// instance of it.
+	ID         windows.GUID
+	Instance   windows.GUID
+	Name       string
+	Components []Component
+}
+
+// Component is a set of files of a writer, such as a database, which is
+// backed up as a whole.
+type Component struct {
+	Writer      *Writer
+	Type        ComponentType
+	LogicalPath string
+	Name        string
+	Caption     string
+	// Selectable is set for the components which may be backed up on
+	// their own; the others are only backed up along with their writer.
+	Selectable bool
+}
+
+// componentInfo is VSS_COMPONENTINFO.
+type componentInfo struct {
+	Type                   ComponentType
+	LogicalPath            *uint16
+	ComponentName          *uint16
+	Caption                *uint16
+	Icon                   *byte
+	IconSize               uint32
+	RestoreMetadata        bool
+	NotifyOnBackupComplete bool
+	Selectable             bool
+	SelectableForRestore   bool
+	ComponentFlags         uint32
+	FileCount              uint32
+	Databases              uint32
+	LogFiles               uint32
+	Dependencies           uint32
+}
+
+// writerMetadata returns the writers described by the metadata gathered
+// with GatherWriterMetadata.
+func (s *Snapshot) writerMetadata() ([]*Writer, error) {
+	var count uint32
+	if err := check("GetWriterMetadataCount", s.comp.call(methodGetWriterMetadataCount, uintptr(unsafe.Pointer(&count)))); err != nil {
+		return nil, err
+	}
+
+	writers := make([]*Writer, 0, count)
+	for i := range count {
+		var (
+			instance windows.GUID
+			md       *object
+		)
+		hr := s.comp.call(methodGetWriterMetadata, uintptr(i), uintptr(unsafe.Pointer(&instance)), uintptr(unsafe.Pointer(&md)))
+		if err := check("GetWriterMetadata", hr); err != nil {
+			return nil, err
+		}
+		w, err := examineWriter(md)
+		md.release()
+		if err != nil {
+			return nil, err
+		}
+		writers = append(writers, w)
+	}
+
+	return writers, nil
+}
+
+// examineWriter returns the writer described by the metadata md.
+func examineWriter(md *object) (*Writer, error) {
+	var (
+		w           Writer
+		name        *uint16
+		usage, src  int32
+		incl, excl  uint32
+		nComponents uint32
+	)
+	hr := md.call(metadataGetIdentity, uintptr(unsafe.Pointer(&w.Instance)), uintptr(unsafe.Pointer(&w.ID)),
+		uintptr(unsafe.Pointer(&name)), uintptr(unsafe.Pointer(&usage)), uintptr(unsafe.Pointer(&src)))
+	if err := check("IVssExamineWriterMetadata::GetIdentity", hr); err != nil {
+		return nil, err
+	}
+	w.Name = windows.UTF16PtrToString(name)
+	sysFreeString(name)
+
+	hr = md.call(metadataGetFileCounts, uintptr(unsafe.Pointer(&incl)), uintptr(unsafe.Pointer(&excl)),
+		uintptr(unsafe.Pointer(&nComponents)))
+	if err := check("IVssExamineWriterMetadata::GetFileCounts", hr); err != nil {
+		return nil, err
+	}
+
+	for i := range nComponents {
+		var comp *object
+		if err := check("IVssExamineWriterMetadata::GetComponent", md.call(metadataGetComponent, uintptr(i), uintptr(unsafe.Pointer(&comp)))); err != nil {
+			return nil, err
+		}
+		var info *componentInfo
+		hr := comp.call(componentGetInfo, uintptr(unsafe.Pointer(&info)))
+		if err := check("IVssWMComponent::GetComponentInfo", hr); err != nil {
+			comp.release()
+			return nil, err
+		}
+		w.Components = append(w.Components, Component{
+			Writer:      &w,
+			Type:        info.Type,
+			LogicalPath: windows.UTF16PtrToString(info.LogicalPath),
+			Name:        windows.UTF16PtrToString(info.ComponentName),
+			Caption:     windows.UTF16PtrToString(info.Caption),
+			Selectable:  info.Selectable,
+		})
+		comp.call(componentFreeInfo, uintptr(unsafe.Pointer(info)))
+		comp.release()
+	}
+
+	return &w, nil
+}
+
+// disableWriters leaves the writers of the classes ids out of the
+// snapshot.
+func (s *Snapshot) disableWriters(ids []windows.GUID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	hr := s.comp.call(methodDisableWriterClasses, uintptr(unsafe.Pointer(&ids[0])), uintptr(len(ids)))
+
+	return check("DisableWriterClasses", hr)
+}
+
+// addComponent selects c for the backup.
+func (s *Snapshot) addComponent(c *Component) error {
+	path, name, err := componentNames(c)
+	if err != nil {
+		return err
+	}
+
+	instance, id := c.Writer.Instance, c.Writer.ID
+	args := append(guidArgs(&instance), guidArgs(&id)...)
+	args = append(args, uintptr(c.Type), uintptr(unsafe.Pointer(path)), uintptr(unsafe.Pointer(name)))
+	hr := s.comp.call(methodAddComponent, args...)
+	runtime.KeepAlive(&instance)
+	runtime.KeepAlive(&id)
+
+	return check("AddComponent", hr)
+}
+
+// setBackupSucceeded tells the writer of c whether c was backed up.
+func (s *Snapshot) setBackupSucceeded(c *Component, succeeded bool) error {
+	path, name, err := componentNames(c)
+	if err != nil {
+		return err
+	}
+
+	instance, id := c.Writer.Instance, c.Writer.ID
+	args := append(guidArgs(&instance), guidArgs(&id)...)
+	args = append(args, uintptr(c.Type), uintptr(unsafe.Pointer(path)), uintptr(unsafe.Pointer(name)), boolArg(succeeded))
+	hr := s.comp.call(methodSetBackupSucceeded, args...)
+	runtime.KeepAlive(&instance)
+	runtime.KeepAlive(&id)
+
+	return check("SetBackupSucceeded", hr)
+}
+
+// componentNames returns the logical path of c, nil if it has none, and
+// its name.
+func componentNames(c *Component) (path, name *uint16, err error) {
+	if c.LogicalPath != "" {
+		if path, err = windows.UTF16PtrFromString(c.LogicalPath); err != nil {
+			return nil, nil, err
+		}
+	}
+	if name, err = windows.UTF16PtrFromString(c.Name); err != nil {
+		return nil, nil, err
+	}
+
+	return path, name, nil
+}
+
+// WriterState is the state of a writer, as reported after each step of
+// the creation of a snapshot.
+type WriterState int32
+
+// WriterFailedAtIdentify is the first of the states of failed writers.
+const WriterFailedAtIdentify WriterState = 6 // VSS_WS_FAILED_AT_IDENTIFY
+
+var writerStateNames = [...]string{
+	"unknown", "stable", "waiting for freeze", "waiting for thaw",
+	"waiting for post snapshot", "waiting for backup complete",
+	"failed at identify", "failed at prepare backup", "failed at prepare snapshot",
+	"failed at freeze", "failed at thaw", "failed at post snapshot",
+	"failed at backup complete", "failed at pre restore", "failed at post restore",
+	"failed at backup shutdown",
+}
+
+func (s WriterState) String() string {
+	if s < 0 || int(s) >= len(writerStateNames) {
+		return fmt.Sprintf("WriterState(%d)", int32(s))
+	}
+
+	return writerStateNames[s]
+}
+
+// Failed reports whether s is the state of a failed writer.
+func (s WriterState) Failed() bool {
+	return s >= WriterFailedAtIdentify
+}
+
+// WriterError is a writer which failed while a snapshot was created,
+// leaving its data in the snapshot crash-consistent at best.
+type WriterError struct {
+	Op      string
+	Writer  string
+	State   WriterState
+	HRESULT uint32
+}
+
+func (e *WriterError) Error() string {
+	return fmt.Sprintf("vss: %s: writer %q %s (HRESULT 0x%08X)", e.Op, e.Writer, e.State, e.HRESULT)
+}
+
+// checkWriters gathers the status of the writers after the step op, and
+// returns their failures, only counting the writers of the selected
+// components if selectedOnly is set.
+func (s *Snapshot) checkWriters(ctx context.Context, op string, selectedOnly bool) error {
+	if err := s.async(ctx, "GatherWriterStatus", methodGatherWriterStatus); err != nil {
+		return err
+	}
+	defer s.comp.call(methodFreeWriterStatus)
+
+	var count uint32
+	if err := check("GetWriterStatusCount", s.comp.call(methodGetWriterStatusCount, uintptr(unsafe.Pointer(&count)))); err != nil {
+		return err
+	}
+
+	var errs []error
+	for i := range count {
+		var (
+			instance, id windows.GUID
+			name         *uint16
+			state        WriterState
+			failure      uint32
+		)
+		hr := s.comp.call(methodGetWriterStatus, uintptr(i), uintptr(unsafe.Pointer(&instance)), uintptr(unsafe.Pointer(&id)),
+			uintptr(unsafe.Pointer(&name)), uintptr(unsafe.Pointer(&state)), uintptr(unsafe.Pointer(&failure)))
+		if err := check("GetWriterStatus", hr); err != nil {
+			return err
+		}
+		writer := windows.UTF16PtrToString(name)
+		sysFreeString(name)
+
+		if state.Failed() && (!selectedOnly || s.hasSelected(instance)) {
+			errs = append(errs, &WriterError{Op: op, Writer: writer, State: state, HRESULT: failure})
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// hasSelected reports whether a component of the writer instance was
+// selected.
+func (s *Snapshot) hasSelected(instance windows.GUID) bool {
+	for _, c := range s.selected {
+		if c.Writer.Instance == instance {
+			return true
+		}
+	}
+
+	return false
+}